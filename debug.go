@@ -0,0 +1,28 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/caproven/mock-server/internal/config"
+)
+
+const defaultDebugAddr = "localhost:6060"
+
+// newDebugHandler serves the enabled profiling endpoints. It's kept off the main handler so the
+// routes are never exposed on the mock's port or recorded in its access log.
+func newDebugHandler(cfg config.Debug) http.Handler {
+	mux := http.NewServeMux()
+	if cfg.PProf {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if cfg.Expvar {
+		mux.Handle("GET /debug/vars", expvar.Handler())
+	}
+	return mux
+}
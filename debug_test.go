@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caproven/mock-server/internal/config"
+	"github.com/caproven/mock-server/internal/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	get := func(h http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	paths := []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/symbol", "/debug/vars"}
+
+	// importing net/http/pprof registers its routes on the default mux, which must never serve
+	// the mock's requests
+	require.Equal(t, http.StatusOK, get(http.DefaultServeMux, "/debug/pprof/"))
+
+	t.Run("not on the main handler", func(t *testing.T) {
+		endpoint, err := rest.NewEndpoint("/users/{id}", http.MethodGet, rest.StaticResponse(rest.Response{}))
+		require.NoError(t, err)
+		mux := newMux([]*rest.Endpoint{endpoint}, rest.NewDrain(0), "")
+		for _, path := range paths {
+			assert.Equal(t, http.StatusNotFound, get(mux, path), path)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		cfg        config.Debug
+		wantPProf  bool
+		wantExpvar bool
+	}{
+		{name: "pprof", cfg: config.Debug{PProf: true}, wantPProf: true},
+		{name: "expvar", cfg: config.Debug{Expvar: true}, wantExpvar: true},
+		{name: "both", cfg: config.Debug{PProf: true, Expvar: true}, wantPProf: true, wantExpvar: true},
+	}
+	status := func(served bool) int {
+		if served {
+			return http.StatusOK
+		}
+		return http.StatusNotFound
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newDebugHandler(tt.cfg)
+			for _, path := range paths[:3] {
+				assert.Equal(t, status(tt.wantPProf), get(h, path), path)
+			}
+			assert.Equal(t, status(tt.wantExpvar), get(h, "/debug/vars"))
+			assert.Equal(t, http.StatusNotFound, get(h, "/users/1"), "only the debug routes")
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package accesslog
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Handler wraps next so every request is written to logger once it has been served.
+func Handler(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &Recorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("addr", r.RemoteAddr),
+			slog.Int("status", cmp.Or(rec.status, http.StatusOK)),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// Recorder wraps a ResponseWriter, recording the final status and the number of body bytes
+// written through it.
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// Status returns the final status written, or zero if nothing has been written yet.
+func (r *Recorder) Status() int {
+	return r.status
+}
+
+// Bytes returns the number of body bytes written.
+func (r *Recorder) Bytes() int64 {
+	return r.bytes
+}
+
+func (r *Recorder) WriteHeader(statusCode int) {
+	// informational responses come before the final one, which is the one recorded
+	if r.status == 0 && statusCode >= http.StatusOK {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and hijacking.
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,141 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+// RotatingWriter is an io.Writer appending to a file, rotating it once it grows past a size limit.
+// Rotated files are renamed with a timestamp suffix and the oldest are removed beyond maxBackups.
+type RotatingWriter struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	now  func() time.Time
+}
+
+// NewRotatingWriter opens (or creates) the file at path for appending. A maxBytes of 0 disables
+// rotation and a maxBackups of 0 keeps every rotated file.
+func NewRotatingWriter(path string, maxBytes int64, maxBackups int) (*RotatingWriter, error) {
+	if maxBytes < 0 {
+		return nil, errors.New("max size cannot be negative")
+	}
+	if maxBackups < 0 {
+		return nil, errors.New("max backups cannot be negative")
+	}
+
+	w := &RotatingWriter{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("rotate access log: %w", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file. Further writes fail.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open access log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat access log: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate must be called with mu held. The current file stays open until the new one is, so after a
+// failed rotation writes keep going to it and the next write tries again.
+func (w *RotatingWriter) rotate() error {
+	backup := w.path + "." + w.now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	old := w.file
+	if err := w.open(); err != nil {
+		// put the file back so it's the one that keeps growing
+		return errors.Join(err, os.Rename(backup, w.path))
+	}
+	if err := old.Close(); err != nil {
+		return err
+	}
+	return w.pruneBackups()
+}
+
+func (w *RotatingWriter) pruneBackups() error {
+	if w.maxBackups == 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return err
+	}
+	backups = slices.DeleteFunc(backups, func(name string) bool {
+		_, err := time.Parse(backupTimeFormat, strings.TrimPrefix(name, w.path+"."))
+		return err != nil
+	})
+	if len(backups) <= w.maxBackups {
+		return nil
+	}
+
+	// timestamp suffixes sort chronologically
+	slices.Sort(backups)
+	for _, name := range backups[:len(backups)-w.maxBackups] {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
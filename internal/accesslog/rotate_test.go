@@ -0,0 +1,139 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	t.Run("invalid limits", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+
+		w, err := NewRotatingWriter(path, -1, 0)
+		assert.Error(t, err)
+		assert.Nil(t, w)
+
+		w, err = NewRotatingWriter(path, 0, -1)
+		assert.Error(t, err)
+		assert.Nil(t, w)
+	})
+
+	t.Run("no rotation when disabled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		w, err := NewRotatingWriter(path, 0, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = w.Close() })
+
+		for range 10 {
+			_, err := w.Write([]byte("0123456789"))
+			require.NoError(t, err)
+		}
+
+		backups, err := filepath.Glob(path + ".*")
+		require.NoError(t, err)
+		assert.Empty(t, backups)
+	})
+
+	t.Run("rotates past limit", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		w, err := NewRotatingWriter(path, 10, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = w.Close() })
+
+		_, err = w.Write([]byte("0123456789"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("abc"))
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(data))
+
+		backups, err := filepath.Glob(path + ".*")
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+		data, err = os.ReadFile(backups[0])
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", string(data))
+	})
+
+	t.Run("prunes old backups", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		w, err := NewRotatingWriter(path, 1, 2)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = w.Close() })
+
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		w.now = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+
+		for _, line := range []string{"a", "b", "c", "d", "e"} {
+			_, err := w.Write([]byte(line))
+			require.NoError(t, err)
+		}
+
+		backups, err := filepath.Glob(path + ".*")
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		for i, want := range []string{"c", "d"} {
+			data, err := os.ReadFile(backups[i])
+			require.NoError(t, err)
+			assert.Equal(t, want, string(data))
+		}
+	})
+
+	t.Run("keeps writing after a failed rotation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		w, err := NewRotatingWriter(path, 10, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = w.Close() })
+
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		w.now = func() time.Time { return now }
+		// a non-empty directory in the backup's place makes renaming onto it fail
+		blocker := path + "." + now.Format(backupTimeFormat)
+		require.NoError(t, os.MkdirAll(filepath.Join(blocker, "sub"), 0o755))
+
+		_, err = w.Write([]byte("0123456789"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("abc"))
+		assert.Error(t, err)
+
+		require.NoError(t, os.RemoveAll(blocker))
+		_, err = w.Write([]byte("abc"))
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(data))
+		data, err = os.ReadFile(blocker)
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", string(data))
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		w, err := NewRotatingWriter(path, 64, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = w.Close() })
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				for range 50 {
+					_, err := w.Write([]byte("line\n"))
+					assert.NoError(t, err)
+				}
+			})
+		}
+		wg.Wait()
+	})
+}
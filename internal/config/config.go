@@ -1,162 +1,1965 @@
 package config
 
 import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/caproven/mock-server/internal/jsonschema"
 	"github.com/caproven/mock-server/internal/rest"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
 )
 
 type Config struct {
+	// Include lists other config files whose endpoints and named responses are merged into this one,
+	// relative to this file.
+	Include   []string   `yaml:"include"`
 	Endpoints []Endpoint `json:"endpoints"`
+	AccessLog *AccessLog `yaml:"accessLog"`
+	Debug     *Debug     `yaml:"debug"`
+	Server    Server     `yaml:"server"`
+	// ETag enables ETags for every endpoint, unless an endpoint disables it.
+	ETag bool `yaml:"etag"`
+	// AutoContentLength sets Content-Length on every buffered response instead of relying on chunking.
+	AutoContentLength bool `yaml:"autoContentLength"`
+	// RequestID tags each request with an ID, echoed in a response header, when set.
+	RequestID *RequestID `yaml:"requestId"`
+	// DebugHeaders adds X-Mock-Strategy and X-Mock-Response-Index headers to every response.
+	DebugHeaders bool `yaml:"debugHeaders"`
+	// AllowStatusOverride lets clients force a response's status with an X-Mock-Status header.
+	AllowStatusOverride bool `yaml:"allowStatusOverride"`
+	// AllowIndexOverride lets clients pick which of an endpoint's responses they get with an
+	// X-Mock-Index header.
+	AllowIndexOverride bool `yaml:"allowIndexOverride"`
+	// NotFound is returned for requests matching no endpoint.
+	NotFound *Response `yaml:"notFound"`
+	// MethodNotAllowed is returned for requests matching an endpoint's path but not its method.
+	MethodNotAllowed *Response `yaml:"methodNotAllowed"`
+	// TooLarge is returned for requests whose body exceeds server.maxRequestBytes.
+	TooLarge *Response `yaml:"tooLarge"`
+	// Defaults are merged into the responses of every endpoint's response strategy.
+	Defaults *Defaults `yaml:"defaults"`
+	// Responses are named responses that can be used anywhere a response is expected via responseRef.
+	Responses map[string]Response `yaml:"responses"`
+}
+
+// Decode reads a YAML config from r. Anchors and aliases can share fragments of it, and merge keys
+// (<<: *anchor) can be followed by keys overriding what they merged.
+func Decode(r io.Reader) (Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Config{}, err
+	}
+	// parsing strictly still rejects keys repeated within a mapping, but the decoder has to allow
+	// duplicates for merged keys to be overridden
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return cfg, nil
+	}
+	if err := yaml.NodeToValue(file.Docs[0].Body, &cfg, yaml.AllowDuplicateMapKey()); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// SetDir sets dir as the directory that relative body, request schema, and TLS file paths in the
+// config are resolved against, normally the config file's directory. It applies to the config's
+// own endpoints, responses, and listeners, so it's set on an included config before merging it
+// into another.
+func (c *Config) SetDir(dir string) {
+	for i := range c.Endpoints {
+		c.Endpoints[i].dir = dir
+	}
+	for name, resp := range c.Responses {
+		resp.dir = dir
+		c.Responses[name] = resp
+	}
+	for _, resp := range []*Response{c.NotFound, c.MethodNotAllowed, c.TooLarge, c.Server.StartupResponse, c.Server.RequestTimeoutResponse, c.Server.ShutdownResponse} {
+		if resp != nil {
+			resp.dir = dir
+		}
+	}
+	for _, listener := range c.Server.Listeners {
+		if listener.TLS != nil {
+			listener.TLS.dir = dir
+		}
+	}
+}
+
+// resolvePath resolves a relative path against dir. Absolute paths, and all paths if dir is
+// empty, are returned as-is.
+func resolvePath(dir, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+type RequestID struct {
+	// Header carries the request ID, default X-Request-Id.
+	Header string `yaml:"header"`
+}
+
+type Defaults struct {
+	Response *ResponseDefaults `yaml:"response"`
+}
+
+// ResponseDefaults fill in fields the responses of an endpoint's response strategy leave unset.
+// Headers are merged per key, with the response's own headers taking precedence. Responses built
+// from other endpoint settings, such as rate limits, faults, and OPTIONS, and sse, websocket, drip,
+// and staticDir responses, which aren't built from a Response, don't get defaults.
+type ResponseDefaults struct {
+	StatusCode int                     `yaml:"status"`
+	Headers    map[string]HeaderValues `yaml:"headers"`
+	// Delay applies to responses without a delay or delayDistribution.
+	Delay string `yaml:"delay"`
+}
+
+// apply returns r with its unset fields filled in from the defaults.
+func (d *ResponseDefaults) apply(r Response) Response {
+	if d == nil {
+		return r
+	}
+	if r.StatusCode == 0 {
+		r.StatusCode = d.StatusCode
+	}
+	if r.Delay == "" && r.DelayDistribution == nil {
+		r.Delay = d.Delay
+	}
+	if len(d.Headers) > 0 {
+		headers := make(map[string]HeaderValues, len(d.Headers)+len(r.Headers))
+		for k, v := range d.Headers {
+			if !hasHeader(r.Headers, k) {
+				headers[k] = v
+			}
+		}
+		maps.Copy(headers, r.Headers)
+		r.Headers = headers
+	}
+	return r
+}
+
+// hasHeader reports whether headers sets name, ignoring case.
+func hasHeader[V any](headers map[string]V, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type Server struct {
+	ReadTimeout       string     `yaml:"readTimeout"`
+	ReadHeaderTimeout string     `yaml:"readHeaderTimeout"`
+	WriteTimeout      string     `yaml:"writeTimeout"`
+	IdleTimeout       string     `yaml:"idleTimeout"`
+	MaxRequestBytes   *int64     `yaml:"maxRequestBytes"`
+	Listeners         []Listener `yaml:"listeners"`
+	// TrailingSlash is one of strict (default), redirect, or ignore.
+	TrailingSlash string `yaml:"trailingSlash"`
+	// BasePath prefixes every endpoint's path, e.g. /api/v1 when mounted behind a gateway.
+	BasePath string `yaml:"basePath"`
+	// AdminInBasePath also prefixes the admin routes, stats included, and the readiness check with
+	// BasePath.
+	AdminInBasePath bool `yaml:"adminInBasePath"`
+	// MaxConnections caps the connections open at once across all listeners. New connections wait
+	// to be accepted until one closes.
+	MaxConnections *int `yaml:"maxConnections"`
+	// KeepAlive enables HTTP keep-alives, letting clients reuse connections. Defaults to true.
+	KeepAlive *bool `yaml:"keepAlive"`
+	// H2C serves HTTP/2 over plaintext connections alongside HTTP/1.
+	H2C bool `yaml:"h2c"`
+	// StartupDelay is how long the server warms up after starting, answering every request with
+	// StartupResponse.
+	StartupDelay string `yaml:"startupDelay"`
+	// StartupResponse replaces the default 503 returned during StartupDelay.
+	StartupResponse *Response `yaml:"startupResponse"`
+	// DrainGracePeriod is how long the server answers requests with a 503 after being drained
+	// through the admin API, before dropping their connections, defaulting to 5s.
+	DrainGracePeriod string `yaml:"drainGracePeriod"`
+	// RequestTimeout answers requests not answered in time with RequestTimeoutResponse, including
+	// their delay.
+	RequestTimeout string `yaml:"requestTimeout"`
+	// RequestTimeoutResponse replaces the default 504 returned after RequestTimeout.
+	RequestTimeoutResponse *Response `yaml:"requestTimeoutResponse"`
+	// ShutdownDrain is how long the server keeps answering new requests with ShutdownResponse after
+	// a shutdown signal, before it stops accepting them and waits for those in flight.
+	ShutdownDrain string `yaml:"shutdownDrain"`
+	// ShutdownResponse replaces the default 503 returned during ShutdownDrain.
+	ShutdownResponse *Response `yaml:"shutdownResponse"`
+}
+
+type Listener struct {
+	Addr string `yaml:"addr"`
+	TLS  *TLS   `yaml:"tls"`
+	// IdleTimeout overrides the server's idleTimeout for connections to this listener.
+	IdleTimeout string `yaml:"idleTimeout"`
+}
+
+// Idle returns the listener's idle timeout, or idle if the listener doesn't override it.
+func (l Listener) Idle(idle time.Duration) (time.Duration, error) {
+	if l.IdleTimeout == "" {
+		return idle, nil
+	}
+	d, err := time.ParseDuration(l.IdleTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid idleTimeout %q", l.IdleTimeout)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("idleTimeout cannot be negative: %s", l.IdleTimeout)
+	}
+	return d, nil
+}
+
+type TLS struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// ClientAuth asks clients for a certificate, either "request", verifying it if given, or
+	// "require", failing the handshake without a valid one.
+	ClientAuth string `yaml:"clientAuth"`
+	// ClientCAFile is a PEM file of the CA certificates client certificates are verified against.
+	ClientCAFile string `yaml:"clientCAFile"`
+	// HandshakeDelay stalls every TLS handshake after the client's hello, for testing clients'
+	// handshake timeouts.
+	HandshakeDelay string `yaml:"handshakeDelay"`
+
+	// dir is the directory of the config file defining the listener, see Config.SetDir.
+	dir string
+}
+
+// KeyPair returns the paths of CertFile and KeyFile, resolved against the config file's directory.
+func (t TLS) KeyPair() (certFile, keyFile string) {
+	return resolvePath(t.dir, t.CertFile), resolvePath(t.dir, t.KeyFile)
+}
+
+// Config returns the TLS config for client authentication and handshake delays, leaving
+// certificates to be loaded from KeyPair.
+func (t TLS) Config() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if t.HandshakeDelay != "" {
+		delay, err := time.ParseDuration(t.HandshakeDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid handshakeDelay %q", t.HandshakeDelay)
+		}
+		if delay <= 0 {
+			return nil, fmt.Errorf("handshakeDelay must be positive: %s", t.HandshakeDelay)
+		}
+		cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				return nil, nil // continue with cfg
+			case <-hello.Context().Done():
+				// the handshake was abandoned, don't hold its goroutine for the rest of the delay
+				return nil, hello.Context().Err()
+			}
+		}
+	}
+
+	switch t.ClientAuth {
+	case "":
+		if t.ClientCAFile != "" {
+			return nil, errors.New("clientCAFile requires clientAuth")
+		}
+		return cfg, nil
+	case "request":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown clientAuth %q", t.ClientAuth)
+	}
+
+	if t.ClientCAFile == "" {
+		return nil, errors.New("clientAuth requires clientCAFile")
+	}
+	data, err := os.ReadFile(resolvePath(t.dir, t.ClientCAFile))
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	cfg.ClientCAs = x509.NewCertPool()
+	if !cfg.ClientCAs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA file %q has no PEM certificates", t.ClientCAFile)
+	}
+	return cfg, nil
+}
+
+const defaultMaxRequestBytes = 10 * 1024 * 1024
+
+// ServerTimeouts are the parsed server timeouts. A zero value means no timeout.
+type ServerTimeouts struct {
+	Read       time.Duration
+	ReadHeader time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+var defaultServerTimeouts = ServerTimeouts{
+	Read:       30 * time.Second,
+	ReadHeader: 10 * time.Second,
+	Write:      30 * time.Second,
+	Idle:       2 * time.Minute,
+}
+
+type AccessLog struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"maxSizeMB"`
+	MaxBackups int    `yaml:"maxBackups"`
+}
+
+// Debug configures profiling endpoints for the mock server itself, served on their own listener.
+type Debug struct {
+	Addr   string `yaml:"addr"`
+	PProf  bool   `yaml:"pprof"`
+	Expvar bool   `yaml:"expvar"`
 }
 
 type Endpoint struct {
-	Path             string           `yaml:"path"`
+	// ID refers to the endpoint in the admin API, defaulting to its method and path, e.g. "GET /users".
+	ID   string `yaml:"id"`
+	Path string `yaml:"path"`
+	// PathType is how Path is matched, either "pattern" (default) for Go's mux patterns or "regex".
+	PathType         string           `yaml:"pathType"`
 	Method           string           `yaml:"method"`
 	ResponseStrategy ResponseStrategy `yaml:"response"`
+	RateLimit        *RateLimit       `yaml:"rateLimit"`
+	// MaxConcurrency bounds the requests the endpoint handles at once.
+	MaxConcurrency *ConcurrencyLimit `yaml:"maxConcurrency"`
+	Faults         []Fault           `yaml:"faults"`
+	ETag           *bool             `yaml:"etag"`
+	LastModified   string            `yaml:"lastModified"`
+	// Outage takes the endpoint down periodically, answering with its response instead.
+	Outage *Outage `yaml:"outage"`
+	// Middleware names built-in middlewares wrapping the endpoint, outermost first.
+	Middleware []string `yaml:"middleware"`
+	// MaxResponseBytes truncates response bodies to this many bytes.
+	MaxResponseBytes *int `yaml:"maxResponseBytes"`
+	// FullContentLength advertises the untruncated length when MaxResponseBytes truncates a body.
+	FullContentLength bool `yaml:"fullContentLength"`
+	// DisabledResponse is returned while the endpoint is disabled through the admin API, default 503.
+	DisabledResponse *Response `yaml:"disabledResponse"`
+	// RequiredQuery are query parameters requests must include.
+	RequiredQuery []string `yaml:"requiredQuery"`
+	// MissingQueryResponse replaces the default 400 listing missing required query parameters.
+	MissingQueryResponse *Response `yaml:"missingQueryResponse"`
+	// RequestSchema is a JSON Schema request bodies must match, either the path to a JSON file or
+	// an inline schema.
+	RequestSchema any `yaml:"requestSchema"`
+	// InvalidRequestResponse replaces the default 400 listing a request body's validation errors.
+	InvalidRequestResponse *Response `yaml:"invalidRequestResponse"`
+	// ReflectHeaders are request headers copied into the response.
+	ReflectHeaders []string `yaml:"reflectHeaders"`
+	// ReflectHeadersOverride lets reflected headers replace headers the response sets itself.
+	ReflectHeadersOverride bool `yaml:"reflectHeadersOverride"`
+	// RawHeaderCase sends response header names with their configured casing, uncanonicalized.
+	RawHeaderCase bool `yaml:"rawHeaderCase"`
+	// CloseConnection closes the connection after each response, with a Connection: close header.
+	CloseConnection bool `yaml:"closeConnection"`
+	// Options is the response to OPTIONS requests for the endpoint's path, default 204. Its Allow
+	// header defaults to the methods of the path's endpoints.
+	Options *Response `yaml:"options"`
+	// MethodFallthrough, when false, answers requests for the endpoint's path whose method none of
+	// the path's endpoints serve with a 405, rather than letting them fall through to a broader
+	// endpoint for all methods. It defaults to true, and disabling it on one of a path's endpoints
+	// disables it for the path.
+	MethodFallthrough *bool `yaml:"methodFallthrough"`
+	// Capture writes each request and its response to files, for debugging the endpoint.
+	Capture *Capture `yaml:"capture"`
+	// ExpectContinue controls the answer to requests sent with Expect: 100-continue.
+	ExpectContinue *ExpectContinue `yaml:"expectContinue"`
+
+	// dir is the directory of the config file defining the endpoint, see Config.SetDir.
+	dir string
+}
+
+// compileRequestSchema compiles the endpoint's request schema, or returns nil if it has none.
+func (e Endpoint) compileRequestSchema() (*jsonschema.Schema, error) {
+	switch schema := e.RequestSchema.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		data, err := os.ReadFile(resolvePath(e.dir, schema))
+		if err != nil {
+			return nil, fmt.Errorf("read request schema: %w", err)
+		}
+		compiled, err := jsonschema.Compile(data)
+		if err != nil {
+			return nil, fmt.Errorf("compile request schema %q: %w", schema, err)
+		}
+		return compiled, nil
+	case map[string]any:
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("encode inline request schema: %w", err)
+		}
+		compiled, err := jsonschema.Compile(data)
+		if err != nil {
+			return nil, fmt.Errorf("compile request schema: %w", err)
+		}
+		return compiled, nil
+	default:
+		return nil, errors.New("requestSchema must be a file path or an inline schema")
+	}
+}
+
+type Fault struct {
+	Probability float64  `yaml:"probability"`
+	Fault       string   `yaml:"fault"`
+	Bytes       int      `yaml:"bytes"`
+	Response    Response `yaml:"response"`
+}
+
+// Outage answers with Response for For at the end of every period of Every, counted from startup.
+type Outage struct {
+	Every    string   `yaml:"every"`
+	For      string   `yaml:"for"`
+	Response Response `yaml:"response"`
+}
+
+func (o Outage) toRest(conv convertContext, resolver rest.ResponseResolver) (*rest.OutageResponse, error) {
+	every, err := time.ParseDuration(o.Every)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outage every %q", o.Every)
+	}
+	duration, err := time.ParseDuration(o.For)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outage for %q", o.For)
+	}
+	resp, err := o.Response.toRestWithStatus(conv, http.StatusServiceUnavailable)
+	if err != nil {
+		return nil, fmt.Errorf("build outage response: %w", err)
+	}
+	return rest.NewOutageResponse(resolver, every, duration, resp, nil)
+}
+
+type Capture struct {
+	Dir string `yaml:"dir"`
+	// MaxBodySize caps each captured body, default 1MB, with an optional KB, MB, or GB suffix.
+	MaxBodySize string `yaml:"maxBodySize"`
+}
+
+const defaultCaptureMaxBodyBytes = 1 << 20
+
+// toRest builds the capture option, with Dir relative to dir.
+func (c Capture) toRest(dir string) (rest.EndpointOption, error) {
+	maxBodyBytes := int64(defaultCaptureMaxBodyBytes)
+	if c.MaxBodySize != "" {
+		var err error
+		if maxBodyBytes, err = parseByteSize(c.MaxBodySize); err != nil {
+			return nil, fmt.Errorf("invalid capture maxBodySize: %w", err)
+		}
+		if maxBodyBytes > math.MaxInt32 {
+			return nil, fmt.Errorf("capture maxBodySize too large: %s", c.MaxBodySize)
+		}
+	}
+	return rest.WithCapture(resolvePath(dir, c.Dir), int(maxBodyBytes)), nil
+}
+
+type ExpectContinue struct {
+	// Delay waits before sending 100 Continue.
+	Delay string `yaml:"delay"`
+	// Suppress never sends 100 Continue, handling the request without its body.
+	Suppress bool `yaml:"suppress"`
+	// RejectStatus answers with a final status instead of 100 Continue, such as 417.
+	RejectStatus int `yaml:"rejectStatus"`
+}
+
+func (e ExpectContinue) toRest() (rest.EndpointOption, error) {
+	var delay time.Duration
+	if e.Delay != "" {
+		var err error
+		if delay, err = time.ParseDuration(e.Delay); err != nil {
+			return nil, fmt.Errorf("invalid expectContinue delay %q", e.Delay)
+		}
+	}
+	return rest.WithExpectContinue(rest.ExpectContinue{
+		Delay:        delay,
+		Suppress:     e.Suppress,
+		RejectStatus: e.RejectStatus,
+	}), nil
+}
+
+type ConcurrencyLimit struct {
+	Limit int `yaml:"limit"`
+	// QueueTimeout is how long requests over the limit wait for a slot, rejecting them immediately if unset.
+	QueueTimeout string `yaml:"queueTimeout"`
+	// Response is returned to rejected requests, default 503.
+	Response Response `yaml:"response"`
+}
+
+type RateLimit struct {
+	RequestsPerSecond float64  `yaml:"requestsPerSecond"`
+	Burst             *int     `yaml:"burst"`
+	RetryAfter        string   `yaml:"retryAfter"`
+	Response          Response `yaml:"response"`
 }
 
 type ResponseStrategy struct {
 	Static   *Response          `yaml:"static"`
 	Weighted []WeightedResponse `yaml:"weighted"`
-	Sequence *SequencedResponse `yaml:"sequence"`
+	// WeightRamp shifts weighted responses' weights over time.
+	WeightRamp *WeightRamp `yaml:"weightRamp"`
+	// Sticky keeps each client on the weighted response first chosen for it.
+	Sticky     *StickySessions    `yaml:"sticky"`
+	Sequence   *SequencedResponse `yaml:"sequence"`
+	Schedule   *ScheduledResponse `yaml:"schedule"`
+	AfterCount *ThresholdResponse `yaml:"afterCount"`
+	// FirstRequest answers only the first request after startup, with Thereafter answering the
+	// rest. It's shorthand for afterCount with a threshold of 1.
+	FirstRequest *Response          `yaml:"firstRequest"`
+	Thereafter   *Response          `yaml:"thereafter"`
+	PerClient    *PerClientResponse `yaml:"perClient"`
+	SSE          *EventStream       `yaml:"sse"`
+	WebSocket    *WebSocket         `yaml:"websocket"`
+	Drip         *DripResponse      `yaml:"drip"`
+	// StaticDir serves files from a directory, named by the endpoint path's last wildcard.
+	StaticDir *StaticDir `yaml:"staticDir"`
+	// Representations negotiates between responses using the request's Accept header.
+	Representations *NegotiatedResponse `yaml:"representations"`
+	Window          *WindowResponse     `yaml:"window"`
+	// Match returns the response of the first case matching the request.
+	Match []MatchCase `yaml:"match"`
+	// Fallthrough tries each strategy in order until one matches the request.
+	Fallthrough []ResponseStrategy `yaml:"fallthrough"`
+	// JSONRPC answers JSON-RPC 2.0 calls with the response of the method called.
+	JSONRPC *JSONRPCResponse `yaml:"jsonrpc"`
+}
+
+type WeightedResponse struct {
+	// Weight may be fractional, such as a percentage like 12.5.
+	Weight   float64  `yaml:"weight"`
+	Response Response `yaml:"response"`
+	// Fault makes the entry a connection fault, either "connectionReset" or "truncate". Truncate
+	// cuts the entry's response off after Bytes of its body.
+	Fault string `yaml:"fault"`
+	Bytes int    `yaml:"bytes"`
+	// MaxOccurrences drops the entry after it has been chosen this many times, such as an error
+	// that heals. Unlimited if unset.
+	MaxOccurrences int `yaml:"maxOccurrences"`
+}
+
+type WeightRamp struct {
+	// EndWeights are the weights reached after Duration, one per weighted response.
+	EndWeights []int  `yaml:"endWeights"`
+	Duration   string `yaml:"duration"`
+}
+
+// StickySessions identifies clients like a per client strategy does, by header, then cookie, then
+// remote IP.
+type StickySessions struct {
+	Header      string `yaml:"header"`
+	Cookie      string `yaml:"cookie"`
+	TTL         string `yaml:"ttl"`
+	MaxSessions *int   `yaml:"maxSessions"`
+}
+
+type SequencedResponse struct {
+	EndBehavior string                   `yaml:"endBehavior"`
+	Responses   []SequencedResponseEntry `yaml:"responses"`
+	// End is the terminal response returned once the sequence is done, with the "end" end behavior.
+	End *Response `yaml:"end"`
+	// DelayStrategy steps the delay of each position in the sequence from a start to an end delay.
+	DelayStrategy *SequenceDelayStrategy `yaml:"delayStrategy"`
+}
+
+type SequenceDelayStrategy struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// Steps is the number of positions taken to reach End, defaulting to the sequence's length.
+	Steps *int `yaml:"steps"`
+}
+
+// delays returns the delay for each of n positions, interpolated linearly from start to end over
+// the first steps positions and holding at end after.
+func (s SequenceDelayStrategy) delays(n int) ([]time.Duration, error) {
+	start, err := time.ParseDuration(s.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delayStrategy start %q", s.Start)
+	}
+	end, err := time.ParseDuration(s.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delayStrategy end %q", s.End)
+	}
+	if start < 0 || end < 0 {
+		return nil, errors.New("delayStrategy start and end cannot be negative")
+	}
+	steps := n
+	if s.Steps != nil {
+		steps = *s.Steps
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("delayStrategy steps must be >= 1: %d", steps)
+	}
+
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		if i >= steps-1 {
+			delays[i] = end
+			continue
+		}
+		delays[i] = start + (end-start)*time.Duration(i)/time.Duration(steps-1)
+	}
+	return delays, nil
+}
+
+type SequencedResponseEntry struct {
+	Count *int `yaml:"count"`
+	// Delay overrides the response's delay for this entry's positions in the sequence.
+	Delay    string   `yaml:"delay"`
+	Response Response `yaml:"response"`
+}
+
+type EventStream struct {
+	Headers map[string]string `yaml:"headers"`
+	Events  []Event           `yaml:"events"`
+	Loop    bool              `yaml:"loop"`
+}
+
+type Event struct {
+	Event string `yaml:"event"`
+	ID    string `yaml:"id"`
+	Data  string `yaml:"data"`
+	Delay string `yaml:"delay"`
+}
+
+type DripResponse struct {
+	Status   int               `yaml:"status"`
+	Headers  map[string]string `yaml:"headers"`
+	Delay    string            `yaml:"delay"`
+	Elements []string          `yaml:"elements"`
+}
+
+type WebSocket struct {
+	Echo     bool               `yaml:"echo"`
+	Messages []WebSocketMessage `yaml:"messages"`
+}
+
+type WebSocketMessage struct {
+	Text  string `yaml:"text"`
+	Delay string `yaml:"delay"`
+}
+
+type StaticDir struct {
+	Dir string `yaml:"dir"`
+	// Listing lists directories without an index.html rather than returning a 404.
+	Listing bool `yaml:"listing"`
+	// SPAFallback names a file, such as index.html, served in place of missing files other than
+	// assets with a file extension.
+	SPAFallback string `yaml:"spaFallback"`
+}
+
+type PerClientResponse struct {
+	Header     string           `yaml:"header"`
+	Cookie     string           `yaml:"cookie"`
+	TTL        string           `yaml:"ttl"`
+	MaxClients *int             `yaml:"maxClients"`
+	Response   ResponseStrategy `yaml:"response"`
+}
+
+const defaultMaxClients = 10000
+
+type ThresholdResponse struct {
+	Threshold int      `yaml:"threshold"`
+	Before    Response `yaml:"before"`
+	After     Response `yaml:"after"`
+}
+
+type NegotiatedResponse struct {
+	Responses []Representation `yaml:"responses"`
+	// Default is returned when no representation is acceptable, otherwise a 406 is returned.
+	Default *Response `yaml:"default"`
+}
+
+type Representation struct {
+	MediaType string   `yaml:"mediaType"`
+	Response  Response `yaml:"response"`
+}
+
+type JSONRPCResponse struct {
+	Methods map[string]JSONRPCMethod `yaml:"methods"`
+}
+
+// JSONRPCMethod sets either the result or the error returned by calls to a method. Each is a
+// response whose body is JSON, wrapped in the call's envelope.
+type JSONRPCMethod struct {
+	Result *Response `yaml:"result"`
+	// Error is an object with a code and message.
+	Error *Response `yaml:"error"`
+}
+
+type WindowResponse struct {
+	// Count is the number of requests allowed per window.
+	Count    int    `yaml:"count"`
+	Duration string `yaml:"duration"`
+	// Mode is either fixed (default) or sliding.
+	Mode     string   `yaml:"mode"`
+	Allowed  Response `yaml:"allowed"`
+	Exceeded Response `yaml:"exceeded"`
+}
+
+type MatchCase struct {
+	// Headers and Query must all be present on the request. An empty value matches any value.
+	Headers map[string]string `yaml:"headers"`
+	Query   map[string]string `yaml:"query"`
+	// HTTPVersion is the protocol version the request must use, such as HTTP/1.1 or HTTP/2.
+	HTTPVersion string `yaml:"httpVersion"`
+	// ClientCertName must be the common name or a DNS name of the client's TLS certificate.
+	ClientCertName string `yaml:"clientCertName"`
+	// BodySize bounds the request body's size, measured as it's read if its length isn't declared.
+	BodySize *BodySize `yaml:"bodySize"`
+	Response Response  `yaml:"response"`
+}
+
+// BodySize is an inclusive range of sizes, such as 1MB, either end of which may be left unset.
+type BodySize struct {
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+}
+
+func (b BodySize) toRest() (*rest.BodySizeRange, error) {
+	if b.Min == "" && b.Max == "" {
+		return nil, errors.New("bodySize requires a min or max")
+	}
+	var bounds rest.BodySizeRange
+	var err error
+	if b.Min != "" {
+		if bounds.Min, err = parseByteSize(b.Min); err != nil {
+			return nil, fmt.Errorf("invalid bodySize min: %w", err)
+		}
+	}
+	if b.Max != "" {
+		if bounds.Max, err = parseByteSize(b.Max); err != nil {
+			return nil, fmt.Errorf("invalid bodySize max: %w", err)
+		}
+		if bounds.Max == 0 {
+			return nil, errors.New("bodySize max must be >= 1")
+		}
+	}
+	return &bounds, nil
+}
+
+type ScheduledResponse struct {
+	Timezone string           `yaml:"timezone"`
+	Windows  []ScheduleWindow `yaml:"windows"`
+	Default  Response         `yaml:"default"`
+}
+
+type ScheduleWindow struct {
+	// Window is a daily time range formatted as HH:MM-HH:MM.
+	Window   string   `yaml:"window"`
+	Response Response `yaml:"response"`
+}
+
+type Response struct {
+	// Ref names one of the top-level responses to use instead. No other fields may be set with it.
+	Ref        string                  `yaml:"responseRef"`
+	StatusCode int                     `yaml:"status"`
+	Headers    map[string]HeaderValues `yaml:"headers"`
+	Body       ResponseBody            `yaml:"body"`
+	Delay      string                  `yaml:"delay"`
+	// DelayDistribution samples a delay per request instead of using a fixed delay.
+	DelayDistribution *DelayDistribution `yaml:"delayDistribution"`
+	// TTFB waits before writing anything, once the response is ready to be sent.
+	TTFB string `yaml:"ttfb"`
+	// Throttle caps the body transfer rate in bytes per second, with an optional KB, MB, or GB suffix.
+	Throttle string `yaml:"throttle"`
+	// ChunkSize sends the body with chunked transfer encoding in chunks of this many bytes, with an
+	// optional KB, MB, or GB suffix.
+	ChunkSize string `yaml:"chunkSize"`
+	// Compress gzips the body for clients that accept it. Static bodies are compressed at load.
+	Compress bool `yaml:"compress"`
+
+	// dir is the directory of the config file defining a top-level response, see Config.SetDir.
+	dir string
+}
+
+// HeaderValues are a response header's values. It's configured as a single string, or a list for
+// headers that repeat, such as Set-Cookie.
+type HeaderValues []string
+
+func (h *HeaderValues) UnmarshalYAML(unmarshal func(any) error) error {
+	var values []string
+	if err := unmarshal(&values); err == nil {
+		*h = values
+		return nil
+	}
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	*h = HeaderValues{value}
+	return nil
+}
+
+type DelayDistribution struct {
+	// Type is one of fixed, normal, exponential, or lognormal.
+	Type   string `yaml:"type"`
+	Mean   string `yaml:"mean"`
+	StdDev string `yaml:"stddev"`
+}
+
+type ResponseBody struct {
+	Literal  string `yaml:"literal"`
+	FilePath string `yaml:"filePath"`
+	// Template is rendered per request with Go's text/template syntax.
+	Template string `yaml:"template"`
+	// Base64 is decoded once at load time, for inline binary payloads.
+	Base64 string `yaml:"base64"`
+	// URL is fetched once at load time and served as a static body.
+	URL        string `yaml:"url"`
+	URLTimeout string `yaml:"urlTimeout"`
+	// Dir responds with a randomly chosen file from the directory per request.
+	Dir string `yaml:"dir"`
+	// MissingStatus is returned if a watched filePath is removed while running, default 404.
+	MissingStatus int `yaml:"missingStatus"`
+	// Generate streams deterministic pseudo-random bytes, for load testing.
+	Generate *GeneratedBody `yaml:"generate"`
+}
+
+type GeneratedBody struct {
+	// Size is a byte count with an optional KB, MB, or GB suffix, e.g. 512KB.
+	Size string `yaml:"size"`
+	Seed uint64 `yaml:"seed"`
+}
+
+func (d DelayDistribution) toRest() (rest.DelayDistribution, error) {
+	if d.Type == "" {
+		return rest.DelayDistribution{}, errors.New("delayDistribution type is required")
+	}
+	dist := rest.DelayDistribution{Type: rest.DelayDistributionType(d.Type)}
+	if d.Mean != "" {
+		mean, err := time.ParseDuration(d.Mean)
+		if err != nil {
+			return rest.DelayDistribution{}, fmt.Errorf("invalid delayDistribution mean %q", d.Mean)
+		}
+		dist.Mean = mean
+	}
+	if d.StdDev != "" {
+		stdDev, err := time.ParseDuration(d.StdDev)
+		if err != nil {
+			return rest.DelayDistribution{}, fmt.Errorf("invalid delayDistribution stddev %q", d.StdDev)
+		}
+		dist.StdDev = stdDev
+	}
+	return dist, nil
+}
+
+// convertContext carries endpoint-level state needed while converting config into rest types.
+type convertContext struct {
+	// pathParams are the wildcard names declared by the endpoint's path.
+	pathParams []string
+	// files tracks file bodies for reloading, nil unless files are watched.
+	files *rest.BodyFiles
+	// responses are the named responses referenced by responseRef.
+	responses map[string]Response
+	// defaults are merged into every response, nil for responses outside of an endpoint's response
+	// strategy.
+	defaults *ResponseDefaults
+	// dir is the directory relative file paths are resolved against.
+	dir string
+}
+
+// resolveRef returns the named response r refers to, following references until reaching a
+// response that isn't one. Responses without a reference are returned as-is.
+func (conv convertContext) resolveRef(r Response) (Response, error) {
+	var chain []string
+	for r.Ref != "" {
+		name := r.Ref
+		r.Ref, r.dir = "", ""
+		if !reflect.ValueOf(r).IsZero() {
+			return Response{}, fmt.Errorf("response referencing %q cannot set other fields", name)
+		}
+		if slices.Contains(chain, name) {
+			return Response{}, fmt.Errorf("response reference cycle: %s", strings.Join(append(chain, name), " -> "))
+		}
+		chain = append(chain, name)
+
+		named, ok := conv.responses[name]
+		if !ok {
+			return Response{}, fmt.Errorf("unknown response %q", name)
+		}
+		r = named
+	}
+	return r, nil
 }
 
-type WeightedResponse struct {
-	Weight   int      `yaml:"weight"`
-	Response Response `yaml:"response"`
-}
+// checkResponseRefs resolves every named response, so broken references are reported even if
+// the response is never used.
+func (c Config) checkResponseRefs() error {
+	conv := convertContext{responses: c.Responses}
+	for _, name := range slices.Sorted(maps.Keys(c.Responses)) {
+		if _, err := conv.resolveRef(c.Responses[name]); err != nil {
+			return fmt.Errorf("named response %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RestEndpoints builds the configured endpoints. Body files are tracked in files when it's non-nil.
+func (c Config) RestEndpoints(files *rest.BodyFiles) ([]*rest.Endpoint, error) {
+	var endpoints []*rest.Endpoint
+	ids := make(map[string]bool)
+	routes := make(map[string]bool)
+
+	basePath, err := c.Server.PathPrefix()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkResponseRefs(); err != nil {
+		return nil, err
+	}
+
+	addEndpoint := func(endpoint *rest.Endpoint) error {
+		if ids[endpoint.ID()] {
+			return fmt.Errorf("duplicate endpoint id %q", endpoint.ID())
+		}
+		ids[endpoint.ID()] = true
+		route := cmp.Or(endpoint.Method, rest.MethodAll) + " " + endpoint.Path
+		if routes[route] {
+			return fmt.Errorf("endpoint %s is defined more than once", route)
+		}
+		routes[route] = true
+		endpoints = append(endpoints, endpoint)
+		return nil
+	}
+
+	// methods of the endpoints for each path, for the Allow header of OPTIONS responses
+	pathMethods := make(map[string][]string)
+	// paths with an OPTIONS endpoint, configured with options or added for CORS preflights
+	optionsPaths := make(map[string]bool)
+	for _, endpointCfg := range c.Endpoints {
+		key := endpointCfg.pathKey()
+		pathMethods[key] = append(pathMethods[key], strings.ToUpper(endpointCfg.Method))
+		if endpointCfg.Options != nil {
+			optionsPaths[key] = true
+		}
+	}
+
+	for _, endpointCfg := range c.Endpoints {
+		conv := convertContext{
+			pathParams: rest.PathParams(endpointCfg.Path),
+			files:      files,
+			responses:  c.Responses,
+			dir:        endpointCfg.dir,
+		}
+
+		path := basePath + endpointCfg.Path
+		// pathOpts are shared with the endpoint's OPTIONS sibling
+		var pathOpts []rest.EndpointOption
+		switch endpointCfg.PathType {
+		case "", "pattern":
+		case "regex":
+			path = regexp.QuoteMeta(basePath) + endpointCfg.Path
+			params, err := rest.RegexPathParams(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path regex for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			conv.pathParams = params
+			pathOpts = append(pathOpts, rest.WithPathRegex())
+		default:
+			return nil, fmt.Errorf("unknown pathType %q for endpoint %q", endpointCfg.PathType, endpointCfg.Path)
+		}
+		var endpointOpts []rest.EndpointOption
+
+		// defaults only apply to the endpoint's response strategy, not the responses of its other
+		// settings like a rate limit's 429
+		strategyConv := conv
+		if c.Defaults != nil {
+			strategyConv.defaults = c.Defaults.Response
+		}
+		resolver, err := endpointCfg.ResponseStrategy.toRest(strategyConv)
+		if err != nil {
+			return nil, fmt.Errorf("build response strategy for endpoint %q: %w", endpointCfg.Path, err)
+		}
+
+		if len(endpointCfg.Faults) > 0 {
+			faulted, err := convertFaultsToRest(conv, resolver, endpointCfg.Faults)
+			if err != nil {
+				return nil, fmt.Errorf("build faults for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			resolver = faulted
+		}
+		if endpointCfg.Outage != nil {
+			outage, err := endpointCfg.Outage.toRest(conv, resolver)
+			if err != nil {
+				return nil, fmt.Errorf("build outage for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			resolver = outage
+		}
+
+		if endpointCfg.RateLimit != nil {
+			rateLimit, err := endpointCfg.RateLimit.toRest(conv)
+			if err != nil {
+				return nil, fmt.Errorf("build rate limit for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			endpointOpts = append(endpointOpts, rest.WithRateLimit(rateLimit))
+		}
+		if endpointCfg.MaxConcurrency != nil {
+			limit, err := endpointCfg.MaxConcurrency.toRest(conv)
+			if err != nil {
+				return nil, fmt.Errorf("build max concurrency for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			endpointOpts = append(endpointOpts, rest.WithConcurrencyLimit(limit))
+		}
+		if (endpointCfg.ETag == nil && c.ETag) || (endpointCfg.ETag != nil && *endpointCfg.ETag) {
+			endpointOpts = append(endpointOpts, rest.WithETag())
+		}
+		if endpointCfg.LastModified != "" {
+			lastModified, err := time.Parse(time.RFC3339, endpointCfg.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lastModified %q for endpoint %q", endpointCfg.LastModified, endpointCfg.Path)
+			}
+			endpointOpts = append(endpointOpts, rest.WithLastModified(lastModified))
+		}
+
+		if endpointCfg.ID != "" {
+			endpointOpts = append(endpointOpts, rest.WithID(endpointCfg.ID))
+		}
+		if endpointCfg.DisabledResponse != nil {
+			resp, err := endpointCfg.DisabledResponse.toRestWithStatus(conv, http.StatusServiceUnavailable)
+			if err != nil {
+				return nil, fmt.Errorf("build disabled response for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			endpointOpts = append(endpointOpts, rest.WithDisabledResponse(resp))
+		}
+		if len(endpointCfg.RequiredQuery) > 0 {
+			var missingResp *rest.Response
+			if endpointCfg.MissingQueryResponse != nil {
+				resp, err := endpointCfg.MissingQueryResponse.toRestWithStatus(conv, http.StatusBadRequest)
+				if err != nil {
+					return nil, fmt.Errorf("build missing query response for endpoint %q: %w", endpointCfg.Path, err)
+				}
+				missingResp = &resp
+			}
+			endpointOpts = append(endpointOpts, rest.WithRequiredQuery(endpointCfg.RequiredQuery, missingResp))
+		} else if endpointCfg.MissingQueryResponse != nil {
+			return nil, fmt.Errorf("missingQueryResponse requires requiredQuery for endpoint %q", endpointCfg.Path)
+		}
+		requestSchema, err := endpointCfg.compileRequestSchema()
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", endpointCfg.Path, err)
+		}
+		if requestSchema != nil {
+			var invalidResp *rest.Response
+			if endpointCfg.InvalidRequestResponse != nil {
+				resp, err := endpointCfg.InvalidRequestResponse.toRestWithStatus(conv, http.StatusBadRequest)
+				if err != nil {
+					return nil, fmt.Errorf("build invalid request response for endpoint %q: %w", endpointCfg.Path, err)
+				}
+				invalidResp = &resp
+			}
+			endpointOpts = append(endpointOpts, rest.WithRequestSchema(requestSchema, invalidResp))
+		} else if endpointCfg.InvalidRequestResponse != nil {
+			return nil, fmt.Errorf("invalidRequestResponse requires requestSchema for endpoint %q", endpointCfg.Path)
+		}
+		if len(endpointCfg.ReflectHeaders) > 0 {
+			endpointOpts = append(endpointOpts, rest.WithReflectHeaders(endpointCfg.ReflectHeaders, endpointCfg.ReflectHeadersOverride))
+		}
+		if endpointCfg.CloseConnection {
+			endpointOpts = append(endpointOpts, rest.WithCloseConnection())
+		}
+		if endpointCfg.RawHeaderCase {
+			endpointOpts = append(endpointOpts, rest.WithRawHeaderCase())
+		}
+		if endpointCfg.Capture != nil {
+			capture, err := endpointCfg.Capture.toRest(endpointCfg.dir)
+			if err != nil {
+				return nil, fmt.Errorf("invalid capture for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			endpointOpts = append(endpointOpts, capture)
+		}
+		if endpointCfg.ExpectContinue != nil {
+			expect, err := endpointCfg.ExpectContinue.toRest()
+			if err != nil {
+				return nil, fmt.Errorf("invalid expectContinue for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			endpointOpts = append(endpointOpts, expect)
+		}
+		if endpointCfg.MaxResponseBytes != nil {
+			endpointOpts = append(endpointOpts, rest.WithMaxResponseBytes(*endpointCfg.MaxResponseBytes, endpointCfg.FullContentLength))
+		} else if endpointCfg.FullContentLength {
+			return nil, fmt.Errorf("fullContentLength requires maxResponseBytes for endpoint %q", endpointCfg.Path)
+		}
+		if endpointCfg.MethodFallthrough != nil && !*endpointCfg.MethodFallthrough {
+			if endpointCfg.PathType == "regex" {
+				return nil, fmt.Errorf("methodFallthrough can only be disabled for pattern paths, for endpoint %q", endpointCfg.Path)
+			}
+			if slices.ContainsFunc(pathMethods[endpointCfg.pathKey()], func(m string) bool { return rest.NormalizeMethod(m) == "" }) {
+				return nil, fmt.Errorf("methodFallthrough can't be disabled for path %q, which has an endpoint matching every method", endpointCfg.Path)
+			}
+			endpointOpts = append(endpointOpts, rest.WithoutMethodFallthrough())
+		}
+		if len(endpointCfg.Middleware) > 0 {
+			var mws []rest.Middleware
+			for _, name := range endpointCfg.Middleware {
+				mw, err := rest.LookupMiddleware(name)
+				if err != nil {
+					return nil, fmt.Errorf("build middleware for endpoint %q: %w", endpointCfg.Path, err)
+				}
+				mws = append(mws, mw)
+			}
+			pathOpts = append(pathOpts, rest.WithMiddleware(mws...))
+		}
+
+		endpointOpts = append(endpointOpts, pathOpts...)
+		endpoint, err := rest.NewEndpoint(path, endpointCfg.Method, resolver, endpointOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("build endpoint %q: %w", endpointCfg.Path, err)
+		}
+		if err := addEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+
+		key := endpointCfg.pathKey()
+		if endpointCfg.Options == nil && endpointCfg.corsPreflightRoute(pathMethods[key]) && !optionsPaths[key] {
+			// CORS preflights are OPTIONS requests, which need a route to reach the cors middleware
+			endpointCfg.Options = &Response{}
+			optionsPaths[key] = true
+		}
+		if endpointCfg.Options != nil {
+			optionsEndpoint, err := endpointCfg.optionsEndpoint(conv, path, pathMethods[key], pathOpts)
+			if err != nil {
+				return nil, fmt.Errorf("build options response for endpoint %q: %w", endpointCfg.Path, err)
+			}
+			if err := addEndpoint(optionsEndpoint); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+// pathKey identifies the endpoint's path along with how it's matched, so endpoints for the same
+// path share an OPTIONS response.
+func (e Endpoint) pathKey() string {
+	return cmp.Or(e.PathType, "pattern") + " " + e.Path
+}
+
+// corsPreflightRoute reports whether the endpoint uses the cors middleware on a path whose endpoints,
+// with the given methods, don't already route OPTIONS requests to it.
+func (e Endpoint) corsPreflightRoute(methods []string) bool {
+	if !slices.Contains(e.Middleware, "cors") {
+		return false
+	}
+	return !slices.ContainsFunc(methods, func(m string) bool {
+		return m == "" || m == "*" || m == rest.MethodAll || m == http.MethodOptions
+	})
+}
+
+// optionsEndpoint builds the endpoint answering OPTIONS requests for the endpoint's path, whose
+// endpoints have the given methods.
+func (e Endpoint) optionsEndpoint(conv convertContext, path string, methods []string, pathOpts []rest.EndpointOption) (*rest.Endpoint, error) {
+	if slices.ContainsFunc(methods, func(m string) bool { return m == "" || m == "*" || m == rest.MethodAll }) {
+		return nil, errors.New("options can't be set for a path with an endpoint matching every method")
+	}
+	optionsCfg, err := conv.resolveRef(*e.Options)
+	if err != nil {
+		return nil, err
+	}
+	if !hasHeader(optionsCfg.Headers, "Allow") {
+		allow := slices.Clone(methods)
+		allow = append(allow, http.MethodOptions)
+		if slices.Contains(allow, http.MethodGet) {
+			allow = append(allow, http.MethodHead) // GET endpoints also serve HEAD
+		}
+		slices.Sort(allow)
+		optionsCfg.Headers = maps.Clone(optionsCfg.Headers)
+		if optionsCfg.Headers == nil {
+			optionsCfg.Headers = make(map[string]HeaderValues, 1)
+		}
+		optionsCfg.Headers["Allow"] = HeaderValues{strings.Join(slices.Compact(allow), ", ")}
+	}
+	resp, err := optionsCfg.toRestWithStatus(conv, http.StatusNoContent)
+	if err != nil {
+		return nil, err
+	}
+	return rest.NewEndpoint(path, http.MethodOptions, rest.StaticResponse(resp), pathOpts...)
+}
+
+func (s ResponseStrategy) toRest(conv convertContext) (rest.ResponseResolver, error) {
+	var resolver rest.ResponseResolver
+	var strategyCount int
+	if s.Static != nil {
+		strategyCount++
+		resp, err := s.Static.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build response: %w", err)
+		}
+		resolver = rest.StaticResponse(resp)
+	}
+	if s.Weighted != nil {
+		strategyCount++
+		resp, err := convertWeightedToRest(conv, s.Weighted, s.WeightRamp)
+		if err != nil {
+			return nil, fmt.Errorf("build weighted response: %w", err)
+		}
+		resolver = resp
+		if s.Sticky != nil {
+			opts, err := perClientOptions(s.Sticky.Header, s.Sticky.Cookie, s.Sticky.TTL, s.Sticky.MaxSessions)
+			if err != nil {
+				return nil, fmt.Errorf("build sticky weighted response: %w", err)
+			}
+			resolver, err = rest.NewStickyResponse(resp, opts, nil)
+			if err != nil {
+				return nil, fmt.Errorf("build sticky weighted response: %w", err)
+			}
+		}
+	}
+	if s.WeightRamp != nil && s.Weighted == nil {
+		return nil, errors.New("weightRamp requires weighted responses")
+	}
+	if s.Sticky != nil && s.Weighted == nil {
+		return nil, errors.New("sticky requires weighted responses")
+	}
+	if s.Sequence != nil {
+		strategyCount++
+		resp, err := convertSequencedToRest(conv, s.Sequence)
+		if err != nil {
+			return nil, fmt.Errorf("build sequenced response: %w", err)
+		}
+		resolver = resp
+	}
+	if s.Schedule != nil {
+		strategyCount++
+		resp, err := convertScheduledToRest(conv, s.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("build scheduled response: %w", err)
+		}
+		resolver = resp
+	}
+	if s.AfterCount != nil {
+		strategyCount++
+		resp, err := convertThresholdToRest(conv, s.AfterCount)
+		if err != nil {
+			return nil, fmt.Errorf("build after count response: %w", err)
+		}
+		resolver = resp
+	}
+	if s.FirstRequest != nil || s.Thereafter != nil {
+		strategyCount++
+		if s.FirstRequest == nil || s.Thereafter == nil {
+			return nil, errors.New("firstRequest and thereafter must be set together")
+		}
+		resp, err := convertThresholdToRest(conv, &ThresholdResponse{
+			Threshold: 1,
+			Before:    *s.FirstRequest,
+			After:     *s.Thereafter,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("build first request response: %w", err)
+		}
+		resolver = resp
+	}
+	if s.PerClient != nil {
+		strategyCount++
+		resp, err := convertPerClientToRest(conv, s.PerClient)
+		if err != nil {
+			return nil, fmt.Errorf("build per client response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if s.SSE != nil {
+		strategyCount++
+		resp, err := s.SSE.toRest()
+		if err != nil {
+			return nil, fmt.Errorf("build sse response: %w", err)
+		}
+		resolver = rest.StaticResponse(resp)
+	}
+
+	if s.WebSocket != nil {
+		strategyCount++
+		resp, err := s.WebSocket.toRest()
+		if err != nil {
+			return nil, fmt.Errorf("build websocket response: %w", err)
+		}
+		resolver = rest.StaticResponse(resp)
+	}
+
+	if s.Drip != nil {
+		strategyCount++
+		resp, err := s.Drip.toRest()
+		if err != nil {
+			return nil, fmt.Errorf("build drip response: %w", err)
+		}
+		resolver = rest.StaticResponse(resp)
+	}
+
+	if s.StaticDir != nil {
+		strategyCount++
+		resp, err := s.StaticDir.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build static dir response: %w", err)
+		}
+		resolver = rest.StaticResponse(resp)
+	}
+
+	if s.Representations != nil {
+		strategyCount++
+		resp, err := convertNegotiatedToRest(conv, s.Representations)
+		if err != nil {
+			return nil, fmt.Errorf("build representations response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if s.Window != nil {
+		strategyCount++
+		resp, err := convertWindowToRest(conv, s.Window)
+		if err != nil {
+			return nil, fmt.Errorf("build window response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if s.Match != nil {
+		strategyCount++
+		resp, err := convertMatchToRest(conv, s.Match)
+		if err != nil {
+			return nil, fmt.Errorf("build match response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if s.Fallthrough != nil {
+		strategyCount++
+		resp, err := convertFallthroughToRest(conv, s.Fallthrough)
+		if err != nil {
+			return nil, fmt.Errorf("build fallthrough response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if s.JSONRPC != nil {
+		strategyCount++
+		resp, err := convertJSONRPCToRest(conv, s.JSONRPC)
+		if err != nil {
+			return nil, fmt.Errorf("build jsonrpc response: %w", err)
+		}
+		resolver = resp
+	}
+
+	if resolver == nil || strategyCount != 1 {
+		return nil, fmt.Errorf("must have exactly one response strategy but had %d", strategyCount)
+	}
+
+	return resolver, nil
+}
+
+// Warmup builds the server's startup warmup, or returns nil if it has no startup delay.
+func (c Config) Warmup(files *rest.BodyFiles) (*rest.Warmup, error) {
+	if c.Server.StartupDelay == "" {
+		if c.Server.StartupResponse != nil {
+			return nil, errors.New("startupResponse requires startupDelay")
+		}
+		return nil, nil
+	}
+	delay, err := time.ParseDuration(c.Server.StartupDelay)
+	if err != nil {
+		return nil, fmt.Errorf("invalid startupDelay %q", c.Server.StartupDelay)
+	}
+	if delay <= 0 {
+		return nil, fmt.Errorf("startupDelay must be positive: %s", c.Server.StartupDelay)
+	}
+
+	var startupResp Response
+	if c.Server.StartupResponse != nil {
+		startupResp = *c.Server.StartupResponse
+	}
+	conv := convertContext{files: files, responses: c.Responses}
+	resp, err := startupResp.toRestWithStatus(conv, http.StatusServiceUnavailable)
+	if err != nil {
+		return nil, fmt.Errorf("build startup response: %w", err)
+	}
+	return rest.NewWarmup(delay, resp), nil
+}
+
+// RequestTimeout builds the server's request timeout, or returns nil if it has none.
+func (c Config) RequestTimeout(files *rest.BodyFiles) (*rest.RequestTimeout, error) {
+	if c.Server.RequestTimeout == "" {
+		if c.Server.RequestTimeoutResponse != nil {
+			return nil, errors.New("requestTimeoutResponse requires requestTimeout")
+		}
+		return nil, nil
+	}
+	timeout, err := time.ParseDuration(c.Server.RequestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requestTimeout %q", c.Server.RequestTimeout)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("requestTimeout must be positive: %s", c.Server.RequestTimeout)
+	}
+
+	var timeoutResp Response
+	if c.Server.RequestTimeoutResponse != nil {
+		timeoutResp = *c.Server.RequestTimeoutResponse
+	}
+	conv := convertContext{files: files, responses: c.Responses}
+	resp, err := timeoutResp.toRestWithStatus(conv, http.StatusGatewayTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("build request timeout response: %w", err)
+	}
+	return rest.NewRequestTimeout(timeout, resp), nil
+}
+
+// ShutdownDrain returns how long the server answers new requests with the returned response after
+// a shutdown signal, which is 0 if it shuts down right away. The response is nil for the default 503.
+func (c Config) ShutdownDrain(files *rest.BodyFiles) (time.Duration, *rest.Response, error) {
+	if c.Server.ShutdownDrain == "" {
+		if c.Server.ShutdownResponse != nil {
+			return 0, nil, errors.New("shutdownResponse requires shutdownDrain")
+		}
+		return 0, nil, nil
+	}
+	window, err := time.ParseDuration(c.Server.ShutdownDrain)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid shutdownDrain %q", c.Server.ShutdownDrain)
+	}
+	if window <= 0 {
+		return 0, nil, fmt.Errorf("shutdownDrain must be positive: %s", c.Server.ShutdownDrain)
+	}
+	if c.Server.ShutdownResponse == nil {
+		return window, nil, nil
+	}
+
+	conv := convertContext{files: files, responses: c.Responses}
+	resp, err := c.Server.ShutdownResponse.toRestWithStatus(conv, http.StatusServiceUnavailable)
+	if err != nil {
+		return 0, nil, fmt.Errorf("build shutdown response: %w", err)
+	}
+	return window, &resp, nil
+}
+
+// Fallbacks builds the responses for requests matching no endpoint.
+func (c Config) Fallbacks(files *rest.BodyFiles) (rest.Fallbacks, error) {
+	var fallbacks rest.Fallbacks
+	conv := convertContext{files: files, responses: c.Responses}
+
+	if c.NotFound != nil {
+		resp, err := c.NotFound.toRestWithStatus(conv, http.StatusNotFound)
+		if err != nil {
+			return rest.Fallbacks{}, fmt.Errorf("build not found response: %w", err)
+		}
+		fallbacks.NotFound = &resp
+	}
+	if c.MethodNotAllowed != nil {
+		resp, err := c.MethodNotAllowed.toRestWithStatus(conv, http.StatusMethodNotAllowed)
+		if err != nil {
+			return rest.Fallbacks{}, fmt.Errorf("build method not allowed response: %w", err)
+		}
+		fallbacks.MethodNotAllowed = &resp
+	}
+	if c.TooLarge != nil {
+		resp, err := c.TooLarge.toRestWithStatus(conv, http.StatusRequestEntityTooLarge)
+		if err != nil {
+			return rest.Fallbacks{}, fmt.Errorf("build too large response: %w", err)
+		}
+		fallbacks.TooLarge = &resp
+	}
+
+	return fallbacks, nil
+}
+
+// Timeouts parses the configured server timeouts, falling back to defaults for any left unset.
+func (s Server) Timeouts() (ServerTimeouts, error) {
+	timeouts := defaultServerTimeouts
+
+	fields := []struct {
+		name string
+		val  string
+		dest *time.Duration
+	}{
+		{"readTimeout", s.ReadTimeout, &timeouts.Read},
+		{"readHeaderTimeout", s.ReadHeaderTimeout, &timeouts.ReadHeader},
+		{"writeTimeout", s.WriteTimeout, &timeouts.Write},
+		{"idleTimeout", s.IdleTimeout, &timeouts.Idle},
+	}
+	for _, field := range fields {
+		if field.val == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.val)
+		if err != nil {
+			return ServerTimeouts{}, fmt.Errorf("invalid %s %q", field.name, field.val)
+		}
+		if d < 0 {
+			return ServerTimeouts{}, fmt.Errorf("%s cannot be negative: %s", field.name, field.val)
+		}
+		*field.dest = d
+	}
+
+	return timeouts, nil
+}
+
+// PathPrefix returns the validated base path, without a trailing slash.
+func (s Server) PathPrefix() (string, error) {
+	if s.BasePath == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(s.BasePath, "/") {
+		return "", fmt.Errorf("basePath must start with /: %q", s.BasePath)
+	}
+	return strings.TrimSuffix(s.BasePath, "/"), nil
+}
+
+// RequestBytesLimit returns the maximum request body size, where 0 means unlimited.
+func (s Server) RequestBytesLimit() (int64, error) {
+	if s.MaxRequestBytes == nil {
+		return defaultMaxRequestBytes, nil
+	}
+	if *s.MaxRequestBytes < 0 {
+		return 0, fmt.Errorf("maxRequestBytes cannot be negative: %d", *s.MaxRequestBytes)
+	}
+	return *s.MaxRequestBytes, nil
+}
+
+const defaultDrainGracePeriod = 5 * time.Second
+
+// DrainGrace returns how long the server answers requests with a 503 after being drained, before
+// dropping their connections.
+func (s Server) DrainGrace() (time.Duration, error) {
+	if s.DrainGracePeriod == "" {
+		return defaultDrainGracePeriod, nil
+	}
+	grace, err := time.ParseDuration(s.DrainGracePeriod)
+	if err != nil {
+		return 0, fmt.Errorf("invalid drainGracePeriod %q", s.DrainGracePeriod)
+	}
+	if grace < 0 {
+		return 0, fmt.Errorf("drainGracePeriod must not be negative: %s", s.DrainGracePeriod)
+	}
+	return grace, nil
+}
+
+// KeepAlivesEnabled reports whether HTTP keep-alives are enabled.
+func (s Server) KeepAlivesEnabled() bool {
+	return s.KeepAlive == nil || *s.KeepAlive
+}
+
+// ConnectionLimit returns the maximum number of open connections, where 0 means unlimited.
+func (s Server) ConnectionLimit() (int, error) {
+	if s.MaxConnections == nil {
+		return 0, nil
+	}
+	if *s.MaxConnections <= 0 {
+		return 0, fmt.Errorf("maxConnections must be positive: %d", *s.MaxConnections)
+	}
+	return *s.MaxConnections, nil
+}
+
+// ServerListeners returns the configured listeners, or a single plaintext listener on defaultAddr
+// if none are configured.
+func (s Server) ServerListeners(defaultAddr string) ([]Listener, error) {
+	if len(s.Listeners) == 0 {
+		return []Listener{{Addr: defaultAddr}}, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, listener := range s.Listeners {
+		if listener.Addr == "" {
+			return nil, errors.New("listener addr is required")
+		}
+		if seen[listener.Addr] {
+			return nil, fmt.Errorf("duplicate listener addr %q", listener.Addr)
+		}
+		seen[listener.Addr] = true
+
+		if listener.TLS != nil && (listener.TLS.CertFile == "" || listener.TLS.KeyFile == "") {
+			return nil, fmt.Errorf("listener %q tls requires both certFile and keyFile", listener.Addr)
+		}
+	}
+
+	return s.Listeners, nil
+}
+
+// toRestWithStatus converts the response, defaulting to statusCode rather than 200 when unset.
+func (r Response) toRestWithStatus(conv convertContext, statusCode int) (rest.Response, error) {
+	r, err := conv.resolveRef(r)
+	if err != nil {
+		return rest.Response{}, err
+	}
+	if r.StatusCode == 0 {
+		r.StatusCode = statusCode
+	}
+	return r.toRest(conv)
+}
+
+// toRest builds the response, applying opts after the configured options.
+func (r Response) toRest(conv convertContext, opts ...rest.ResponseOption) (rest.Response, error) {
+	r, err := conv.resolveRef(r)
+	if err != nil {
+		return rest.Response{}, err
+	}
+	r = conv.defaults.apply(r)
+	if r.dir != "" {
+		conv.dir = r.dir
+	}
+
+	var respOpts []rest.ResponseOption
+
+	if len(r.Headers) > 0 {
+		single := make(map[string]string, len(r.Headers))
+		multi := make(map[string][]string)
+		// headers without template syntax are written as-is, without rendering them per request
+		templated := make(map[string][]string)
+		for k, v := range r.Headers {
+			switch {
+			case slices.ContainsFunc(v, rest.IsTemplate):
+				templated[k] = v
+			case len(v) == 1:
+				single[k] = v[0]
+			default:
+				multi[k] = v
+			}
+		}
+		respOpts = append(respOpts, rest.WithResponseHeaders(single))
+		if len(multi) > 0 {
+			respOpts = append(respOpts, rest.WithResponseHeadersMulti(multi))
+		}
+		if len(templated) > 0 {
+			respOpts = append(respOpts, rest.WithResponseHeaderTemplates(templated, conv.pathParams))
+		}
+	}
+
+	if r.StatusCode != 0 {
+		respOpts = append(respOpts, rest.WithResponseStatus(r.StatusCode))
+	}
+
+	if len(r.Delay) > 0 {
+		d, err := time.ParseDuration(r.Delay)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid response delay %q", r.Delay)
+		}
+		respOpts = append(respOpts, rest.WithResponseDelay(d))
+	}
+
+	if r.DelayDistribution != nil {
+		if r.Delay != "" {
+			return rest.Response{}, errors.New("response delay and delayDistribution are mutually exclusive")
+		}
+		dist, err := r.DelayDistribution.toRest()
+		if err != nil {
+			return rest.Response{}, err
+		}
+		respOpts = append(respOpts, rest.WithDelayDistribution(dist))
+	}
+
+	if r.TTFB != "" {
+		d, err := time.ParseDuration(r.TTFB)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid response ttfb %q", r.TTFB)
+		}
+		respOpts = append(respOpts, rest.WithTimeToFirstByte(d))
+	}
+
+	if r.Throttle != "" {
+		rate, err := parseByteSize(r.Throttle)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid response throttle: %w", err)
+		}
+		if rate > math.MaxInt32 {
+			return rest.Response{}, fmt.Errorf("response throttle too large: %s", r.Throttle)
+		}
+		respOpts = append(respOpts, rest.WithThrottle(int(rate)))
+	}
+
+	if r.ChunkSize != "" {
+		size, err := parseByteSize(r.ChunkSize)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid response chunkSize: %w", err)
+		}
+		if size > math.MaxInt32 {
+			return rest.Response{}, fmt.Errorf("response chunkSize too large: %s", r.ChunkSize)
+		}
+		respOpts = append(respOpts, rest.WithChunkSize(int(size)))
+	}
+
+	if r.Compress {
+		respOpts = append(respOpts, rest.WithCompression())
+	}
+
+	var bodySources int
+	for _, source := range []string{r.Body.Literal, r.Body.FilePath, r.Body.Template, r.Body.Base64, r.Body.URL, r.Body.Dir} {
+		if source != "" {
+			bodySources++
+		}
+	}
+	if r.Body.Generate != nil {
+		bodySources++
+	}
+	if bodySources > 1 {
+		return rest.Response{}, errors.New("response body must use only one of literal, filePath, template, base64, url, dir, and generate")
+	}
+	if r.Body.Template != "" {
+		respOpts = append(respOpts, rest.WithResponseTemplate(r.Body.Template, conv.pathParams))
+	}
+	respBody := []byte(r.Body.Literal)
+	if r.Body.FilePath != "" {
+		respOpts = append(respOpts, rest.WithResponseBodyFile(resolvePath(conv.dir, r.Body.FilePath), conv.files))
+		if r.Body.MissingStatus != 0 {
+			respOpts = append(respOpts, rest.WithMissingFileStatus(r.Body.MissingStatus))
+		}
+	}
+	if r.Body.Dir != "" {
+		respOpts = append(respOpts, rest.WithResponseBodyDir(resolvePath(conv.dir, r.Body.Dir)))
+	}
+	if r.Body.Base64 != "" {
+		data, err := base64.StdEncoding.DecodeString(r.Body.Base64)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("decode base64 body: %w", err)
+		}
+		respBody = data
+	}
+	if r.Body.Generate != nil {
+		size, err := parseByteSize(r.Body.Generate.Size)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid generated body size: %w", err)
+		}
+		respOpts = append(respOpts, rest.WithGeneratedBody(size, r.Body.Generate.Seed))
+	}
+	if r.Body.URL != "" {
+		data, err := r.Body.fetchURL()
+		if err != nil {
+			return rest.Response{}, err
+		}
+		respBody = data
+	}
+	if len(respBody) > 0 {
+		respOpts = append(respOpts, rest.WithResponseBody(respBody))
+	}
+	respOpts = append(respOpts, opts...)
+
+	resp, err := rest.NewResponse(respOpts...)
+	if err != nil {
+		return rest.Response{}, fmt.Errorf("build response: %w", err)
+	}
 
-type SequencedResponse struct {
-	EndBehavior string                   `yaml:"endBehavior"`
-	Responses   []SequencedResponseEntry `yaml:"responses"`
+	return resp, nil
 }
 
-type SequencedResponseEntry struct {
-	Count    *int     `yaml:"count"`
-	Response Response `yaml:"response"`
-}
+// parseByteSize parses a byte count with an optional binary KB, MB, or GB suffix.
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
 
-type Response struct {
-	StatusCode int               `yaml:"status"`
-	Headers    map[string]string `yaml:"headers"`
-	Body       ResponseBody      `yaml:"body"`
-	Delay      string            `yaml:"delay"`
-}
+	s = strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, unit := range units {
+		if trimmed, ok := strings.CutSuffix(strings.ToUpper(s), unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(trimmed), unit.multiplier
+			break
+		}
+	}
 
-type ResponseBody struct {
-	Literal  string `yaml:"literal"`
-	FilePath string `yaml:"filePath"`
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse byte size %q: %w", s, err)
+	}
+	if n > math.MaxInt64/multiplier || n < math.MinInt64/multiplier {
+		return 0, fmt.Errorf("byte size %q overflows", s)
+	}
+	return n * multiplier, nil
 }
 
-func (c Config) RestEndpoints() ([]*rest.Endpoint, error) {
-	var endpoints []*rest.Endpoint
-
-	for _, endpointCfg := range c.Endpoints {
-		strategy := endpointCfg.ResponseStrategy
+const defaultBodyURLTimeout = 10 * time.Second
 
-		var resolver rest.ResponseResolver
-		var strategyCount int
-		if strategy.Static != nil {
-			strategyCount++
-			resp, err := strategy.Static.toRest()
-			if err != nil {
-				return nil, fmt.Errorf("build response for endpoint %q: %w", endpointCfg.Path, err)
-			}
-			resolver = rest.StaticResponse(resp)
-		}
-		if strategy.Weighted != nil {
-			strategyCount++
-			resp, err := convertWeightedToRest(strategy.Weighted)
-			if err != nil {
-				return nil, fmt.Errorf("build weighted response for endpoint %q: %w", endpointCfg.Path, err)
-			}
-			resolver = resp
-		}
-		if strategy.Sequence != nil {
-			strategyCount++
-			resp, err := convertSequencedToRest(strategy.Sequence)
-			if err != nil {
-				return nil, fmt.Errorf("build sequenced response for endpoint %q: %w", endpointCfg.Path, err)
-			}
-			resolver = resp
+func (b ResponseBody) fetchURL() ([]byte, error) {
+	timeout := defaultBodyURLTimeout
+	if b.URLTimeout != "" {
+		d, err := time.ParseDuration(b.URLTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url timeout %q", b.URLTimeout)
 		}
-
-		if resolver == nil || strategyCount != 1 {
-			return nil, fmt.Errorf("endpoint %q must have exactly one response strategy but had %d", endpointCfg.Path, strategyCount)
+		if d <= 0 {
+			return nil, fmt.Errorf("url timeout must be positive: %s", b.URLTimeout)
 		}
+		timeout = d
+	}
 
-		endpoint := rest.NewEndpoint(endpointCfg.Path, endpointCfg.Method, resolver)
-		endpoints = append(endpoints, endpoint)
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(b.URL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch body url %q: %w", b.URL, err)
 	}
+	defer resp.Body.Close()
 
-	return endpoints, nil
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch body url %q: unexpected status %s", b.URL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body url %q: %w", b.URL, err)
+	}
+	return data, nil
 }
 
-func (r Response) toRest() (rest.Response, error) {
-	var respOpts []rest.ResponseOption
+func (l ConcurrencyLimit) toRest(conv convertContext) (rest.ConcurrencyLimit, error) {
+	var queueTimeout time.Duration
+	if l.QueueTimeout != "" {
+		d, err := time.ParseDuration(l.QueueTimeout)
+		if err != nil {
+			return rest.ConcurrencyLimit{}, fmt.Errorf("invalid queueTimeout %q", l.QueueTimeout)
+		}
+		queueTimeout = d
+	}
 
-	if len(r.Headers) > 0 {
-		respOpts = append(respOpts, rest.WithResponseHeaders(r.Headers))
+	resp, err := l.Response.toRestWithStatus(conv, http.StatusServiceUnavailable)
+	if err != nil {
+		return rest.ConcurrencyLimit{}, fmt.Errorf("build rejected response: %w", err)
 	}
+	return rest.ConcurrencyLimit{
+		Max:          l.Limit,
+		QueueTimeout: queueTimeout,
+		Response:     resp,
+	}, nil
+}
 
-	if r.StatusCode != 0 {
-		respOpts = append(respOpts, rest.WithResponseStatus(r.StatusCode))
+func (r RateLimit) toRest(conv convertContext) (rest.RateLimit, error) {
+	burst := 1
+	if r.Burst != nil {
+		burst = *r.Burst
 	}
 
-	if len(r.Delay) > 0 {
-		d, err := time.ParseDuration(r.Delay)
+	retryAfter := time.Second
+	if r.RetryAfter != "" {
+		d, err := time.ParseDuration(r.RetryAfter)
 		if err != nil {
-			return rest.Response{}, fmt.Errorf("invalid response delay %q", r.Delay)
+			return rest.RateLimit{}, fmt.Errorf("invalid retryAfter %q", r.RetryAfter)
 		}
-		respOpts = append(respOpts, rest.WithResponseDelay(d))
+		if d < 0 {
+			return rest.RateLimit{}, fmt.Errorf("retryAfter cannot be negative: %s", r.RetryAfter)
+		}
+		retryAfter = d
 	}
 
-	if r.Body.Literal != "" && r.Body.FilePath != "" {
-		return rest.Response{}, errors.New("response body cannot use both literal and path")
+	throttled, err := conv.resolveRef(r.Response)
+	if err != nil {
+		return rest.RateLimit{}, err
 	}
-	respBody := []byte(r.Body.Literal)
-	if r.Body.FilePath != "" {
-		data, err := os.ReadFile(r.Body.FilePath)
-		if err != nil {
-			return rest.Response{}, fmt.Errorf("read file %q: %w", r.Body.FilePath, err)
+	if !hasHeader(throttled.Headers, "Retry-After") {
+		headers := maps.Clone(throttled.Headers)
+		if headers == nil {
+			headers = make(map[string]HeaderValues)
 		}
-		respBody = data
+		// Retry-After is in whole seconds, so round up to avoid telling clients to retry too early
+		headers["Retry-After"] = HeaderValues{strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}
+		throttled.Headers = headers
 	}
-	if len(respBody) > 0 {
-		respOpts = append(respOpts, rest.WithResponseBody(respBody))
+
+	resp, err := throttled.toRestWithStatus(conv, http.StatusTooManyRequests)
+	if err != nil {
+		return rest.RateLimit{}, fmt.Errorf("build throttled response: %w", err)
 	}
 
-	resp, err := rest.NewResponse(respOpts...)
+	return rest.RateLimit{
+		RequestsPerSecond: r.RequestsPerSecond,
+		Burst:             burst,
+		Response:          resp,
+	}, nil
+}
+
+func (f Fault) toRest(conv convertContext) (rest.Response, error) {
+	if f.Fault == "" {
+		return f.Response.toRest(conv)
+	}
+	faultOpt := rest.WithConnectionFault(rest.ConnectionFault(f.Fault))
+	if rest.ConnectionFault(f.Fault) == rest.ConnectionFaultTruncate {
+		faultOpt = rest.WithTruncatedBody(f.Bytes)
+	}
+	resp, err := rest.NewResponse(faultOpt)
 	if err != nil {
 		return rest.Response{}, fmt.Errorf("build response: %w", err)
 	}
-
 	return resp, nil
 }
 
-func convertWeightedToRest(weighted []WeightedResponse) (*rest.WeightedResponse, error) {
+func convertFaultsToRest(conv convertContext, resolver rest.ResponseResolver, faults []Fault) (*rest.FaultResponse, error) {
+	var restFaults []rest.Fault
+
+	for _, faultCfg := range faults {
+		resp, err := faultCfg.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build fault response: %w", err)
+		}
+		restFaults = append(restFaults, rest.Fault{
+			Probability: faultCfg.Probability,
+			Response:    resp,
+		})
+	}
+
+	return rest.NewFaultResponse(resolver, restFaults, nil)
+}
+
+func convertWeightedToRest(conv convertContext, weighted []WeightedResponse, ramp *WeightRamp) (*rest.WeightedResponse, error) {
 	var entries []rest.WeightedResponseEntry
 
 	for _, weightedRespCfg := range weighted {
-		resp, err := weightedRespCfg.Response.toRest()
+		var faultOpts []rest.ResponseOption
+		switch rest.ConnectionFault(weightedRespCfg.Fault) {
+		case "":
+		case rest.ConnectionFaultTruncate:
+			faultOpts = append(faultOpts, rest.WithTruncatedBody(weightedRespCfg.Bytes))
+		default:
+			faultOpts = append(faultOpts, rest.WithConnectionFault(rest.ConnectionFault(weightedRespCfg.Fault)))
+		}
+		resp, err := weightedRespCfg.Response.toRest(conv, faultOpts...)
 		if err != nil {
 			return nil, fmt.Errorf("build weighted response: %w", err)
 		}
 		entries = append(entries, rest.WeightedResponseEntry{
-			Response: resp,
-			Weight:   weightedRespCfg.Weight,
+			Response:       resp,
+			Weight:         weightedRespCfg.Weight,
+			MaxOccurrences: weightedRespCfg.MaxOccurrences,
 		})
 	}
 
+	if ramp != nil {
+		duration, err := time.ParseDuration(ramp.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight ramp duration %q", ramp.Duration)
+		}
+		return rest.NewRampedWeightedResponse(entries, ramp.EndWeights, duration, nil, nil)
+	}
+
 	return rest.NewWeightedResponse(entries, nil)
 }
 
-func convertSequencedToRest(sequencedResp *SequencedResponse) (*rest.SequencedResponse, error) {
+func convertSequencedToRest(conv convertContext, sequencedResp *SequencedResponse) (*rest.SequencedResponse, error) {
 	var sequence []rest.Response
+	// entryDelayed records the positions whose entry sets a delay, which the delay strategy keeps
+	var entryDelayed []bool
 
 	for _, respEntry := range sequencedResp.Responses {
 		count := 1
@@ -167,13 +1970,42 @@ func convertSequencedToRest(sequencedResp *SequencedResponse) (*rest.SequencedRe
 			count = *respEntry.Count
 		}
 
-		resp, err := respEntry.Response.toRest()
+		respCfg, err := conv.resolveRef(respEntry.Response)
+		if err != nil {
+			return nil, err
+		}
+		if respEntry.Delay != "" {
+			d, err := time.ParseDuration(respEntry.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sequence entry delay %q", respEntry.Delay)
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("sequence entry delay cannot be negative: %s", respEntry.Delay)
+			}
+			respCfg.Delay = respEntry.Delay
+			respCfg.DelayDistribution = nil
+		}
+
+		resp, err := respCfg.toRest(conv)
 		if err != nil {
 			return nil, fmt.Errorf("build sequence response: %w", err)
 		}
 
 		for range count {
 			sequence = append(sequence, resp)
+			entryDelayed = append(entryDelayed, respEntry.Delay != "")
+		}
+	}
+
+	if sequencedResp.DelayStrategy != nil {
+		delays, err := sequencedResp.DelayStrategy.delays(len(sequence))
+		if err != nil {
+			return nil, err
+		}
+		for i, delay := range delays {
+			if !entryDelayed[i] {
+				sequence[i] = sequence[i].Delayed(delay)
+			}
 		}
 	}
 
@@ -181,5 +2013,312 @@ func convertSequencedToRest(sequencedResp *SequencedResponse) (*rest.SequencedRe
 	if sequencedResp.EndBehavior != "" {
 		endBehavior = rest.SequenceBehavior(sequencedResp.EndBehavior)
 	}
-	return rest.NewSequencedResponse(endBehavior, sequence)
+	var end *rest.Response
+	if sequencedResp.End != nil {
+		resp, err := sequencedResp.End.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build sequence end response: %w", err)
+		}
+		end = &resp
+	}
+	return rest.NewSequencedResponse(endBehavior, sequence, end)
+}
+
+func (e EventStream) toRest() (rest.Response, error) {
+	var events []rest.ServerSentEvent
+	for _, eventCfg := range e.Events {
+		var delay time.Duration
+		if eventCfg.Delay != "" {
+			d, err := time.ParseDuration(eventCfg.Delay)
+			if err != nil {
+				return rest.Response{}, fmt.Errorf("invalid event delay %q", eventCfg.Delay)
+			}
+			delay = d
+		}
+		events = append(events, rest.ServerSentEvent{
+			Event: eventCfg.Event,
+			ID:    eventCfg.ID,
+			Data:  eventCfg.Data,
+			Delay: delay,
+		})
+	}
+
+	var respOpts []rest.ResponseOption
+	if len(e.Headers) > 0 {
+		respOpts = append(respOpts, rest.WithResponseHeaders(e.Headers))
+	}
+	respOpts = append(respOpts, rest.WithEventStream(events, e.Loop))
+
+	resp, err := rest.NewResponse(respOpts...)
+	if err != nil {
+		return rest.Response{}, fmt.Errorf("build response: %w", err)
+	}
+	return resp, nil
+}
+
+func (d DripResponse) toRest() (rest.Response, error) {
+	var delay time.Duration
+	if d.Delay != "" {
+		parsed, err := time.ParseDuration(d.Delay)
+		if err != nil {
+			return rest.Response{}, fmt.Errorf("invalid drip delay %q", d.Delay)
+		}
+		delay = parsed
+	}
+
+	var elements [][]byte
+	for _, element := range d.Elements {
+		elements = append(elements, []byte(element))
+	}
+
+	var respOpts []rest.ResponseOption
+	if d.Status != 0 {
+		respOpts = append(respOpts, rest.WithResponseStatus(d.Status))
+	}
+	if len(d.Headers) > 0 {
+		respOpts = append(respOpts, rest.WithResponseHeaders(d.Headers))
+	}
+	respOpts = append(respOpts, rest.WithJSONArrayStream(elements, delay))
+
+	resp, err := rest.NewResponse(respOpts...)
+	if err != nil {
+		return rest.Response{}, fmt.Errorf("build response: %w", err)
+	}
+	return resp, nil
+}
+
+func (ws WebSocket) toRest() (rest.Response, error) {
+	var script []rest.WebSocketMessage
+	for _, msgCfg := range ws.Messages {
+		var delay time.Duration
+		if msgCfg.Delay != "" {
+			d, err := time.ParseDuration(msgCfg.Delay)
+			if err != nil {
+				return rest.Response{}, fmt.Errorf("invalid websocket message delay %q", msgCfg.Delay)
+			}
+			delay = d
+		}
+		script = append(script, rest.WebSocketMessage{
+			Text:  msgCfg.Text,
+			Delay: delay,
+		})
+	}
+
+	resp, err := rest.NewResponse(rest.WithWebSocket(ws.Echo, script))
+	if err != nil {
+		return rest.Response{}, fmt.Errorf("build response: %w", err)
+	}
+	return resp, nil
+}
+
+func (sd StaticDir) toRest(conv convertContext) (rest.Response, error) {
+	if len(conv.pathParams) == 0 {
+		return rest.Response{}, errors.New("static dir endpoint path must end in a wildcard, such as /assets/{path...}")
+	}
+	resp, err := rest.NewResponse(rest.WithStaticDir(
+		resolvePath(conv.dir, sd.Dir),
+		conv.pathParams[len(conv.pathParams)-1],
+		rest.StaticDirOptions{Listing: sd.Listing, SPAFallback: sd.SPAFallback},
+	))
+	if err != nil {
+		return rest.Response{}, fmt.Errorf("build response: %w", err)
+	}
+	return resp, nil
+}
+
+func convertPerClientToRest(conv convertContext, perClientResp *PerClientResponse) (*rest.PerClientResponse, error) {
+	prototype, err := perClientResp.Response.toRest(conv)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := perClientOptions(perClientResp.Header, perClientResp.Cookie, perClientResp.TTL, perClientResp.MaxClients)
+	if err != nil {
+		return nil, err
+	}
+	return rest.NewPerClientResponse(prototype, opts, nil)
+}
+
+func perClientOptions(header, cookie, ttl string, maxClients *int) (rest.PerClientOptions, error) {
+	opts := rest.PerClientOptions{
+		Header:     header,
+		Cookie:     cookie,
+		MaxClients: defaultMaxClients,
+	}
+	if maxClients != nil {
+		opts.MaxClients = *maxClients
+	}
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return rest.PerClientOptions{}, fmt.Errorf("invalid per client ttl %q", ttl)
+		}
+		opts.TTL = d
+	}
+	return opts, nil
+}
+
+func convertThresholdToRest(conv convertContext, thresholdResp *ThresholdResponse) (*rest.ThresholdResponse, error) {
+	before, err := thresholdResp.Before.toRest(conv)
+	if err != nil {
+		return nil, fmt.Errorf("build before response: %w", err)
+	}
+	after, err := thresholdResp.After.toRest(conv)
+	if err != nil {
+		return nil, fmt.Errorf("build after response: %w", err)
+	}
+	return rest.NewThresholdResponse(thresholdResp.Threshold, before, after)
+}
+
+func convertNegotiatedToRest(conv convertContext, negotiatedResp *NegotiatedResponse) (*rest.NegotiatedResponse, error) {
+	var representations []rest.Representation
+	for i, rep := range negotiatedResp.Responses {
+		resp, err := rep.Response.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build response for representation %d: %w", i, err)
+		}
+		representations = append(representations, rest.Representation{
+			MediaType: rep.MediaType,
+			Response:  resp,
+		})
+	}
+
+	var fallback *rest.Response
+	if negotiatedResp.Default != nil {
+		resp, err := negotiatedResp.Default.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build default response: %w", err)
+		}
+		fallback = &resp
+	}
+
+	return rest.NewNegotiatedResponse(representations, fallback)
+}
+
+func convertWindowToRest(conv convertContext, windowResp *WindowResponse) (*rest.WindowResponse, error) {
+	duration, err := time.ParseDuration(windowResp.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window duration %q", windowResp.Duration)
+	}
+	mode := rest.WindowModeFixed
+	if windowResp.Mode != "" {
+		mode = rest.WindowMode(windowResp.Mode)
+	}
+
+	allowed, err := windowResp.Allowed.toRest(conv)
+	if err != nil {
+		return nil, fmt.Errorf("build allowed response: %w", err)
+	}
+	exceeded, err := windowResp.Exceeded.toRestWithStatus(conv, http.StatusTooManyRequests)
+	if err != nil {
+		return nil, fmt.Errorf("build exceeded response: %w", err)
+	}
+	return rest.NewWindowResponse(windowResp.Count, duration, mode, allowed, exceeded, nil)
+}
+
+func convertMatchToRest(conv convertContext, cases []MatchCase) (*rest.MatchResponse, error) {
+	restCases := make([]rest.MatchCase, 0, len(cases))
+	for _, c := range cases {
+		resp, err := c.Response.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build match case response: %w", err)
+		}
+		var bodySize *rest.BodySizeRange
+		if c.BodySize != nil {
+			if bodySize, err = c.BodySize.toRest(); err != nil {
+				return nil, err
+			}
+		}
+		restCases = append(restCases, rest.MatchCase{
+			Headers:        c.Headers,
+			Query:          c.Query,
+			HTTPVersion:    c.HTTPVersion,
+			ClientCertName: c.ClientCertName,
+			BodySize:       bodySize,
+			Response:       resp,
+		})
+	}
+	return rest.NewMatchResponse(restCases)
+}
+
+func convertJSONRPCToRest(conv convertContext, rpc *JSONRPCResponse) (*rest.JSONRPCResponse, error) {
+	methods := make(map[string]rest.JSONRPCMethod, len(rpc.Methods))
+	for name, method := range rpc.Methods {
+		if (method.Result == nil) == (method.Error == nil) {
+			return nil, fmt.Errorf("method %q must have exactly one of result and error", name)
+		}
+		cfgResp := cmp.Or(method.Result, method.Error)
+		resp, err := cfgResp.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build method %q response: %w", name, err)
+		}
+		methods[name] = rest.JSONRPCMethod{Response: resp, Error: method.Error != nil}
+	}
+	return rest.NewJSONRPCResponse(methods)
+}
+
+func convertFallthroughToRest(conv convertContext, strategies []ResponseStrategy) (*rest.FallthroughResponse, error) {
+	resolvers := make([]rest.ResponseResolver, 0, len(strategies))
+	for i, strategy := range strategies {
+		resolver, err := strategy.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build fallthrough strategy %d: %w", i, err)
+		}
+		resolvers = append(resolvers, resolver)
+	}
+	return rest.NewFallthroughResponse(resolvers)
+}
+
+func convertScheduledToRest(conv convertContext, scheduledResp *ScheduledResponse) (*rest.ScheduledResponse, error) {
+	location := time.UTC
+	if scheduledResp.Timezone != "" {
+		loc, err := time.LoadLocation(scheduledResp.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("load timezone %q: %w", scheduledResp.Timezone, err)
+		}
+		location = loc
+	}
+
+	var windows []rest.ScheduleWindow
+	for _, windowCfg := range scheduledResp.Windows {
+		start, end, err := parseTimeWindow(windowCfg.Window)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := windowCfg.Response.toRest(conv)
+		if err != nil {
+			return nil, fmt.Errorf("build schedule response: %w", err)
+		}
+		windows = append(windows, rest.ScheduleWindow{
+			Start:    start,
+			End:      end,
+			Response: resp,
+		})
+	}
+
+	fallback, err := scheduledResp.Default.toRest(conv)
+	if err != nil {
+		return nil, fmt.Errorf("build default schedule response: %w", err)
+	}
+
+	return rest.NewScheduledResponse(windows, fallback, location, nil)
+}
+
+// parseTimeWindow parses an HH:MM-HH:MM window into offsets from midnight.
+func parseTimeWindow(window string) (time.Duration, time.Duration, error) {
+	startStr, endStr, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid schedule window %q, expected HH:MM-HH:MM", window)
+	}
+
+	var offsets [2]time.Duration
+	for i, str := range []string{startStr, endStr} {
+		t, err := time.Parse("15:04", strings.TrimSpace(str))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid schedule window %q, expected HH:MM-HH:MM", window)
+		}
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	return offsets[0], offsets[1], nil
 }
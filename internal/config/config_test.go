@@ -0,0 +1,1111 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caproven/mock-server/internal/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAnchors(t *testing.T) {
+	src := `
+responses:
+  ok: &ok
+    status: 200
+    headers: &headers
+      Content-Type: application/json
+      Link: [<a>, <b>]
+    body:
+      literal: &body '{"ok": true}'
+endpoints:
+  - path: /alias
+    method: GET
+    response:
+      static: *ok
+  - path: /merged
+    method: GET
+    response:
+      static:
+        <<: *ok
+        status: 201
+        headers:
+          <<: *headers
+          X-Merged: merged
+  - path: /limited
+    method: GET
+    rateLimit:
+      requestsPerSecond: 0.001
+      response:
+        headers: *headers
+        body:
+          literal: *body
+    response:
+      static: *ok
+  - path: /sequence
+    method: GET
+    response:
+      sequence:
+        responses:
+          - response: *ok
+          - response:
+              <<: *ok
+              status: 500
+`
+	cfg, err := Decode(strings.NewReader(src))
+	require.NoError(t, err)
+	endpoints, err := cfg.RestEndpoints(nil)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	rest.RegisterHandlers(mux, endpoints)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/alias")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"<a>", "<b>"}, w.Header().Values("Link"))
+	assert.Empty(t, w.Header().Get("X-Merged"), "merged headers don't leak into the anchor")
+	assert.Equal(t, `{"ok": true}`, w.Body.String())
+
+	w = get("/merged")
+	assert.Equal(t, http.StatusCreated, w.Code, "keys after a merge override it")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "merged", w.Header().Get("X-Merged"))
+	assert.Equal(t, `{"ok": true}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, get("/limited").Code)
+	w = get("/limited")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, `{"ok": true}`, w.Body.String())
+	assert.Empty(t, get("/alias").Header().Get("Retry-After"), "the throttled response's headers don't leak into the anchor")
+
+	assert.Equal(t, http.StatusOK, get("/sequence").Code)
+	assert.Equal(t, http.StatusInternalServerError, get("/sequence").Code)
+	assert.Equal(t, http.StatusOK, get("/alias").Code)
+}
+
+func TestDecodeDuplicateKeys(t *testing.T) {
+	_, err := Decode(strings.NewReader("endpoints:\n  - path: /a\n    path: /b\n"))
+	assert.Error(t, err)
+}
+
+func TestResponseDefaultsApply(t *testing.T) {
+	defaults := &ResponseDefaults{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]HeaderValues{"Content-Type": {"application/json"}, "X-Env": {"test"}},
+		Delay:      "50ms",
+	}
+	distribution := &DelayDistribution{Type: "fixed", Mean: "10ms"}
+	tests := map[string]struct {
+		defaults *ResponseDefaults
+		resp     Response
+		want     Response
+	}{
+		"unset fields filled in": {
+			defaults: defaults,
+			want: Response{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]HeaderValues{"Content-Type": {"application/json"}, "X-Env": {"test"}},
+				Delay:      "50ms",
+			},
+		},
+		"status replaced as a whole": {
+			defaults: defaults,
+			resp:     Response{StatusCode: http.StatusCreated},
+			want: Response{
+				StatusCode: http.StatusCreated,
+				Headers:    map[string]HeaderValues{"Content-Type": {"application/json"}, "X-Env": {"test"}},
+				Delay:      "50ms",
+			},
+		},
+		"headers merged per key ignoring case": {
+			defaults: defaults,
+			resp:     Response{Headers: map[string]HeaderValues{"content-type": {"text/csv"}, "X-Extra": {"1"}}},
+			want: Response{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]HeaderValues{"content-type": {"text/csv"}, "X-Env": {"test"}, "X-Extra": {"1"}},
+				Delay:      "50ms",
+			},
+		},
+		"multi-value header replaced as a whole": {
+			defaults: &ResponseDefaults{Headers: map[string]HeaderValues{"Link": {"<a>", "<b>"}}},
+			resp:     Response{Headers: map[string]HeaderValues{"Link": {"<c>"}}},
+			want:     Response{Headers: map[string]HeaderValues{"Link": {"<c>"}}},
+		},
+		"delay replaced as a whole": {
+			defaults: defaults,
+			resp:     Response{Delay: "1s"},
+			want: Response{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]HeaderValues{"Content-Type": {"application/json"}, "X-Env": {"test"}},
+				Delay:      "1s",
+			},
+		},
+		"zero delay opts out": {
+			defaults: &ResponseDefaults{Delay: "50ms"},
+			resp:     Response{Delay: "0s"},
+			want:     Response{Delay: "0s"},
+		},
+		"delay distribution skips the default delay": {
+			defaults: &ResponseDefaults{Delay: "50ms"},
+			resp:     Response{DelayDistribution: distribution},
+			want:     Response{DelayDistribution: distribution},
+		},
+		"no defaults": {
+			resp: Response{StatusCode: http.StatusAccepted},
+			want: Response{StatusCode: http.StatusAccepted},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.defaults.apply(tt.resp))
+		})
+	}
+}
+
+func TestResponseDefaults(t *testing.T) {
+	cfg, err := Decode(strings.NewReader(`
+defaults:
+  response:
+    delay: 100ms
+    headers:
+      X-Env: test
+endpoints:
+  - path: /limited
+    method: GET
+    rateLimit:
+      requestsPerSecond: 0.001
+    response:
+      static:
+        status: 200
+`))
+	require.NoError(t, err)
+	endpoints, err := cfg.RestEndpoints(nil)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	rest.RegisterHandlers(mux, endpoints)
+	get := func() (*httptest.ResponseRecorder, time.Duration) {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/limited", nil))
+		return w, time.Since(start)
+	}
+
+	w, took := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "test", w.Header().Get("X-Env"))
+	assert.GreaterOrEqual(t, took, 100*time.Millisecond)
+
+	w, took = get()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, w.Header().Get("X-Env"), "the rate limit's 429 doesn't get defaults")
+	assert.Less(t, took, 50*time.Millisecond)
+}
+
+func TestResolvePath(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "body.json")
+	tests := map[string]struct {
+		dir, path, want string
+	}{
+		"relative":     {dir: "/srv/mocks", path: "bodies/a.json", want: filepath.Join("/srv/mocks", "bodies/a.json")},
+		"parent":       {dir: "/srv/mocks", path: "../shared/a.json", want: filepath.Join("/srv/shared", "a.json")},
+		"absolute":     {dir: "/srv/mocks", path: abs, want: abs},
+		"no dir":       {path: "bodies/a.json", want: "bodies/a.json"},
+		"no path":      {dir: "/srv/mocks"},
+		"dot segments": {dir: "/srv/mocks", path: "./a.json", want: filepath.Join("/srv/mocks", "a.json")},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolvePath(tt.dir, tt.path))
+		})
+	}
+}
+
+func TestSetDir(t *testing.T) {
+	root := t.TempDir()
+	includeDir := filepath.Join(root, "included")
+	elsewhere := t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(root, "main.txt"):           "main",
+		filepath.Join(root, "missing.txt"):        "not found",
+		filepath.Join(includeDir, "included.txt"): "included",
+		filepath.Join(includeDir, "shared.txt"):   "shared",
+		filepath.Join(elsewhere, "absolute.txt"):  "absolute",
+		filepath.Join(includeDir, "main.txt"):     "wrong dir",
+		filepath.Join(includeDir, "missing.txt"):  "wrong dir",
+		filepath.Join(root, "included.txt"):       "wrong dir",
+		filepath.Join(root, "shared.txt"):         "wrong dir",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	cfg, err := Decode(strings.NewReader(`
+notFound:
+  body:
+    filePath: missing.txt
+endpoints:
+  - path: /main
+    method: GET
+    response:
+      static:
+        body:
+          filePath: main.txt
+  - path: /absolute
+    method: GET
+    response:
+      static:
+        body:
+          filePath: ` + filepath.Join(elsewhere, "absolute.txt") + `
+  - path: /shared
+    method: GET
+    response:
+      static:
+        responseRef: shared
+`))
+	require.NoError(t, err)
+	cfg.SetDir(root)
+
+	included, err := Decode(strings.NewReader(`
+responses:
+  shared:
+    body:
+      filePath: shared.txt
+endpoints:
+  - path: /included
+    method: GET
+    response:
+      static:
+        body:
+          filePath: included.txt
+`))
+	require.NoError(t, err)
+	included.SetDir(includeDir)
+	cfg.Endpoints = append(cfg.Endpoints, included.Endpoints...)
+	cfg.Responses = included.Responses
+
+	endpoints, err := cfg.RestEndpoints(nil)
+	require.NoError(t, err)
+	fallbacks, err := cfg.Fallbacks(nil)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	rest.RegisterHandlers(mux, endpoints)
+	handler := rest.FallbackHandler(mux, fallbacks)
+
+	for path, want := range map[string]string{
+		"/main":     "main",
+		"/absolute": "absolute",
+		"/included": "included",
+		"/shared":   "shared",
+		"/missing":  "not found",
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Body.String(), path)
+	}
+}
+
+func TestSequenceDelayStrategy(t *testing.T) {
+	steps := func(n int) *int { return &n }
+	ms := time.Millisecond
+	tests := map[string]struct {
+		strategy SequenceDelayStrategy
+		n        int
+		want     []time.Duration
+	}{
+		"shrinking over the sequence": {
+			strategy: SequenceDelayStrategy{Start: "1s", End: "100ms"},
+			n:        4,
+			want:     []time.Duration{1000 * ms, 700 * ms, 400 * ms, 100 * ms},
+		},
+		"growing over the sequence": {
+			strategy: SequenceDelayStrategy{Start: "0s", End: "300ms"},
+			n:        4,
+			want:     []time.Duration{0, 100 * ms, 200 * ms, 300 * ms},
+		},
+		"holds at end after steps": {
+			strategy: SequenceDelayStrategy{Start: "1s", End: "100ms", Steps: steps(4)},
+			n:        6,
+			want:     []time.Duration{1000 * ms, 700 * ms, 400 * ms, 100 * ms, 100 * ms, 100 * ms},
+		},
+		"more steps than positions": {
+			strategy: SequenceDelayStrategy{Start: "0s", End: "400ms", Steps: steps(5)},
+			n:        3,
+			want:     []time.Duration{0, 100 * ms, 200 * ms},
+		},
+		"single entry": {
+			strategy: SequenceDelayStrategy{Start: "1s", End: "100ms"},
+			n:        1,
+			want:     []time.Duration{100 * ms},
+		},
+		"single step": {
+			strategy: SequenceDelayStrategy{Start: "1s", End: "100ms", Steps: steps(1)},
+			n:        2,
+			want:     []time.Duration{100 * ms, 100 * ms},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			delays, err := tt.strategy.delays(tt.n)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, delays)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, strategy := range []SequenceDelayStrategy{
+			{Start: "soon", End: "1s"},
+			{Start: "1s", End: ""},
+			{Start: "-1s", End: "1s"},
+			{Start: "1s", End: "-1s"},
+			{Start: "1s", End: "0s", Steps: steps(0)},
+		} {
+			_, err := strategy.delays(3)
+			assert.Error(t, err, strategy)
+		}
+	})
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]struct {
+		in      string
+		want    int64
+		wantErr string
+	}{
+		"plain bytes":          {in: "512", want: 512},
+		"bytes suffix":         {in: "512B", want: 512},
+		"kilobytes":            {in: "4KB", want: 4 << 10},
+		"megabytes":            {in: "2MB", want: 2 << 20},
+		"gigabytes":            {in: "1GB", want: 1 << 30},
+		"lowercase suffix":     {in: "4kb", want: 4 << 10},
+		"mixed case suffix":    {in: "4Mb", want: 4 << 20},
+		"spaces":               {in: " 4 KB ", want: 4 << 10},
+		"zero":                 {in: "0KB", want: 0},
+		"negative":             {in: "-1KB", want: -1 << 10},
+		"largest":              {in: "8589934591GB", want: 8589934591 << 30},
+		"empty":                {in: "", wantErr: "parse byte size"},
+		"suffix only":          {in: "KB", wantErr: "parse byte size"},
+		"unknown suffix":       {in: "4TB", wantErr: "parse byte size"},
+		"fraction":             {in: "1.5MB", wantErr: "parse byte size"},
+		"overflow":             {in: "8589934592GB", wantErr: "overflows"},
+		"negative overflow":    {in: "-8589934593GB", wantErr: "overflows"},
+		"out of int64 range":   {in: "9223372036854775808", wantErr: "parse byte size"},
+		"suffix before number": {in: "KB4", wantErr: "parse byte size"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseByteSize(tt.in)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResponseRefs(t *testing.T) {
+	build := func(responses, strategy string) ([]*rest.Endpoint, error) {
+		cfg, err := Decode(strings.NewReader(`
+responses:
+` + responses + `
+endpoints:
+  - path: /ref
+    method: GET
+    response:
+` + strategy))
+		require.NoError(t, err)
+		return cfg.RestEndpoints(nil)
+	}
+	named := `
+  created: {status: 201, body: {literal: created}}
+  alias: {responseRef: created}
+`
+
+	t.Run("resolved wherever a response is accepted", func(t *testing.T) {
+		tests := map[string]struct {
+			strategy string
+			// requests is the number of requests until the referenced response is returned.
+			requests int
+		}{
+			"static":               {strategy: "static: {responseRef: created}", requests: 1},
+			"chained":              {strategy: "static: {responseRef: alias}", requests: 1},
+			"weighted entry":       {strategy: "weighted: [{weight: 1, response: {responseRef: created}}]", requests: 1},
+			"sequence entry":       {strategy: "sequence: {responses: [{response: {responseRef: created}}]}", requests: 1},
+			"sequence end":         {strategy: "sequence: {endBehavior: end, responses: [{response: {status: 200}}], end: {responseRef: created}}", requests: 2},
+			"after count":          {strategy: "afterCount: {threshold: 1, before: {status: 200}, after: {responseRef: created}}", requests: 2},
+			"first request":        {strategy: "firstRequest: {responseRef: created}\n      thereafter: {status: 200}", requests: 1},
+			"match case":           {strategy: "match: [{query: {id: \"\"}, response: {responseRef: created}}]", requests: 1},
+			"fallthrough strategy": {strategy: "fallthrough: [{static: {responseRef: alias}}]", requests: 1},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				endpoints, err := build(named, "      "+tt.strategy+"\n")
+				require.NoError(t, err)
+				mux := http.NewServeMux()
+				rest.RegisterHandlers(mux, endpoints)
+
+				var w *httptest.ResponseRecorder
+				for range tt.requests {
+					w = httptest.NewRecorder()
+					mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ref?id=1", nil))
+				}
+				assert.Equal(t, http.StatusCreated, w.Code)
+				assert.Equal(t, "created", w.Body.String())
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := map[string]struct {
+			responses, strategy, wantErr string
+		}{
+			"unknown name": {
+				responses: named,
+				strategy:  "static: {responseRef: missing}",
+				wantErr:   `unknown response "missing"`,
+			},
+			"unknown name in weighted entry": {
+				responses: named,
+				strategy:  "weighted: [{weight: 1, response: {responseRef: missing}}]",
+				wantErr:   `unknown response "missing"`,
+			},
+			"unknown name in sequence end": {
+				responses: named,
+				strategy:  "sequence: {endBehavior: end, responses: [{response: {status: 200}}], end: {responseRef: missing}}",
+				wantErr:   `unknown response "missing"`,
+			},
+			"unknown name in unused response": {
+				responses: named + "  broken: {responseRef: missing}\n",
+				strategy:  "static: {status: 200}",
+				wantErr:   `named response "broken": unknown response "missing"`,
+			},
+			"cycle": {
+				responses: "  a: {responseRef: b}\n  b: {responseRef: a}\n",
+				strategy:  "static: {status: 200}",
+				wantErr:   "response reference cycle: b -> a -> b",
+			},
+			"self reference": {
+				responses: "  a: {responseRef: a}\n",
+				strategy:  "static: {status: 200}",
+				wantErr:   "response reference cycle: a -> a",
+			},
+			"other fields set": {
+				responses: named,
+				strategy:  "static: {responseRef: created, status: 500}",
+				wantErr:   `response referencing "created" cannot set other fields`,
+			},
+			"other fields set in named response": {
+				responses: named + "  loud: {responseRef: created, headers: {X-Loud: yes}}\n",
+				strategy:  "static: {status: 200}",
+				wantErr:   `response referencing "created" cannot set other fields`,
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := build(tt.responses, "      "+tt.strategy+"\n")
+				assert.ErrorContains(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestResponseBodyURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users.json":
+			_, _ = w.Write([]byte(`[{"id":1}]`))
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	build := func(body ResponseBody) (rest.Response, error) {
+		return Response{Body: body}.toRest(convertContext{})
+	}
+
+	t.Run("fetched once at startup", func(t *testing.T) {
+		resp, err := build(ResponseBody{URL: server.URL + "/users.json"})
+		require.NoError(t, err)
+		endpoint, err := rest.NewEndpoint("/users", http.MethodGet, rest.StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, []*rest.Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Equal(t, `[{"id":1}]`, w.Body.String())
+	})
+
+	t.Run("non-2xx status", func(t *testing.T) {
+		_, err := build(ResponseBody{URL: server.URL + "/missing"})
+		assert.ErrorContains(t, err, "unexpected status 404")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := build(ResponseBody{URL: server.URL + "/slow", URLTimeout: "20ms"})
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("invalid timeouts", func(t *testing.T) {
+		for _, timeout := range []string{"soon", "0s", "-1s"} {
+			_, err := build(ResponseBody{URL: server.URL + "/users.json", URLTimeout: timeout})
+			assert.ErrorContains(t, err, "url timeout", timeout)
+		}
+	})
+}
+
+func TestResponseBodyBase64(t *testing.T) {
+	build := func(body string) ([]*rest.Endpoint, error) {
+		cfg, err := Decode(strings.NewReader(`
+endpoints:
+  - path: /image
+    method: GET
+    response:
+      static:
+        body:
+` + body))
+		require.NoError(t, err)
+		return cfg.RestEndpoints(nil)
+	}
+
+	t.Run("decoded at load", func(t *testing.T) {
+		endpoints, err := build("          base64: iVBORwAA/w==\n")
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, endpoints)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/image", nil))
+		assert.Equal(t, []byte{0x89, 'P', 'N', 'G', 0, 0, 0xff}, w.Body.Bytes())
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := build("          base64: not*base64\n")
+		assert.ErrorContains(t, err, "decode base64 body")
+	})
+
+	t.Run("with another body source", func(t *testing.T) {
+		for _, other := range []string{"literal: hello", "filePath: body.bin"} {
+			_, err := build("          base64: aGVsbG8=\n          " + other + "\n")
+			assert.ErrorContains(t, err, "only one of", other)
+		}
+	})
+}
+
+func TestSequenceEntryDelay(t *testing.T) {
+	build := func(entryDelay string) ([]*rest.Endpoint, error) {
+		cfg, err := Decode(strings.NewReader(`
+endpoints:
+  - path: /flaky
+    method: GET
+    response:
+      sequence:
+        responses:
+          - count: 2
+            delay: ` + entryDelay + `
+            response:
+              status: 200
+              delay: 100ms
+          - response:
+              status: 504
+              delay: 100ms
+`))
+		require.NoError(t, err)
+		return cfg.RestEndpoints(nil)
+	}
+
+	t.Run("overrides the response delay for its positions", func(t *testing.T) {
+		endpoints, err := build("0s")
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, endpoints)
+
+		get := func() (int, time.Duration) {
+			start := time.Now()
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flaky", nil))
+			return w.Code, time.Since(start)
+		}
+		for range 2 {
+			status, took := get()
+			assert.Equal(t, http.StatusOK, status)
+			assert.Less(t, took, 50*time.Millisecond)
+		}
+		status, took := get()
+		assert.Equal(t, http.StatusGatewayTimeout, status)
+		assert.GreaterOrEqual(t, took, 100*time.Millisecond)
+	})
+
+	t.Run("invalid delays", func(t *testing.T) {
+		for _, delay := range []string{"soon", "-1s"} {
+			_, err := build(delay)
+			assert.ErrorContains(t, err, "sequence entry delay", delay)
+		}
+	})
+}
+
+func TestFirstRequest(t *testing.T) {
+	build := func(strategy string) ([]*rest.Endpoint, error) {
+		cfg, err := Decode(strings.NewReader(`
+endpoints:
+  - path: /config
+    method: GET
+    response:
+` + strategy))
+		require.NoError(t, err)
+		return cfg.RestEndpoints(nil)
+	}
+
+	t.Run("first request then thereafter", func(t *testing.T) {
+		endpoints, err := build(`
+      firstRequest:
+        status: 503
+      thereafter:
+        status: 200
+`)
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, endpoints)
+		get := func() int {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+			return w.Code
+		}
+		assert.Equal(t, http.StatusServiceUnavailable, get())
+		assert.Equal(t, http.StatusOK, get())
+		assert.Equal(t, http.StatusOK, get())
+
+		require.True(t, endpoints[0].ResetCount())
+		assert.Equal(t, http.StatusServiceUnavailable, get())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		const shorthand = "      firstRequest: {status: 503}\n      thereafter: {status: 200}\n"
+		tests := map[string]struct {
+			strategy, wantErr string
+		}{
+			"only firstRequest": {strategy: "      firstRequest: {status: 503}\n", wantErr: "must be set together"},
+			"only thereafter":   {strategy: "      thereafter: {status: 200}\n", wantErr: "must be set together"},
+			"with static": {
+				strategy: shorthand + "      static: {status: 200}\n",
+				wantErr:  "exactly one response strategy",
+			},
+			"with afterCount": {
+				strategy: shorthand + "      afterCount: {threshold: 2, before: {status: 503}, after: {status: 200}}\n",
+				wantErr:  "exactly one response strategy",
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := build(tt.strategy)
+				assert.ErrorContains(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	src := `
+endpoints:
+  - path: /default
+    method: GET
+    rateLimit:
+      requestsPerSecond: 0.001
+      retryAfter: 1500ms
+    response:
+      static:
+        status: 200
+  - path: /configured
+    method: GET
+    rateLimit:
+      requestsPerSecond: 0.001
+      response:
+        headers:
+          retry-after: "60"
+    response:
+      static:
+        status: 200
+`
+	cfg, err := Decode(strings.NewReader(src))
+	require.NoError(t, err)
+	endpoints, err := cfg.RestEndpoints(nil)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	rest.RegisterHandlers(mux, endpoints)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for path, want := range map[string]string{"/default": "2", "/configured": "60"} {
+		assert.Equal(t, http.StatusOK, get(path).Code, path)
+		w := get(path)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, path)
+		assert.Equal(t, []string{want}, w.Header().Values("Retry-After"), path)
+	}
+}
+
+func TestEndpointOptions(t *testing.T) {
+	build := func(t *testing.T, src string) (*http.ServeMux, error) {
+		t.Helper()
+		cfg, err := Decode(strings.NewReader(src))
+		require.NoError(t, err)
+		endpoints, err := cfg.RestEndpoints(nil)
+		if err != nil {
+			return nil, err
+		}
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, endpoints)
+		return mux, nil
+	}
+	options := func(mux *http.ServeMux, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, path, nil))
+		return w
+	}
+
+	t.Run("allow lists every method for the path", func(t *testing.T) {
+		mux, err := build(t, `
+endpoints:
+  - path: /items
+    method: GET
+    options: {}
+    response:
+      static:
+        status: 200
+  - path: /items
+    pathType: pattern
+    method: post
+    response:
+      static:
+        status: 201
+  - path: /other
+    method: DELETE
+    response:
+      static:
+        status: 204
+`)
+		require.NoError(t, err)
+		w := options(mux, "/items")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+	})
+
+	t.Run("configured response", func(t *testing.T) {
+		mux, err := build(t, `
+endpoints:
+  - path: /items
+    method: PUT
+    options:
+      status: 200
+      headers:
+        allow: PUT
+        Access-Control-Max-Age: "600"
+    response:
+      static:
+        status: 200
+`)
+		require.NoError(t, err)
+		w := options(mux, "/items")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"PUT"}, w.Header().Values("Allow"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("cors preflight", func(t *testing.T) {
+		mux, err := build(t, `
+endpoints:
+  - path: /items
+    method: GET
+    middleware: [cors]
+    response:
+      static:
+        status: 200
+  - path: /items
+    method: POST
+    middleware: [cors]
+    response:
+      static:
+        status: 201
+`)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.MethodPost, w.Header().Get("Access-Control-Allow-Methods"))
+
+		w = options(mux, "/items")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+	})
+
+	t.Run("path matching every method", func(t *testing.T) {
+		_, err := build(t, `
+endpoints:
+  - path: /items
+    method: GET
+    options: {}
+    response:
+      static:
+        status: 200
+  - path: /items
+    response:
+      static:
+        status: 200
+`)
+		assert.ErrorContains(t, err, "matching every method")
+	})
+}
+
+func TestMethodFallthrough(t *testing.T) {
+	t.Run("disabled keeps the 405", func(t *testing.T) {
+		cfg, err := Decode(strings.NewReader(`
+endpoints:
+  - path: /files/{path...}
+    response:
+      static:
+        status: 200
+  - path: /files/readme.txt
+    method: GET
+    methodFallthrough: false
+    response:
+      static:
+        status: 200
+`))
+		require.NoError(t, err)
+		endpoints, err := cfg.RestEndpoints(nil)
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		rest.RegisterHandlers(mux, endpoints)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/readme.txt", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/other.txt", nil))
+		assert.Equal(t, http.StatusOK, w.Code, "other paths still fall through")
+	})
+
+	invalid := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "regex path",
+			src: `
+endpoints:
+  - path: /files/.*
+    pathType: regex
+    method: GET
+    methodFallthrough: false
+    response:
+      static:
+        status: 200
+`,
+			wantErr: "methodFallthrough can only be disabled for pattern paths",
+		},
+		{
+			name: "path matching every method",
+			src: `
+endpoints:
+  - path: /files/readme.txt
+    method: GET
+    methodFallthrough: false
+    response:
+      static:
+        status: 200
+  - path: /files/readme.txt
+    method: "*"
+    response:
+      static:
+        status: 200
+`,
+			wantErr: "which has an endpoint matching every method",
+		},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Decode(strings.NewReader(tt.src))
+			require.NoError(t, err)
+			_, err = cfg.RestEndpoints(nil)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// newTestCert returns a new self-signed certificate for localhost, and its PEM encoding.
+func newTestCert(t *testing.T) (tls.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	_, caPEM := newTestCert(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), caPEM, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.crt"), []byte("not a certificate"), 0o644))
+
+	listenerTLS := func(t *testing.T, tlsYAML string) *TLS {
+		t.Helper()
+		cfg, err := Decode(strings.NewReader(`
+server:
+  listeners:
+    - addr: :8443
+      tls:
+` + tlsYAML))
+		require.NoError(t, err)
+		cfg.SetDir(dir)
+		return cfg.Server.Listeners[0].TLS
+	}
+
+	t.Run("client auth", func(t *testing.T) {
+		wantCAs := x509.NewCertPool()
+		require.True(t, wantCAs.AppendCertsFromPEM(caPEM))
+		for clientAuth, want := range map[string]tls.ClientAuthType{
+			"request": tls.VerifyClientCertIfGiven,
+			"require": tls.RequireAndVerifyClientCert,
+		} {
+			cfg, err := listenerTLS(t, "        clientAuth: "+clientAuth+"\n        clientCAFile: ca.crt\n").Config()
+			require.NoError(t, err, clientAuth)
+			assert.Equal(t, want, cfg.ClientAuth, clientAuth)
+			assert.True(t, wantCAs.Equal(cfg.ClientCAs), clientAuth)
+		}
+	})
+
+	t.Run("no client auth", func(t *testing.T) {
+		cfg, err := listenerTLS(t, "        certFile: server.crt\n").Config()
+		require.NoError(t, err)
+		assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+		assert.Nil(t, cfg.ClientCAs)
+	})
+
+	t.Run("key pair paths", func(t *testing.T) {
+		abs := filepath.Join(t.TempDir(), "server.key")
+		certFile, keyFile := listenerTLS(t, "        certFile: certs/server.crt\n        keyFile: "+abs+"\n").KeyPair()
+		assert.Equal(t, filepath.Join(dir, "certs/server.crt"), certFile)
+		assert.Equal(t, abs, keyFile)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for tlsYAML, wantErr := range map[string]string{
+			"        clientAuth: always\n        clientCAFile: ca.crt\n":     "unknown clientAuth",
+			"        clientAuth: require\n":                                  "requires clientCAFile",
+			"        clientCAFile: ca.crt\n":                                 "requires clientAuth",
+			"        clientAuth: require\n        clientCAFile: none.crt\n":  "read client CA file",
+			"        clientAuth: require\n        clientCAFile: empty.crt\n": "no PEM certificates",
+		} {
+			_, err := listenerTLS(t, tlsYAML).Config()
+			assert.ErrorContains(t, err, wantErr, tlsYAML)
+		}
+	})
+}
+
+func TestTLSHandshakeDelay(t *testing.T) {
+	cert, _ := newTestCert(t)
+	newConfig := func(delay string) *tls.Config {
+		cfg, err := TLS{HandshakeDelay: delay}.Config()
+		require.NoError(t, err)
+		cfg.Certificates = []tls.Certificate{cert}
+		return cfg
+	}
+
+	t.Run("stalls the handshake", func(t *testing.T) {
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", newConfig("100ms"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = ln.Close() })
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}
+		}()
+
+		start := time.Now()
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		require.NoError(t, err)
+		_ = conn.Close()
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("abandoned handshake", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		t.Cleanup(func() {
+			_ = clientConn.Close()
+			_ = serverConn.Close()
+		})
+		go func() { _ = tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true}).Handshake() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := tls.Server(serverConn, newConfig("10s")).HandshakeContext(ctx)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second, "should stop waiting once the handshake is abandoned")
+	})
+
+	t.Run("invalid delays", func(t *testing.T) {
+		for _, delay := range []string{"soon", "0s", "-1s"} {
+			_, err := TLS{HandshakeDelay: delay}.Config()
+			assert.Error(t, err, delay)
+		}
+	})
+}
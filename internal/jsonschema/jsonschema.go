@@ -0,0 +1,517 @@
+// Package jsonschema validates JSON documents against a JSON Schema, covering just what the mock
+// server needs: the validation keywords for types, objects, arrays, strings, and numbers, plus the
+// allOf, anyOf, oneOf, and not combinators. References ($ref) and remote schemas are not supported,
+// and schemas using unsupported keywords fail to compile rather than being partially enforced.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// annotations are keywords that don't affect validation.
+var annotations = []string{
+	"$schema", "$id", "$comment", "title", "description", "default", "examples", "format",
+	"deprecated", "readOnly", "writeOnly",
+}
+
+var types = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	// always is set for the boolean schemas true and false, which accept and reject everything.
+	always *bool
+
+	types []string
+	enum  []any
+	cnst  *any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+}
+
+// ValidationError describes where and why a document failed validation.
+type ValidationError struct {
+	// Path is a JSON pointer to the invalid value, empty for the whole document.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	return compile(doc, "")
+}
+
+func compile(doc any, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema%s must be an object or boolean", at(path))
+	}
+
+	s := &Schema{}
+	for keyword, val := range obj {
+		if err := s.compileKeyword(keyword, val, path); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) compileKeyword(keyword string, val any, path string) error {
+	var err error
+	keywordPath := path + "/" + keyword
+	switch keyword {
+	case "type":
+		s.types, err = compileTypes(val)
+	case "enum":
+		enum, ok := val.([]any)
+		if !ok {
+			err = errors.New("must be an array")
+		}
+		s.enum = enum
+	case "const":
+		s.cnst = &val
+	case "properties":
+		s.properties, err = compileSchemaMap(val, keywordPath)
+	case "required":
+		s.required, err = compileStrings(val)
+	case "additionalProperties":
+		s.additionalProperties, err = compile(val, keywordPath)
+	case "minProperties":
+		s.minProperties, err = compileCount(val)
+	case "maxProperties":
+		s.maxProperties, err = compileCount(val)
+	case "items":
+		s.items, err = compile(val, keywordPath)
+	case "minItems":
+		s.minItems, err = compileCount(val)
+	case "maxItems":
+		s.maxItems, err = compileCount(val)
+	case "uniqueItems":
+		unique, ok := val.(bool)
+		if !ok {
+			err = errors.New("must be a boolean")
+		}
+		s.uniqueItems = unique
+	case "minLength":
+		s.minLength, err = compileCount(val)
+	case "maxLength":
+		s.maxLength, err = compileCount(val)
+	case "pattern":
+		pattern, ok := val.(string)
+		if !ok {
+			err = errors.New("must be a string")
+			break
+		}
+		s.pattern, err = regexp.Compile(pattern)
+	case "minimum":
+		s.minimum, err = compileNumber(val)
+	case "maximum":
+		s.maximum, err = compileNumber(val)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = compileNumber(val)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = compileNumber(val)
+	case "multipleOf":
+		s.multipleOf, err = compileNumber(val)
+		if err == nil && *s.multipleOf <= 0 {
+			err = errors.New("must be greater than 0")
+		}
+	case "allOf":
+		s.allOf, err = compileSchemaList(val, keywordPath)
+	case "anyOf":
+		s.anyOf, err = compileSchemaList(val, keywordPath)
+	case "oneOf":
+		s.oneOf, err = compileSchemaList(val, keywordPath)
+	case "not":
+		s.not, err = compile(val, keywordPath)
+	default:
+		if !slices.Contains(annotations, keyword) {
+			return fmt.Errorf("unsupported keyword %q%s", keyword, at(path))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %q%s: %w", keyword, at(path), err)
+	}
+	return nil
+}
+
+func compileTypes(val any) ([]string, error) {
+	if typ, ok := val.(string); ok {
+		val = []any{typ}
+	}
+	names, err := compileStrings(val)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !slices.Contains(types, name) {
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return names, nil
+}
+
+func compileStrings(val any) ([]string, error) {
+	list, ok := val.([]any)
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func compileCount(val any) (*int, error) {
+	n, ok := val.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func compileNumber(val any) (*float64, error) {
+	n, ok := val.(float64)
+	if !ok {
+		return nil, errors.New("must be a number")
+	}
+	return &n, nil
+}
+
+func compileSchemaMap(val any, path string) (map[string]*Schema, error) {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New("must be an object")
+	}
+	schemas := make(map[string]*Schema, len(obj))
+	for name, doc := range obj {
+		schema, err := compile(doc, path+"/"+escape(name))
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = schema
+	}
+	return schemas, nil
+}
+
+func compileSchemaList(val any, path string) ([]*Schema, error) {
+	list, ok := val.([]any)
+	if !ok || len(list) == 0 {
+		return nil, errors.New("must be a non-empty array")
+	}
+	schemas := make([]*Schema, 0, len(list))
+	for i, doc := range list {
+		schema, err := compile(doc, path+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+// Validate parses a JSON document and validates it, returning every validation error found. It
+// returns an error if the document isn't valid JSON.
+func (s *Schema) Validate(data []byte) ([]ValidationError, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("parse JSON: unexpected data after top-level value")
+	}
+	return s.ValidateValue(doc), nil
+}
+
+// ValidateValue validates an already decoded JSON document, returning every validation error found.
+// Numbers can be float64 or json.Number, for documents decoded with UseNumber.
+func (s *Schema) ValidateValue(doc any) []ValidationError {
+	return s.validate(doc, "")
+}
+
+func (s *Schema) validate(v any, path string) []ValidationError {
+	v = number(v)
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return []ValidationError{{Path: path, Message: "no value is allowed"}}
+	}
+
+	var errs []ValidationError
+	fail := func(format string, args ...any) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(typ string) bool { return hasType(v, typ) }) {
+		fail("expected %s but got %s", strings.Join(s.types, " or "), typeOf(v))
+		// the remaining keywords would only add noise about the same value
+		return errs
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return equal(e, v) }) {
+		fail("value is not one of the allowed values")
+	}
+	if s.cnst != nil && !equal(*s.cnst, v) {
+		fail("value does not equal the constant")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		errs = append(errs, s.validateObject(v, path)...)
+	case []any:
+		errs = append(errs, s.validateArray(v, path)...)
+	case string:
+		errs = append(errs, s.validateString(v, path)...)
+	case float64:
+		errs = append(errs, s.validateNumber(v, path)...)
+	}
+
+	for _, sub := range s.allOf {
+		errs = append(errs, sub.validate(v, path)...)
+	}
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(sub *Schema) bool { return sub.valid(v) }) {
+		fail("value does not match any of the allowed schemas")
+	}
+	if s.oneOf != nil {
+		var matches int
+		for _, sub := range s.oneOf {
+			if sub.valid(v) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("value must match exactly one schema but matched %d", matches)
+		}
+	}
+	if s.not != nil && s.not.valid(v) {
+		fail("value matches a disallowed schema")
+	}
+	return errs
+}
+
+func (s *Schema) valid(v any) bool {
+	return len(s.validate(v, "")) == 0
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string) []ValidationError {
+	var errs []ValidationError
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d properties", *s.minProperties)})
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d properties", *s.maxProperties)})
+	}
+
+	// sorted so errors are reported in a stable order
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		propPath := path + "/" + escape(name)
+		if prop, ok := s.properties[name]; ok {
+			errs = append(errs, prop.validate(obj[name], propPath)...)
+		} else if s.additionalProperties != nil {
+			if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+				errs = append(errs, ValidationError{Path: propPath, Message: "additional property is not allowed"})
+				continue
+			}
+			errs = append(errs, s.additionalProperties.validate(obj[name], propPath)...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateArray(arr []any, path string) []ValidationError {
+	var errs []ValidationError
+	if s.minItems != nil && len(arr) < *s.minItems {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d items", *s.minItems)})
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d items", *s.maxItems)})
+	}
+	if s.uniqueItems {
+		for i := range arr {
+			if slices.ContainsFunc(arr[:i], func(prev any) bool { return equal(prev, arr[i]) }) {
+				errs = append(errs, ValidationError{Path: path, Message: "items must be unique"})
+				break
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			errs = append(errs, s.items.validate(item, path+"/"+strconv.Itoa(i))...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateString(str string, path string) []ValidationError {
+	var errs []ValidationError
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must be at least %d characters", *s.minLength)})
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must be at most %d characters", *s.maxLength)})
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must match pattern %q", s.pattern)})
+	}
+	return errs
+}
+
+func (s *Schema) validateNumber(n float64, path string) []ValidationError {
+	var errs []ValidationError
+	fail := func(format string, bound float64) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, strconv.FormatFloat(bound, 'g', -1, 64))})
+	}
+	if s.minimum != nil && n < *s.minimum {
+		fail("must be >= %s", *s.minimum)
+	}
+	if s.maximum != nil && n > *s.maximum {
+		fail("must be <= %s", *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		fail("must be > %s", *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		fail("must be < %s", *s.exclusiveMaximum)
+	}
+	if s.multipleOf != nil {
+		if q := n / *s.multipleOf; q != math.Trunc(q) {
+			fail("must be a multiple of %s", *s.multipleOf)
+		}
+	}
+	return errs
+}
+
+func hasType(v any, typ string) bool {
+	if typ == "integer" {
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return typeOf(v) == typ
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// number converts a json.Number to a float64, leaving other values as they are. Out of range
+// numbers are still numbers, so it keeps the infinity Float64 returns along with its error.
+func number(v any) any {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
+
+// equal reports whether two JSON values are equal, comparing numbers by value whether they're
+// float64 or json.Number.
+func equal(a, b any) bool {
+	a, b = number(a), number(b)
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, val := range a {
+			if other, ok := b[name]; !ok || !equal(val, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equal)
+	default:
+		// the rest are comparable scalars
+		return a == b
+	}
+}
+
+// escape escapes a property name for use in a JSON pointer.
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// at describes where in the schema a problem is, for errors.
+func at(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " at " + path
+}
@@ -0,0 +1,139 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	cases := map[string]string{
+		"not json":            `{`,
+		"not a schema":        `"string"`,
+		"unknown type":        `{"type": "float"}`,
+		"unsupported keyword": `{"$ref": "#/definitions/user"}`,
+		"nested unsupported":  `{"properties": {"name": {"if": true}}}`,
+		"negative count":      `{"minLength": -1}`,
+		"invalid pattern":     `{"pattern": "("}`,
+		"zero multipleOf":     `{"multipleOf": 0}`,
+		"empty anyOf":         `{"anyOf": []}`,
+	}
+	for name, schema := range cases {
+		_, err := Compile([]byte(schema))
+		assert.Error(t, err, name)
+	}
+
+	_, err := Compile([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "User", "format": "email"}`))
+	assert.NoError(t, err, "annotations are ignored")
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"type": "object",
+		"required": ["name", "age"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2, "uniqueItems": true},
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer", "multipleOf": 2}]},
+			"a/b": {"not": {"type": "null"}}
+		},
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		doc  string
+		want []ValidationError
+	}{
+		"valid": {
+			doc: `{"name": "ada", "age": 36, "role": "admin", "tags": ["a", "b"], "id": 4, "a/b": 1}`,
+		},
+		"wrong type": {
+			doc:  `[]`,
+			want: []ValidationError{{Path: "", Message: "expected object but got array"}},
+		},
+		"missing required": {
+			doc:  `{"name": "ada"}`,
+			want: []ValidationError{{Path: "", Message: `missing required property "age"`}},
+		},
+		"nested errors": {
+			doc: `{"name": "", "age": 36.5, "role": "root", "tags": ["a", "B", "a"], "id": 3, "a/b": null, "extra": 1}`,
+			want: []ValidationError{
+				{Path: "/a~1b", Message: "value matches a disallowed schema"},
+				{Path: "/age", Message: "expected integer but got number"},
+				{Path: "/extra", Message: "additional property is not allowed"},
+				{Path: "/id", Message: "value does not match any of the allowed schemas"},
+				{Path: "/name", Message: "must be at least 1 characters"},
+				{Path: "/role", Message: "value is not one of the allowed values"},
+				{Path: "/tags", Message: "must have at most 2 items"},
+				{Path: "/tags", Message: "items must be unique"},
+				{Path: "/tags/1", Message: `must match pattern "^[a-z]+$"`},
+			},
+		},
+		"bounds": {
+			doc: `{"name": "abcdef", "age": 150}`,
+			want: []ValidationError{
+				{Path: "/age", Message: "must be < 150"},
+				{Path: "/name", Message: "must be at most 5 characters"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			errs, err := schema.Validate([]byte(tc.doc))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, errs)
+
+			// the same document decoded with UseNumber validates the same
+			dec := json.NewDecoder(strings.NewReader(tc.doc))
+			dec.UseNumber()
+			var doc any
+			require.NoError(t, dec.Decode(&doc))
+			assert.Equal(t, tc.want, schema.ValidateValue(doc))
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := schema.Validate([]byte(`{"name": `))
+		assert.Error(t, err)
+
+		_, err = schema.Validate([]byte(`{} {}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("oneOf", func(t *testing.T) {
+		schema, err := Compile([]byte(`{"oneOf": [{"type": "integer"}, {"type": "number"}]}`))
+		require.NoError(t, err)
+
+		errs, err := schema.Validate([]byte(`1.5`))
+		require.NoError(t, err)
+		assert.Empty(t, errs)
+
+		errs, err = schema.Validate([]byte(`1`))
+		require.NoError(t, err)
+		assert.Equal(t, []ValidationError{{Message: "value must match exactly one schema but matched 2"}}, errs)
+	})
+
+	t.Run("json.Number values", func(t *testing.T) {
+		schema, err := Compile([]byte(`{"type": "array", "uniqueItems": true, "items": {"enum": [1, {"n": 2}]}}`))
+		require.NoError(t, err)
+
+		assert.Empty(t, schema.ValidateValue([]any{json.Number("1"), map[string]any{"n": json.Number("2.0")}}))
+		assert.Equal(t, []ValidationError{{Message: "items must be unique"}}, schema.ValidateValue([]any{json.Number("1"), json.Number("1.0")}))
+		assert.Equal(t, []ValidationError{{Path: "/0", Message: "value is not one of the allowed values"}}, schema.ValidateValue([]any{json.Number("3")}))
+	})
+
+	t.Run("boolean schemas", func(t *testing.T) {
+		schema, err := Compile([]byte(`{"properties": {"any": true, "none": false}}`))
+		require.NoError(t, err)
+
+		errs, err := schema.Validate([]byte(`{"any": 1, "none": 2}`))
+		require.NoError(t, err)
+		assert.Equal(t, []ValidationError{{Path: "/none", Message: "no value is allowed"}}, errs)
+	})
+}
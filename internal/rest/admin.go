@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminPathPrefix is reserved for admin routes.
+const AdminPathPrefix = "/__admin/"
+
+// RegisterAdminHandlers registers routes for inspecting the given endpoints under AdminPathPrefix,
+// itself prefixed with basePath.
+func RegisterAdminHandlers(mux *http.ServeMux, endpoints []*Endpoint, basePath string) {
+	prefix := basePath + AdminPathPrefix
+	mux.HandleFunc("GET "+prefix+"verify", func(w http.ResponseWriter, r *http.Request) {
+		verifyHits(w, r, endpoints)
+	})
+	mux.HandleFunc("GET "+prefix+"endpoints/{id}", func(w http.ResponseWriter, r *http.Request) {
+		endpoint := findEndpoint(endpoints, r.PathValue("id"))
+		if endpoint == nil {
+			http.Error(w, "no endpoint with that id", http.StatusNotFound)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, endpoint.Describe())
+	})
+	mux.HandleFunc("POST "+prefix+"endpoints/{id}/enable", func(w http.ResponseWriter, r *http.Request) {
+		setEndpointEnabled(w, r, endpoints, true)
+	})
+	mux.HandleFunc("POST "+prefix+"endpoints/{id}/disable", func(w http.ResponseWriter, r *http.Request) {
+		setEndpointEnabled(w, r, endpoints, false)
+	})
+	mux.HandleFunc("POST "+prefix+"endpoints/{id}/reset", func(w http.ResponseWriter, r *http.Request) {
+		endpoint := findEndpoint(endpoints, r.PathValue("id"))
+		if endpoint == nil {
+			http.Error(w, "no endpoint with that id", http.StatusNotFound)
+			return
+		}
+		if !endpoint.ResetCount() {
+			http.Error(w, "endpoint has no afterCount or firstRequest count to reset", http.StatusBadRequest)
+			return
+		}
+		slog.Info("endpoint count reset", "id", endpoint.ID())
+		writeAdminJSON(w, http.StatusOK, endpoint.Describe())
+	})
+	mux.HandleFunc("GET "+prefix+"stats", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, allStats(endpoints))
+	})
+	mux.HandleFunc("POST "+prefix+"stats/reset", func(w http.ResponseWriter, r *http.Request) {
+		for _, e := range endpoints {
+			e.stats.reset()
+		}
+		slog.Info("endpoint stats reset")
+		writeAdminJSON(w, http.StatusOK, allStats(endpoints))
+	})
+}
+
+type statsResult struct {
+	Endpoints []EndpointStats `json:"endpoints"`
+}
+
+func allStats(endpoints []*Endpoint) statsResult {
+	result := statsResult{Endpoints: make([]EndpointStats, len(endpoints))}
+	for i, e := range endpoints {
+		result.Endpoints[i] = e.Stats()
+	}
+	return result
+}
+
+func findEndpoint(endpoints []*Endpoint, id string) *Endpoint {
+	for _, e := range endpoints {
+		if e.ID() == id {
+			return e
+		}
+	}
+	return nil
+}
+
+type endpointState struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+}
+
+func setEndpointEnabled(w http.ResponseWriter, r *http.Request, endpoints []*Endpoint, enabled bool) {
+	endpoint := findEndpoint(endpoints, r.PathValue("id"))
+	if endpoint == nil {
+		http.Error(w, "no endpoint with that id", http.StatusNotFound)
+		return
+	}
+
+	endpoint.SetEnabled(enabled)
+	slog.Info("endpoint toggled", "id", endpoint.ID(), "enabled", enabled)
+	writeAdminJSON(w, http.StatusOK, endpointState{ID: endpoint.ID(), Enabled: enabled})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("failed to write admin response", "err", err)
+	}
+}
+
+type verifyResult struct {
+	Path   string `json:"path"`
+	Method string `json:"method,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+// verifyHits responds 200 if the endpoint identified by the path and method query params was hit a
+// number of times satisfying the times, atLeast, and atMost params, or 409 if it wasn't. With no
+// count params, the endpoint must have been hit at least once.
+func verifyHits(w http.ResponseWriter, r *http.Request, endpoints []*Endpoint) {
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+	method := strings.ToUpper(query.Get("method"))
+
+	var endpoint *Endpoint
+	for _, e := range endpoints {
+		if e.Path == path && strings.ToUpper(e.Method) == method {
+			endpoint = e
+			break
+		}
+	}
+	if endpoint == nil {
+		http.Error(w, "no endpoint with that path and method", http.StatusNotFound)
+		return
+	}
+
+	count := endpoint.hits.Load()
+	satisfied := true
+	checked := false
+	for _, param := range []string{"times", "atLeast", "atMost"} {
+		raw := query.Get(param)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, param+" must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		checked = true
+		switch param {
+		case "times":
+			satisfied = satisfied && count == n
+		case "atLeast":
+			satisfied = satisfied && count >= n
+		case "atMost":
+			satisfied = satisfied && count <= n
+		}
+	}
+	if !checked {
+		satisfied = count >= 1
+	}
+
+	status := http.StatusOK
+	if !satisfied {
+		status = http.StatusConflict
+	}
+	writeAdminJSON(w, status, verifyResult{Path: endpoint.Path, Method: endpoint.Method, Count: count})
+}
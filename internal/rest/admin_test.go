@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyHits(t *testing.T) {
+	users, err := NewEndpoint("/users/{id}", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}))
+	require.NoError(t, err)
+	health, err := NewEndpoint("/health", "", StaticResponse(Response{statusCode: http.StatusOK}))
+	require.NoError(t, err)
+	endpoints := []*Endpoint{users, health}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	RegisterAdminHandlers(mux, endpoints, "")
+
+	for range 2 {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	}
+
+	cases := map[string]struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		"missing path":            {query: "method=GET", wantCode: http.StatusBadRequest},
+		"unknown endpoint":        {query: "path=/nope&method=GET", wantCode: http.StatusNotFound},
+		"wrong method":            {query: "path=/users/{id}&method=POST", wantCode: http.StatusNotFound},
+		"invalid times":           {query: "path=/users/{id}&method=GET&times=x", wantCode: http.StatusBadRequest},
+		"hit at all":              {query: "path=/users/{id}&method=get", wantCode: http.StatusOK, wantBody: `{"path":"/users/{id}","method":"GET","count":2}`},
+		"never hit":               {query: "path=/health", wantCode: http.StatusConflict, wantBody: `{"path":"/health","count":0}`},
+		"never hit, times zero":   {query: "path=/health&times=0", wantCode: http.StatusOK},
+		"exact times":             {query: "path=/users/{id}&method=GET&times=2", wantCode: http.StatusOK},
+		"wrong times":             {query: "path=/users/{id}&method=GET&times=1", wantCode: http.StatusConflict, wantBody: `{"path":"/users/{id}","method":"GET","count":2}`},
+		"within bounds":           {query: "path=/users/{id}&method=GET&atLeast=1&atMost=3", wantCode: http.StatusOK},
+		"below at least":          {query: "path=/users/{id}&method=GET&atLeast=3", wantCode: http.StatusConflict},
+		"above at most":           {query: "path=/users/{id}&method=GET&atMost=1", wantCode: http.StatusConflict},
+		"bounds and times differ": {query: "path=/users/{id}&method=GET&atLeast=1&times=3", wantCode: http.StatusConflict},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/__admin/verify?"+tc.query, nil))
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetEndpointEnabled(t *testing.T) {
+	users, err := NewEndpoint("/users", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}))
+	require.NoError(t, err)
+	orders, err := NewEndpoint("/orders", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}),
+		WithID("orders"),
+		WithDisabledResponse(Response{statusCode: http.StatusBadGateway, body: []byte("down")}),
+	)
+	require.NoError(t, err)
+	endpoints := []*Endpoint{users, orders}
+	assert.Equal(t, "GET /users", users.ID())
+	assert.Equal(t, "orders", orders.ID())
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	RegisterAdminHandlers(mux, endpoints, "")
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/__admin/endpoints/unknown/disable").Code)
+
+	w := do(http.MethodPost, "/__admin/endpoints/GET%20%2Fusers/disable")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"GET /users","enabled":false}`, w.Body.String())
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/users").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/__admin/endpoints/orders/disable").Code)
+	w = do(http.MethodGet, "/orders")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "down", w.Body.String())
+
+	w = do(http.MethodPost, "/__admin/endpoints/GET%20%2Fusers/enable")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"GET /users","enabled":true}`, w.Body.String())
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/users").Code)
+	assert.Equal(t, http.StatusBadGateway, do(http.MethodGet, "/orders").Code)
+}
+
+func TestResetCount(t *testing.T) {
+	threshold, err := NewThresholdResponse(1, Response{statusCode: http.StatusAccepted}, Response{statusCode: http.StatusOK})
+	require.NoError(t, err)
+	cold, err := NewEndpoint("/config", http.MethodGet, threshold, WithID("config"))
+	require.NoError(t, err)
+	faulted, err := NewThresholdResponse(1, Response{statusCode: http.StatusAccepted}, Response{statusCode: http.StatusOK})
+	require.NoError(t, err)
+	wrapped, err := NewFaultResponse(faulted, []Fault{{Probability: 0.5, Response: Response{statusCode: http.StatusInternalServerError}}}, nil)
+	require.NoError(t, err)
+	withFaults, err := NewEndpoint("/faulty", http.MethodGet, wrapped, WithID("faulty"))
+	require.NoError(t, err)
+	static, err := NewEndpoint("/static", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}), WithID("static"))
+	require.NoError(t, err)
+	endpoints := []*Endpoint{cold, withFaults, static}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	RegisterAdminHandlers(mux, endpoints, "")
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusAccepted, do(http.MethodGet, "/config").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/config").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/__admin/endpoints/config/reset").Code)
+	assert.Equal(t, http.StatusAccepted, do(http.MethodGet, "/config").Code, "the first request's response again")
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/config").Code)
+
+	assert.True(t, withFaults.ResetCount(), "counts are reset through wrapping strategies")
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/__admin/endpoints/static/reset").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/__admin/endpoints/missing/reset").Code)
+}
+
+func TestRegisterAdminHandlersBasePath(t *testing.T) {
+	users, err := NewEndpoint("/api/users", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterAdminHandlers(mux, []*Endpoint{users}, "/api")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/__admin/verify?path=/api/users&method=GET&times=0", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/__admin/verify?path=/api/users&method=GET&times=0", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// bodyDir serves a randomly chosen file from a directory as the body of each response.
+type bodyDir struct {
+	path         string
+	numGenerator numberGenerator
+}
+
+// WithResponseBodyDir responds with a randomly chosen file from the directory at path, so clients
+// see varied payloads. The directory is listed per request, so files can be added and removed while
+// running, but it must hold at least one file when loaded. Each body's Content-Type is set from its
+// file extension or sniffed from its contents, unless the response sets it.
+func WithResponseBodyDir(path string) ResponseOption {
+	return withResponseBodyDir(path, rng{})
+}
+
+func withResponseBodyDir(path string, numGenerator numberGenerator) ResponseOption {
+	return func(r *Response) error {
+		dir := &bodyDir{path: path, numGenerator: numGenerator}
+		files, err := dir.files()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("body dir %q has no files", path)
+		}
+		r.dir = dir
+		return nil
+	}
+}
+
+// files lists the regular files in the directory.
+func (d *bodyDir) files() ([]string, error) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("read body dir: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files = append(files, entry.Name())
+		}
+	}
+	return files, nil
+}
+
+// pick reads a randomly chosen file, returning its contents and Content-Type.
+func (d *bodyDir) pick() ([]byte, string, error) {
+	files, err := d.files()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) == 0 {
+		return nil, "", errors.New("body dir has no files")
+	}
+
+	name := files[d.numGenerator.N(len(files))]
+	body, err := os.ReadFile(filepath.Join(d.path, name))
+	if err != nil {
+		return nil, "", fmt.Errorf("read body file: %w", err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return body, contentType, nil
+}
+
+// loadDirBody replaces the response's body with a file picked from its body dir, if it has one.
+// It responds with a 500 and reports false if the directory can't be read.
+func (r *Response) loadDirBody(w http.ResponseWriter) bool {
+	if r.dir == nil {
+		return true
+	}
+	body, contentType, err := r.dir.pick()
+	if err != nil {
+		slog.Error("failed to pick body from dir", "path", r.dir.path, "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	r.body = body
+	// configured headers are set after, so they take precedence
+	w.Header().Set("Content-Type", contentType)
+	return true
+}
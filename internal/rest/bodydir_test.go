@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResponseBodyDir(t *testing.T) {
+	serve := func(t *testing.T, resp Response) *httptest.ResponseRecorder {
+		t.Helper()
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":1}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b"), []byte("<html></html>"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+
+	t.Run("picks a file", func(t *testing.T) {
+		resp, err := NewResponse(withResponseBodyDir(dir, &mockNumGenerator{val: 0}))
+		require.NoError(t, err)
+		w := serve(t, resp)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"id":1}`, w.Body.String())
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		resp, err = NewResponse(withResponseBodyDir(dir, &mockNumGenerator{val: 1}))
+		require.NoError(t, err)
+		w = serve(t, resp)
+		assert.Equal(t, "<html></html>", w.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"), "should be sniffed")
+	})
+
+	t.Run("configured content type wins", func(t *testing.T) {
+		resp, err := NewResponse(
+			withResponseBodyDir(dir, &mockNumGenerator{val: 0}),
+			WithResponseHeaders(map[string]string{"Content-Type": "text/plain"}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain", serve(t, resp).Header().Get("Content-Type"))
+	})
+
+	t.Run("empty dir", func(t *testing.T) {
+		empty := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(empty, "nested"), 0o755))
+		_, err := NewResponse(WithResponseBodyDir(empty))
+		assert.Error(t, err)
+	})
+
+	t.Run("missing dir", func(t *testing.T) {
+		_, err := NewResponse(WithResponseBodyDir(filepath.Join(dir, "missing")))
+		assert.Error(t, err)
+	})
+
+	t.Run("dir removed while running", func(t *testing.T) {
+		removed := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(removed, "a.txt"), []byte("hi"), 0o644))
+		resp, err := NewResponse(WithResponseBodyDir(removed))
+		require.NoError(t, err)
+		require.NoError(t, os.RemoveAll(removed))
+
+		w := serve(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("not allowed with no content status", func(t *testing.T) {
+		_, err := NewResponse(WithResponseBodyDir(dir), WithResponseStatus(http.StatusNoContent))
+		assert.Error(t, err)
+	})
+}
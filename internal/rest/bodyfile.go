@@ -0,0 +1,184 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// BodyFiles tracks response bodies read from files so they can be reloaded when the files change.
+// Responses sharing a path share a single copy of the body.
+type BodyFiles struct {
+	mu     sync.Mutex
+	bodies map[string]*fileBody
+}
+
+func NewBodyFiles() *BodyFiles {
+	return &BodyFiles{bodies: make(map[string]*fileBody)}
+}
+
+type fileBody struct {
+	path string
+
+	mu      sync.RWMutex
+	body    []byte
+	missing bool
+	modTime time.Time
+	size    int64
+}
+
+// WithResponseBodyFile reads the body from the file at path. If files is non-nil the body is
+// tracked there and reloaded when the file changes, otherwise it is read once.
+func WithResponseBodyFile(path string, files *BodyFiles) ResponseOption {
+	return func(r *Response) error {
+		if files == nil {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read file %q: %w", path, err)
+			}
+			r.body = data
+			return nil
+		}
+
+		fb, err := files.add(path)
+		if err != nil {
+			return err
+		}
+		r.file = fb
+		return nil
+	}
+}
+
+func (f *BodyFiles) add(path string) (*fileBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if fb, ok := f.bodies[path]; ok {
+		return fb, nil
+	}
+	fb := &fileBody{path: path}
+	if _, err := fb.reload(); err != nil {
+		return nil, err
+	}
+	f.bodies[path] = fb
+	return fb, nil
+}
+
+// Watch polls the tracked files every interval until ctx is done, reloading any whose size or
+// modification time changed. Responses for a file that was removed get their missing file status
+// until it's restored. A file that can't be read for any other reason keeps serving its last body.
+func (f *BodyFiles) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.reload()
+		}
+	}
+}
+
+func (f *BodyFiles) reload() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, fb := range f.bodies {
+		changed, err := fb.reload()
+		if errors.Is(err, fs.ErrNotExist) {
+			if fb.markMissing() {
+				slog.Warn("body file removed, serving missing file status until it's restored", "path", fb.path)
+			}
+			continue
+		}
+		if err != nil {
+			slog.Warn("failed to reload body file", "path", fb.path, "err", err)
+			continue
+		}
+		if changed {
+			slog.Info("reloaded body file", "path", fb.path)
+		}
+	}
+}
+
+// reload re-reads the file if it changed since it was last read, reporting whether it did.
+func (fb *fileBody) reload() (bool, error) {
+	info, err := os.Stat(fb.path)
+	if err != nil {
+		return false, fmt.Errorf("stat file %q: %w", fb.path, err)
+	}
+
+	fb.mu.RLock()
+	unchanged := !fb.missing && info.ModTime().Equal(fb.modTime) && info.Size() == fb.size
+	fb.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(fb.path)
+	if err != nil {
+		return false, fmt.Errorf("read file %q: %w", fb.path, err)
+	}
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.body = data
+	fb.missing = false
+	fb.modTime = info.ModTime()
+	fb.size = info.Size()
+	return true, nil
+}
+
+// markMissing records that the file no longer exists, reporting whether it was present before.
+func (fb *fileBody) markMissing() bool {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	wasPresent := !fb.missing
+	fb.missing = true
+	return wasPresent
+}
+
+// load returns the file's latest body, or false if the file was removed.
+func (fb *fileBody) load() ([]byte, bool) {
+	fb.mu.RLock()
+	defer fb.mu.RUnlock()
+	return fb.body, !fb.missing
+}
+
+// WithMissingFileStatus sets the status returned in place of a watched body file that was removed.
+// Defaults to 404.
+func WithMissingFileStatus(statusCode int) ResponseOption {
+	return func(r *Response) error {
+		if statusCode < 100 || statusCode > 599 {
+			return fmt.Errorf("invalid missing file status code: %d", statusCode)
+		}
+		r.missingFileStatus = statusCode
+		return nil
+	}
+}
+
+// currentBody returns the response's body, reading the latest copy of a watched file. It reports
+// false if the watched file was removed.
+func (r Response) currentBody() ([]byte, bool) {
+	if r.file != nil {
+		return r.file.load()
+	}
+	return r.body, true
+}
+
+// writeMissingFile responds in place of a watched body file that was removed.
+func writeMissingFile(w http.ResponseWriter, resp Response) {
+	statusCode := resp.missingFileStatus
+	if statusCode == 0 {
+		statusCode = http.StatusNotFound
+	}
+	w.WriteHeader(statusCode)
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseBodyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":1}`), 0o600))
+
+	getCode := func(t *testing.T, resp Response) (int, string) {
+		t.Helper()
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code, w.Body.String()
+	}
+	get := func(t *testing.T, resp Response) string {
+		t.Helper()
+		_, body := getCode(t, resp)
+		return body
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.json")
+
+		resp, err := NewResponse(WithResponseBodyFile(missing, nil))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+
+		resp, err = NewResponse(WithResponseBodyFile(missing, NewBodyFiles()))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+
+	t.Run("unwatched", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseBodyFile(path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, `{"version":1}`, get(t, resp))
+	})
+
+	t.Run("watched", func(t *testing.T) {
+		files := NewBodyFiles()
+		resp, err := NewResponse(WithResponseBodyFile(path, files))
+		require.NoError(t, err)
+		other, err := NewResponse(WithResponseBodyFile(path, files), WithResponseStatus(http.StatusCreated))
+		require.NoError(t, err)
+		assert.Equal(t, `{"version":1}`, get(t, resp))
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"version":2}`), 0o600))
+		require.NoError(t, os.Chtimes(path, time.Time{}, time.Now().Add(time.Minute)))
+		files.reload()
+		assert.Equal(t, `{"version":2}`, get(t, resp))
+		assert.Equal(t, `{"version":2}`, get(t, other))
+
+	})
+
+	t.Run("removed while watched", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "body.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"version":1}`), 0o600))
+
+		files := NewBodyFiles()
+		resp, err := NewResponse(WithResponseBodyFile(path, files))
+		require.NoError(t, err)
+		gone, err := NewResponse(WithResponseBodyFile(path, files), WithMissingFileStatus(http.StatusGone))
+		require.NoError(t, err)
+
+		require.NoError(t, os.Remove(path))
+		files.reload()
+		files.reload()
+		code, body := getCode(t, resp)
+		assert.Equal(t, http.StatusNotFound, code)
+		assert.Empty(t, body)
+		code, _ = getCode(t, gone)
+		assert.Equal(t, http.StatusGone, code)
+
+		// restoring the file serves it again, even with the same size and modification time
+		require.NoError(t, os.WriteFile(path, []byte(`{"version":1}`), 0o600))
+		files.reload()
+		code, body = getCode(t, resp)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, `{"version":1}`, body)
+	})
+
+	t.Run("invalid missing file status", func(t *testing.T) {
+		resp, err := NewResponse(WithMissingFileStatus(99))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+}
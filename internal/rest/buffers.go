@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferBytes keeps unusually large bodies from pinning memory in the pool.
+const maxPooledBufferBytes = 64 << 10
+
+// bodyBuffers holds buffers dynamic bodies are rendered into, so rendering doesn't allocate a
+// fresh buffer per request. Static bodies are written directly and never copied.
+var bodyBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBodyBuffer() *bytes.Buffer {
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferBytes {
+		return
+	}
+	bodyBuffers.Put(buf)
+}
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// captureSeq numbers captured exchanges across all endpoints, so endpoints capturing to the same
+// directory never write the same file names.
+var captureSeq atomic.Uint64
+
+// requestCapture writes each request to an endpoint, and the response it got, to files in a
+// directory.
+type requestCapture struct {
+	dir          string
+	maxBodyBytes int
+}
+
+// WithCapture writes each request to the endpoint and its response to files in dir, creating it
+// if needed, for inspecting a flaky endpoint's traffic later. Each exchange is written as a pair
+// of files named by the time it was received and a sequence number, such as
+// 20240102T150405.000000000Z-000001-request.http. At most maxBodyBytes of each body are written.
+func WithCapture(dir string, maxBodyBytes int) EndpointOption {
+	return func(e *Endpoint) error {
+		if dir == "" {
+			return errors.New("capture dir is required")
+		}
+		if maxBodyBytes < 0 {
+			return errors.New("capture max body bytes cannot be negative")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create capture dir: %w", err)
+		}
+		e.capture = &requestCapture{dir: dir, maxBodyBytes: maxBodyBytes}
+		return nil
+	}
+}
+
+// handler wraps next, capturing every request it serves.
+func (c *requestCapture) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := time.Now().UTC()
+		reqHead, err := httputil.DumpRequest(r, false)
+		if err != nil {
+			slog.Error("failed to capture request", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		reqBody := peekBody(r, c.maxBodyBytes)
+
+		rec := newDumpRecorder(w, c.maxBodyBytes)
+		next.ServeHTTP(rec, r)
+
+		name := fmt.Sprintf("%s-%06d", received.Format("20060102T150405.000000000Z"), captureSeq.Add(1))
+		c.write(name+"-request.http", reqHead, reqBody)
+		c.write(name+"-response.http", rec.head(r.Proto), rec.body)
+	})
+}
+
+// write writes a captured message, its body cut off at maxBodyBytes.
+func (c *requestCapture) write(name string, head, body []byte) {
+	data := append(head, body[:min(len(body), c.maxBodyBytes)]...)
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0o644); err != nil {
+		slog.Warn("failed to write captured request", "file", name, "err", err)
+	}
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		resp, err := NewResponse()
+		require.NoError(t, err)
+
+		_, err = NewEndpoint("/x", http.MethodPost, StaticResponse(resp), WithCapture("", 10))
+		assert.Error(t, err)
+		_, err = NewEndpoint("/x", http.MethodPost, StaticResponse(resp), WithCapture(t.TempDir(), -1))
+		assert.Error(t, err)
+	})
+
+	dir := filepath.Join(t.TempDir(), "captures")
+	resp, err := NewResponse(
+		WithResponseStatus(http.StatusCreated),
+		WithResponseHeaders(map[string]string{"X-Custom": "value"}),
+		WithResponseBody([]byte("created a new order")),
+	)
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/orders", http.MethodPost, StaticResponse(resp), WithCapture(dir, 8))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/orders?id=1", strings.NewReader(`{"item": "book"}`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "created a new order", rec.Body.String(), "the client gets the full body")
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	assert.Regexp(t, `^\d{8}T\d{6}\.\d{9}Z-\d{6}-request\.http$`, names[0])
+	assert.Regexp(t, `^\d{8}T\d{6}\.\d{9}Z-\d{6}-response\.http$`, names[1])
+	assert.NotEqual(t, strings.TrimSuffix(names[0], "-request.http"), strings.TrimSuffix(names[2], "-request.http"))
+
+	request, err := os.ReadFile(filepath.Join(dir, names[0]))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(request), "POST /orders?id=1 HTTP/1.1\r\n"))
+	assert.True(t, strings.HasSuffix(string(request), "\r\n\r\n"+`{"item":`))
+
+	response, err := os.ReadFile(filepath.Join(dir, names[1]))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(response), "HTTP/1.1 201 Created\r\n"))
+	assert.Contains(t, string(response), "X-Custom: value\r\n")
+	assert.True(t, strings.HasSuffix(string(response), "\r\n\r\ncreated "))
+}
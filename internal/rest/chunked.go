@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// WithChunkSize writes the body in chunks of chunkSize bytes, flushing after each, so the body is
+// sent with chunked transfer encoding and no Content-Length, even when it's small. Chunks are sent
+// without pause unless the response is also throttled.
+func WithChunkSize(chunkSize int) ResponseOption {
+	return func(r *Response) error {
+		if chunkSize <= 0 {
+			return fmt.Errorf("chunk size must be positive: %d", chunkSize)
+		}
+		r.chunkSize = chunkSize
+		return nil
+	}
+}
+
+// chunkedResponseWriter flushes every chunkSize bytes written through it.
+type chunkedResponseWriter struct {
+	http.ResponseWriter
+	rc        *http.ResponseController
+	chunkSize int
+}
+
+func newChunkedResponseWriter(w http.ResponseWriter, chunkSize int) *chunkedResponseWriter {
+	return &chunkedResponseWriter{ResponseWriter: w, rc: http.NewResponseController(w), chunkSize: chunkSize}
+}
+
+func (c *chunkedResponseWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n, err := c.ResponseWriter.Write(p[:min(c.chunkSize, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if err := c.rc.Flush(); err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (c *chunkedResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
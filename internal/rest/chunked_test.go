@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithChunkSize(t *testing.T) {
+	t.Run("invalid size", func(t *testing.T) {
+		for _, size := range []int{0, -1} {
+			_, err := NewResponse(WithChunkSize(size))
+			assert.Error(t, err, size)
+		}
+	})
+
+	resp, err := NewResponse(WithResponseBody([]byte("hello world")), WithChunkSize(4))
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint}, WithAutoContentLength())
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+	require.NoError(t, err)
+	got, err := io.ReadAll(conn)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(got), "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, string(got), "Content-Length")
+	assert.Contains(t, string(got), "\r\n\r\n4\r\nhell\r\n4\r\no wo\r\n3\r\nrld\r\n0\r\n\r\n")
+}
@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compression gzips a response's body for clients that accept it. A static body is compressed
+// once, when the response is built; dynamic bodies, such as templates and watched files, are
+// compressed per request.
+type compression struct {
+	// static is the response's static body, and gzipped its precomputed variant.
+	static  []byte
+	gzipped []byte
+}
+
+// WithCompression gzips the body for requests whose Accept-Encoding allows it, setting
+// Content-Encoding and Vary headers. Generated and streamed bodies are sent uncompressed.
+func WithCompression() ResponseOption {
+	return func(r *Response) error {
+		r.compression = &compression{}
+		return nil
+	}
+}
+
+// precompress compresses the response's body ahead of time, if it's static.
+func (r *Response) precompress() error {
+	if r.compression == nil || len(r.body) == 0 || r.file != nil || r.dir != nil || r.template != nil {
+		return nil
+	}
+	gzipped, err := gzipBytes(r.body)
+	if err != nil {
+		return err
+	}
+	r.compression = &compression{static: r.body, gzipped: gzipped}
+	return nil
+}
+
+// compress returns body gzipped, using the precomputed variant when body is the response's
+// unchanged static body.
+func (c *compression) compress(body []byte) ([]byte, error) {
+	if c.gzipped != nil && sameBytes(body, c.static) {
+		return c.gzipped, nil
+	}
+	return gzipBytes(body)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sameBytes reports whether a and b are the same slice, not just equal contents.
+func sameBytes(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows a gzipped response.
+func acceptsGzip(r *http.Request) bool {
+	gzipQ, wildcardQ := -1.0, -1.0
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for part := range strings.SplitSeq(header, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			q := 1.0
+			if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					continue
+				}
+				q = parsed
+			}
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case "gzip", "x-gzip":
+				gzipQ = q
+			case "*":
+				wildcardQ = q
+			}
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return wildcardQ > 0
+}
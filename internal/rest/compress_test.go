@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	serve := func(t *testing.T, acceptEncoding string, opts ...ResponseOption) *httptest.ResponseRecorder {
+		t.Helper()
+		resp, err := NewResponse(opts...)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/data", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	gunzip := func(t *testing.T, body io.Reader) string {
+		t.Helper()
+		zr, err := gzip.NewReader(body)
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	body := []byte(strings.Repeat(`{"hello":"world"}`, 100))
+
+	t.Run("static body precomputed", func(t *testing.T) {
+		resp, err := NewResponse(WithCompression(), WithResponseBody(body))
+		require.NoError(t, err)
+		require.NotNil(t, resp.compression.gzipped)
+
+		rec := serve(t, "gzip, deflate", WithCompression(), WithResponseBody(body))
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Less(t, rec.Body.Len(), len(body))
+		assert.Equal(t, string(body), gunzip(t, rec.Body))
+	})
+
+	t.Run("template compressed per request", func(t *testing.T) {
+		rec := serve(t, "gzip", WithCompression(), WithResponseTemplate(`hello {{ .Proto }}`, nil))
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "hello HTTP/1.1", gunzip(t, rec.Body))
+		assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "identity", "gzip;q=0", "br, *;q=0", "*;q=1, gzip;q=0"} {
+			rec := serve(t, acceptEncoding, WithCompression(), WithResponseBody(body))
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"), acceptEncoding)
+			assert.Equal(t, body, rec.Body.Bytes(), acceptEncoding)
+		}
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		rec := serve(t, "br;q=1.0, *;q=0.5", WithCompression(), WithResponseBody(body))
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("without compression", func(t *testing.T) {
+		rec := serve(t, "gzip", WithResponseBody(body))
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Empty(t, rec.Header().Get("Vary"))
+		assert.Equal(t, body, rec.Body.Bytes())
+	})
+}
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type ConcurrencyLimit struct {
+	// Max is the number of requests handled at once.
+	Max int
+	// QueueTimeout is how long requests over the limit wait for a slot before being rejected. Zero
+	// rejects them immediately.
+	QueueTimeout time.Duration
+	// Response is returned instead of the endpoint's response to rejected requests.
+	Response Response
+}
+
+// concurrencyLimiter is a semaphore bounding the requests an endpoint handles at once.
+type concurrencyLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	response     Response
+}
+
+// WithConcurrencyLimit bounds the requests the endpoint handles at once, simulating a backend with
+// limited capacity. Requests over the limit are queued up to the limit's queue timeout, then get
+// the limit's response.
+func WithConcurrencyLimit(limit ConcurrencyLimit) EndpointOption {
+	return func(e *Endpoint) error {
+		if limit.Max <= 0 {
+			return errors.New("max concurrency must be >= 1")
+		}
+		if limit.QueueTimeout < 0 {
+			return errors.New("queue timeout cannot be negative")
+		}
+		e.concurrencyLimiter = &concurrencyLimiter{
+			slots:        make(chan struct{}, limit.Max),
+			queueTimeout: limit.QueueTimeout,
+			response:     limit.Response,
+		}
+		return nil
+	}
+}
+
+// acquire takes a slot, waiting up to the queue timeout for one to free up. It reports false if no
+// slot was taken, in which case release must not be called.
+func (l *concurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.queueTimeout == 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
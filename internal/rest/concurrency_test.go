@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	ok := Response{statusCode: http.StatusOK, delay: 100 * time.Millisecond}
+	rejected := Response{statusCode: http.StatusServiceUnavailable}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewEndpoint("/", http.MethodGet, StaticResponse(ok), WithConcurrencyLimit(ConcurrencyLimit{Max: 0}))
+		assert.Error(t, err)
+
+		_, err = NewEndpoint("/", http.MethodGet, StaticResponse(ok), WithConcurrencyLimit(ConcurrencyLimit{Max: 1, QueueTimeout: -time.Second}))
+		assert.Error(t, err)
+	})
+
+	// serve sends concurrent requests, returning their statuses in no particular order
+	serve := func(t *testing.T, limit ConcurrencyLimit, requests int) []int {
+		t.Helper()
+		endpoint, err := NewEndpoint("/busy", http.MethodGet, StaticResponse(ok), WithConcurrencyLimit(limit))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		var mu sync.Mutex
+		var statuses []int
+		var wg sync.WaitGroup
+		for range requests {
+			wg.Go(func() {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/busy", nil))
+				mu.Lock()
+				defer mu.Unlock()
+				statuses = append(statuses, rec.Code)
+			})
+		}
+		wg.Wait()
+		return statuses
+	}
+
+	t.Run("rejects immediately", func(t *testing.T) {
+		statuses := serve(t, ConcurrencyLimit{Max: 2, Response: rejected}, 4)
+		assert.ElementsMatch(t, []int{200, 200, 503, 503}, statuses)
+	})
+
+	t.Run("queues", func(t *testing.T) {
+		statuses := serve(t, ConcurrencyLimit{Max: 2, QueueTimeout: time.Second, Response: rejected}, 4)
+		assert.ElementsMatch(t, []int{200, 200, 200, 200}, statuses)
+	})
+
+	t.Run("queue timeout", func(t *testing.T) {
+		statuses := serve(t, ConcurrencyLimit{Max: 1, QueueTimeout: 10 * time.Millisecond, Response: rejected}, 2)
+		assert.ElementsMatch(t, []int{200, 503}, statuses)
+	})
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithETag sets an ETag derived from the response body on responses to safe methods, answering
+// requests with a matching If-None-Match with a 304.
+func WithETag() EndpointOption {
+	return func(e *Endpoint) error {
+		e.etag = true
+		return nil
+	}
+}
+
+// WithLastModified sets the Last-Modified header on responses to safe methods, answering requests
+// with an If-Modified-Since at or after it with a 304.
+func WithLastModified(lastModified time.Time) EndpointOption {
+	return func(e *Endpoint) error {
+		e.lastModified = lastModified.UTC().Truncate(time.Second)
+		return nil
+	}
+}
+
+// checkNotModified sets validator headers for resp and reports whether the request's conditional
+// headers allow a 304 in place of resp. Only successful responses to GET and HEAD are considered.
+func (p *Endpoint) checkNotModified(w http.ResponseWriter, r *http.Request, resp Response) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if resp.statusCode != http.StatusOK {
+		return false
+	}
+
+	var etag string
+	if p.etag {
+		sum := sha256.Sum256(resp.body)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+	}
+	if !p.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", p.lastModified.Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since when both are present
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagMatches(ifNoneMatch, etag)
+	}
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !p.lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !p.lastModified.After(since)
+	}
+	return false
+}
+
+// etagMatches performs the weak comparison If-None-Match calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
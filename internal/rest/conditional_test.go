@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	lastModified := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	resp := Response{
+		statusCode: http.StatusOK,
+		body:       []byte(`{"id":1}`),
+	}
+	endpoint, err := NewEndpoint("/item", "", StaticResponse(resp), WithETag(), WithLastModified(lastModified))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, lastModified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	cases := map[string]struct {
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		"matching etag": {
+			method:  http.MethodGet,
+			headers: map[string]string{"If-None-Match": etag},
+			want:    http.StatusNotModified,
+		},
+		"weak matching etag in list": {
+			method:  http.MethodHead,
+			headers: map[string]string{"If-None-Match": `"other", W/` + etag},
+			want:    http.StatusNotModified,
+		},
+		"stale etag": {
+			method:  http.MethodGet,
+			headers: map[string]string{"If-None-Match": `"stale"`},
+			want:    http.StatusOK,
+		},
+		"etag takes precedence over date": {
+			method: http.MethodGet,
+			headers: map[string]string{
+				"If-None-Match":     `"stale"`,
+				"If-Modified-Since": lastModified.Format(http.TimeFormat),
+			},
+			want: http.StatusOK,
+		},
+		"not modified since": {
+			method:  http.MethodGet,
+			headers: map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			want:    http.StatusNotModified,
+		},
+		"modified since": {
+			method:  http.MethodGet,
+			headers: map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			want:    http.StatusOK,
+		},
+		"unsafe method": {
+			method:  http.MethodPut,
+			headers: map[string]string{"If-None-Match": etag},
+			want:    http.StatusOK,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/item", nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.want, w.Code)
+			if tc.want == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// responseChoice describes which of a strategy's responses was chosen, for debug headers.
+type responseChoice struct {
+	strategy string
+	index    int
+}
+
+// choosingResolver is implemented by resolvers that can report which response they chose.
+type choosingResolver interface {
+	nextChosenResponse(r *http.Request) (Response, responseChoice)
+}
+
+func nextChosenResponse(resolver ResponseResolver, r *http.Request) (Response, responseChoice) {
+	if chooser, ok := resolver.(choosingResolver); ok {
+		return chooser.nextChosenResponse(r)
+	}
+	return resolver.NextResponse(r), responseChoice{strategy: "unknown"}
+}
+
+// WithDebugHeaders adds X-Mock-Strategy and X-Mock-Response-Index headers to every response,
+// naming the strategy that produced it and which of its responses was chosen. A Server-Timing
+// header also breaks down the time spent on the response's delay, rendering its body, and in total.
+func WithDebugHeaders() HandlerOption {
+	return func(o *handlerOptions) {
+		o.debugHeaders = true
+	}
+}
+
+// StatusOverrideHeader is the request header that overrides a response's status when enabled.
+const StatusOverrideHeader = "X-Mock-Status"
+
+// WithStatusOverride lets clients force the status of an endpoint's response with the
+// StatusOverrideHeader request header, keeping the response's headers and body. Requests with a
+// header that isn't a valid status get a 400.
+func WithStatusOverride() HandlerOption {
+	return func(o *handlerOptions) {
+		o.statusOverride = true
+	}
+}
+
+// overrideStatus applies the request's status override to resp. It reports false if the override
+// isn't a valid status.
+func overrideStatus(r *http.Request, resp *Response) bool {
+	header := r.Header.Get(StatusOverrideHeader)
+	if header == "" {
+		return true
+	}
+	statusCode, err := strconv.Atoi(header)
+	if err != nil || statusCode < 200 || statusCode > 599 {
+		return false
+	}
+	resp.statusCode = statusCode
+	return true
+}
+
+// IndexOverrideHeader is the request header that picks which of a strategy's responses is returned
+// when enabled.
+const IndexOverrideHeader = "X-Mock-Index"
+
+// WithIndexOverride lets clients pick which of an endpoint's responses they get with the
+// IndexOverrideHeader request header, using the same indexes as X-Mock-Response-Index. The strategy's
+// state, such as a sequence's position, isn't advanced. Requests with an index that's invalid, out of
+// range, or for a strategy that can't pick responses by index get a 400.
+func WithIndexOverride() HandlerOption {
+	return func(o *handlerOptions) {
+		o.indexOverride = true
+	}
+}
+
+// indexedResolver is implemented by resolvers whose responses can be picked by index.
+type indexedResolver interface {
+	// responseAt returns the response at index without advancing the resolver, reporting false if
+	// index is out of range.
+	responseAt(r *http.Request, index int) (Response, responseChoice, bool)
+}
+
+func responseAt(resolver ResponseResolver, r *http.Request, index int) (Response, responseChoice, bool) {
+	if indexed, ok := resolver.(indexedResolver); ok {
+		return indexed.responseAt(r, index)
+	}
+	return Response{}, responseChoice{}, false
+}
+
+func setDebugHeaders(w http.ResponseWriter, choice responseChoice) {
+	w.Header().Set("X-Mock-Strategy", choice.strategy)
+	w.Header().Set("X-Mock-Response-Index", strconv.Itoa(choice.index))
+}
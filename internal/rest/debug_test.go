@@ -0,0 +1,239 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHeaders(t *testing.T) {
+	ok := Response{statusCode: http.StatusOK}
+	created := Response{statusCode: http.StatusCreated}
+
+	sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{ok, created}, nil)
+	require.NoError(t, err)
+	weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+		{Response: ok, Weight: 1},
+		{Response: created, Weight: 1},
+	}, &mockNumGenerator{val: 1})
+	require.NoError(t, err)
+	faulted, err := NewFaultResponse(sequence, []Fault{{Probability: 1, Response: Response{statusCode: http.StatusBadGateway}}}, nil)
+	require.NoError(t, err)
+
+	endpoint := func(t *testing.T, path string, resolver ResponseResolver) *Endpoint {
+		t.Helper()
+		e, err := NewEndpoint(path, http.MethodGet, resolver)
+		require.NoError(t, err)
+		return e
+	}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{
+		endpoint(t, "/static", StaticResponse(ok)),
+		endpoint(t, "/sequence", sequence),
+		endpoint(t, "/weighted", weighted),
+		endpoint(t, "/faulted", faulted),
+	}, WithDebugHeaders())
+
+	cases := []struct {
+		path         string
+		wantStrategy string
+		wantIndex    string
+	}{
+		{path: "/static", wantStrategy: "static", wantIndex: "0"},
+		{path: "/sequence", wantStrategy: "sequence", wantIndex: "0"},
+		{path: "/sequence", wantStrategy: "sequence", wantIndex: "1"},
+		{path: "/weighted", wantStrategy: "weighted", wantIndex: "1"},
+		{path: "/faulted", wantStrategy: "fault", wantIndex: "0"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.wantStrategy, w.Header().Get("X-Mock-Strategy"), tc.path)
+		assert.Equal(t, tc.wantIndex, w.Header().Get("X-Mock-Response-Index"), tc.path)
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint(t, "/static", StaticResponse(ok))})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static", nil))
+		assert.Empty(t, w.Header().Get("X-Mock-Strategy"))
+		assert.Empty(t, w.Header().Get("X-Mock-Response-Index"))
+	})
+}
+
+func TestServerTiming(t *testing.T) {
+	delayed, err := NewResponse(
+		WithResponseDelay(20*time.Millisecond),
+		WithResponseTemplate(`{{ .Proto }}`, nil),
+	)
+	require.NoError(t, err)
+	plain, err := NewResponse(WithResponseBody([]byte("plain")))
+	require.NoError(t, err)
+
+	serve := func(t *testing.T, resp Response, opts ...HandlerOption) *httptest.ResponseRecorder {
+		t.Helper()
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, opts...)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	t.Run("delay and render", func(t *testing.T) {
+		w := serve(t, delayed, WithDebugHeaders())
+		assert.Equal(t, "HTTP/1.1", w.Body.String())
+		timing := w.Header().Get("Server-Timing")
+		assert.Regexp(t, `^delay;dur=20, render;dur=[0-9.]+, total;dur=[0-9.]+$`, timing)
+	})
+
+	t.Run("only total", func(t *testing.T) {
+		w := serve(t, plain, WithDebugHeaders())
+		assert.Regexp(t, `^total;dur=[0-9.]+$`, w.Header().Get("Server-Timing"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		w := serve(t, plain)
+		assert.Empty(t, w.Header().Get("Server-Timing"))
+	})
+}
+
+func TestServerTimingMetric(t *testing.T) {
+	assert.Equal(t, "delay;dur=100", serverTimingMetric("delay", 100*time.Millisecond))
+	assert.Equal(t, "render;dur=0.35", serverTimingMetric("render", 350*time.Microsecond))
+	assert.Equal(t, "total;dur=1.235", serverTimingMetric("total", 1234567*time.Nanosecond))
+}
+
+func TestStatusOverride(t *testing.T) {
+	resp, err := NewResponse(
+		WithResponseBody([]byte("configured")),
+		WithResponseHeaders(map[string]string{"X-Configured": "yes"}),
+	)
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+	require.NoError(t, err)
+
+	serve := func(t *testing.T, override string, opts ...HandlerOption) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, opts...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if override != "" {
+			req.Header.Set(StatusOverrideHeader, override)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		w := serve(t, "503")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		w := serve(t, "503", WithStatusOverride())
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "configured", w.Body.String())
+		assert.Equal(t, "yes", w.Header().Get("X-Configured"))
+	})
+
+	t.Run("no header", func(t *testing.T) {
+		w := serve(t, "", WithStatusOverride())
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("status without body", func(t *testing.T) {
+		w := serve(t, "204", WithStatusOverride())
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, override := range []string{"abc", "100", "600", "-1"} {
+			w := serve(t, override, WithStatusOverride())
+			assert.Equal(t, http.StatusBadRequest, w.Code, override)
+		}
+	})
+}
+
+func TestIndexOverride(t *testing.T) {
+	first, err := NewResponse(WithResponseBody([]byte("first")))
+	require.NoError(t, err)
+	second, err := NewResponse(WithResponseBody([]byte("second")))
+	require.NoError(t, err)
+
+	serve := func(t *testing.T, resolver ResponseResolver, index string, opts ...HandlerOption) *httptest.ResponseRecorder {
+		t.Helper()
+		endpoint, err := NewEndpoint("/", http.MethodGet, resolver)
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, opts...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if index != "" {
+			req.Header.Set(IndexOverrideHeader, index)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
+		require.NoError(t, err)
+		w := serve(t, sequence, "1")
+		assert.Equal(t, "first", w.Body.String())
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+			{Response: first, Weight: 1},
+			{Response: second, Weight: 1},
+		}, &mockNumGenerator{val: 0})
+		require.NoError(t, err)
+		w := serve(t, weighted, "1", WithIndexOverride(), WithDebugHeaders())
+		assert.Equal(t, "second", w.Body.String())
+		assert.Equal(t, "weighted", w.Header().Get("X-Mock-Strategy"))
+		assert.Equal(t, "1", w.Header().Get("X-Mock-Response-Index"))
+	})
+
+	t.Run("sequence isn't advanced", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
+		require.NoError(t, err)
+		w := serve(t, sequence, "1", WithIndexOverride())
+		assert.Equal(t, "second", w.Body.String())
+		w = serve(t, sequence, "", WithIndexOverride())
+		assert.Equal(t, "first", w.Body.String())
+	})
+
+	t.Run("fault skipped", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
+		require.NoError(t, err)
+		faulted, err := NewFaultResponse(sequence, []Fault{{Probability: 1, Response: Response{statusCode: http.StatusInternalServerError}}}, nil)
+		require.NoError(t, err)
+		w := serve(t, faulted, "1", WithIndexOverride())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "second", w.Body.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
+		require.NoError(t, err)
+		for _, index := range []string{"abc", "-1", "2"} {
+			w := serve(t, sequence, index, WithIndexOverride())
+			assert.Equal(t, http.StatusBadRequest, w.Code, index)
+		}
+	})
+
+	t.Run("strategy without indexes", func(t *testing.T) {
+		w := serve(t, StaticResponse(first), "0", WithIndexOverride())
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DecompressRequestBody transparently decompresses request bodies sent with a gzip or deflate
+// Content-Encoding, so handlers read the original body. Requests with any other encoding get a
+// 415. Reads of the decompressed body are capped at maxBytes, with 0 disabling the limit.
+func DecompressRequestBody(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Content-Encoding")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var encodings []string
+		for encoding := range strings.SplitSeq(header, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			switch encoding {
+			case "identity", "":
+				continue
+			case "gzip", "x-gzip", "deflate":
+				encodings = append(encodings, encoding)
+			default:
+				w.Header().Set("Accept-Encoding", "gzip, deflate")
+				http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+
+		// encodings are listed in the order they were applied
+		body := r.Body
+		for _, encoding := range slices.Backward(encodings) {
+			decoded, err := decompressReader(encoding, body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s request body", encoding), http.StatusBadRequest)
+				return
+			}
+			body = decoded
+		}
+		if maxBytes > 0 {
+			body = http.MaxBytesReader(w, body, maxBytes)
+		}
+
+		r.Body = body
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
+
+func decompressReader(encoding string, r io.ReadCloser) (io.ReadCloser, error) {
+	if encoding == "deflate" {
+		return zlib.NewReader(r)
+	}
+	return gzip.NewReader(r)
+}
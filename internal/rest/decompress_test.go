@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressRequestBody(t *testing.T) {
+	compress := func(t *testing.T, encoding, body string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		var w io.WriteCloser = gzip.NewWriter(&buf)
+		if encoding == "deflate" {
+			w = zlib.NewWriter(&buf)
+		}
+		_, err := io.WriteString(w, body)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+
+	cases := map[string]struct {
+		encoding string
+		body     []byte
+		maxBytes int64
+		wantCode int
+		wantBody string
+	}{
+		"no encoding": {
+			body:     []byte("plain"),
+			wantCode: http.StatusOK,
+			wantBody: "plain",
+		},
+		"gzip": {
+			encoding: "gzip",
+			body:     compress(t, "gzip", "hello gzip"),
+			wantCode: http.StatusOK,
+			wantBody: "hello gzip",
+		},
+		"deflate": {
+			encoding: "deflate",
+			body:     compress(t, "deflate", "hello deflate"),
+			wantCode: http.StatusOK,
+			wantBody: "hello deflate",
+		},
+		"stacked encodings": {
+			encoding: "deflate, gzip",
+			body: func() []byte {
+				deflated := compress(t, "deflate", "hello both")
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				_, _ = w.Write(deflated)
+				_ = w.Close()
+				return buf.Bytes()
+			}(),
+			wantCode: http.StatusOK,
+			wantBody: "hello both",
+		},
+		"unsupported encoding": {
+			encoding: "br",
+			body:     []byte("whatever"),
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		"invalid gzip": {
+			encoding: "gzip",
+			body:     []byte("not gzip"),
+			wantCode: http.StatusBadRequest,
+		},
+		"decompressed body too large": {
+			encoding: "gzip",
+			body:     compress(t, "gzip", strings.Repeat("a", 1024)),
+			maxBytes: 100,
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+			if tc.encoding != "" {
+				req.Header.Set("Content-Encoding", tc.encoding)
+			}
+			w := httptest.NewRecorder()
+			DecompressRequestBody(echo, tc.maxBytes).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
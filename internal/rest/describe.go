@@ -0,0 +1,311 @@
+package rest
+
+import (
+	"fmt"
+	"maps"
+	"time"
+	"unicode/utf8"
+)
+
+// EndpointDescription is an endpoint's configuration as resolved at startup, after env
+// substitution, defaults, and named response references, for checking a config does what's meant.
+type EndpointDescription struct {
+	ID       string              `json:"id"`
+	Path     string              `json:"path"`
+	Method   string              `json:"method,omitempty"`
+	Enabled  bool                `json:"enabled"`
+	Strategy StrategyDescription `json:"strategy"`
+}
+
+// StrategyDescription describes a response strategy. Type is named like the X-Mock-Strategy debug
+// header, and the other fields are set as they apply to the strategy.
+type StrategyDescription struct {
+	Type string `json:"type"`
+	// Entries are the strategy's responses, in the order of their X-Mock-Response-Index.
+	Entries []EntryDescription `json:"entries,omitempty"`
+	// Fallback answers requests no entry covers.
+	Fallback *ResponseDescription `json:"fallback,omitempty"`
+	// Wrapped is the strategy a fault, outage, per client, or sticky strategy wraps.
+	Wrapped *StrategyDescription `json:"wrapped,omitempty"`
+	// Strategies are a fallthrough strategy's strategies, in the order they're tried.
+	Strategies []StrategyDescription `json:"strategies,omitempty"`
+
+	EndBehavior SequenceBehavior     `json:"endBehavior,omitempty"`
+	End         *ResponseDescription `json:"end,omitempty"`
+	Threshold   int                  `json:"threshold,omitempty"`
+	Limit       int                  `json:"limit,omitempty"`
+	Window      string               `json:"window,omitempty"`
+	Mode        WindowMode           `json:"mode,omitempty"`
+	Timezone    string               `json:"timezone,omitempty"`
+	Sticky      bool                 `json:"sticky,omitempty"`
+	// Every and For are an outage's period and duration.
+	Every string `json:"every,omitempty"`
+	For   string `json:"for,omitempty"`
+}
+
+// EntryDescription describes one of a strategy's responses, with what selects it.
+type EntryDescription struct {
+	Weight         float64 `json:"weight,omitempty"`
+	MaxOccurrences int     `json:"maxOccurrences,omitempty"`
+	Probability    float64 `json:"probability,omitempty"`
+	MediaType      string  `json:"mediaType,omitempty"`
+	Method         string  `json:"method,omitempty"`
+	Error          bool    `json:"error,omitempty"`
+	// Window is a scheduled entry's time of day, such as 09:00-17:00.
+	Window         string            `json:"window,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Query          map[string]string `json:"query,omitempty"`
+	HTTPVersion    string            `json:"httpVersion,omitempty"`
+	ClientCertName string            `json:"clientCertName,omitempty"`
+	MinBodyBytes   int64             `json:"minBodyBytes,omitempty"`
+	MaxBodyBytes   int64             `json:"maxBodyBytes,omitempty"`
+
+	Response ResponseDescription `json:"response"`
+}
+
+// ResponseDescription describes a response. Templated header values and bodies are shown as their
+// template source, and bodies that aren't literal text by where they come from.
+type ResponseDescription struct {
+	Status            int                 `json:"status,omitempty"`
+	Headers           map[string][]string `json:"headers,omitempty"`
+	Body              string              `json:"body,omitempty"`
+	BodyBytes         int                 `json:"bodyBytes,omitempty"`
+	BodyFile          string              `json:"bodyFile,omitempty"`
+	BodyDir           string              `json:"bodyDir,omitempty"`
+	Template          string              `json:"template,omitempty"`
+	GeneratedBytes    int64               `json:"generatedBytes,omitempty"`
+	Stream            bool                `json:"stream,omitempty"`
+	WebSocket         bool                `json:"webSocket,omitempty"`
+	StaticDir         bool                `json:"staticDir,omitempty"`
+	Delay             string              `json:"delay,omitempty"`
+	DelayDistribution *DelayDescription   `json:"delayDistribution,omitempty"`
+	Fault             ConnectionFault     `json:"fault,omitempty"`
+	TruncateBytes     int                 `json:"truncateBytes,omitempty"`
+}
+
+// DelayDescription describes a delay distribution, with its durations written as strings.
+type DelayDescription struct {
+	Type   DelayDistributionType `json:"type"`
+	Mean   string                `json:"mean"`
+	StdDev string                `json:"stdDev,omitempty"`
+}
+
+// describingResolver is implemented by resolvers that can describe their configuration.
+type describingResolver interface {
+	describe() StrategyDescription
+}
+
+func describeResolver(resolver ResponseResolver) StrategyDescription {
+	if d, ok := resolver.(describingResolver); ok {
+		return d.describe()
+	}
+	return StrategyDescription{Type: "unknown"}
+}
+
+// Describe returns the endpoint's resolved configuration.
+func (p *Endpoint) Describe() EndpointDescription {
+	return EndpointDescription{
+		ID:       p.ID(),
+		Path:     p.Path,
+		Method:   p.Method,
+		Enabled:  !p.disabled.Load(),
+		Strategy: describeResolver(p.responseResolver),
+	}
+}
+
+func (r Response) describe() ResponseDescription {
+	d := ResponseDescription{
+		Status:        r.statusCode,
+		Fault:         r.connFault,
+		TruncateBytes: r.truncateBytes,
+		Stream:        r.stream != nil,
+		WebSocket:     r.websocket != nil,
+		StaticDir:     r.staticDir != nil,
+	}
+	if r.delay > 0 {
+		d.Delay = r.delay.String()
+	}
+	if r.delaySampler != nil {
+		dist := r.delaySampler.dist
+		d.DelayDistribution = &DelayDescription{Type: dist.Type, Mean: dist.Mean.String()}
+		if dist.StdDev > 0 {
+			d.DelayDistribution.StdDev = dist.StdDev.String()
+		}
+	}
+
+	if len(r.headers)+len(r.multiHeaders)+len(r.headerTemplates) > 0 {
+		d.Headers = make(map[string][]string)
+		for name, val := range r.headers {
+			d.Headers[name] = []string{val}
+		}
+		maps.Copy(d.Headers, r.multiHeaders)
+		for name, tmpls := range r.headerTemplates {
+			d.Headers[name] = nil
+			for _, tmpl := range tmpls {
+				d.Headers[name] = append(d.Headers[name], tmpl.Root.String())
+			}
+		}
+	}
+
+	switch {
+	case r.file != nil:
+		d.BodyFile = r.file.path
+	case r.dir != nil:
+		d.BodyDir = r.dir.path
+	case r.template != nil:
+		d.Template = r.template.Root.String()
+	case r.generated != nil:
+		d.GeneratedBytes = r.generated.size
+	case len(r.body) > 0:
+		d.BodyBytes = len(r.body)
+		if utf8.Valid(r.body) {
+			d.Body = string(r.body)
+		}
+	}
+	return d
+}
+
+func (r *Response) describePtr() *ResponseDescription {
+	if r == nil {
+		return nil
+	}
+	d := r.describe()
+	return &d
+}
+
+func responseEntries(responses ...Response) []EntryDescription {
+	entries := make([]EntryDescription, len(responses))
+	for i, resp := range responses {
+		entries[i] = EntryDescription{Response: resp.describe()}
+	}
+	return entries
+}
+
+func (r StaticResponse) describe() StrategyDescription {
+	return StrategyDescription{Type: "static", Entries: responseEntries(Response(r))}
+}
+
+// describe reports each entry's current weight, which changes over a ramp and drops to zero once
+// an entry reaches its maxOccurrences.
+func (w *WeightedResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "weighted"}
+	for i, share := range w.weightShares() {
+		entry := EntryDescription{Weight: share.Weight, Response: w.responses[i].describe()}
+		if w.limits != nil {
+			entry.MaxOccurrences = w.limits[i].max
+		}
+		d.Entries = append(d.Entries, entry)
+	}
+	return d
+}
+
+func (s *SequencedResponse) describe() StrategyDescription {
+	return StrategyDescription{
+		Type:        "sequence",
+		Entries:     responseEntries(s.sequence...),
+		EndBehavior: s.endBehavior,
+		End:         s.end.describePtr(),
+	}
+}
+
+func (t *ThresholdResponse) describe() StrategyDescription {
+	return StrategyDescription{
+		Type:      "afterCount",
+		Entries:   responseEntries(t.before, t.after),
+		Threshold: t.threshold,
+	}
+}
+
+func (w *WindowResponse) describe() StrategyDescription {
+	return StrategyDescription{
+		Type:    "window",
+		Entries: responseEntries(w.allowed, w.exceeded),
+		Limit:   w.limit,
+		Window:  w.window.String(),
+		Mode:    w.mode,
+	}
+}
+
+func (f *FaultResponse) describe() StrategyDescription {
+	wrapped := describeResolver(f.resolver)
+	d := StrategyDescription{Type: "fault", Wrapped: &wrapped}
+	for _, fault := range f.faults {
+		d.Entries = append(d.Entries, EntryDescription{Probability: fault.Probability, Response: fault.Response.describe()})
+	}
+	return d
+}
+
+func (p *PerClientResponse) describe() StrategyDescription {
+	wrapped := describeResolver(p.prototype)
+	if wrapped.Sticky {
+		return wrapped
+	}
+	return StrategyDescription{Type: "perClient", Wrapped: &wrapped}
+}
+
+// describe describes the strategy made sticky, which is what the per client strategy holding it
+// reports.
+func (s *stickyResolver) describe() StrategyDescription {
+	d := describeResolver(s.resolver)
+	d.Sticky = true
+	return d
+}
+
+func (n *NegotiatedResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "representations", Fallback: n.fallback.describePtr()}
+	for _, rep := range n.representations {
+		d.Entries = append(d.Entries, EntryDescription{MediaType: rep.MediaType, Response: rep.Response.describe()})
+	}
+	return d
+}
+
+func (c *MatchResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "match"}
+	for _, matchCase := range c.cases {
+		entry := EntryDescription{
+			Headers:        matchCase.Headers,
+			Query:          matchCase.Query,
+			HTTPVersion:    matchCase.HTTPVersion,
+			ClientCertName: matchCase.ClientCertName,
+			Response:       matchCase.Response.describe(),
+		}
+		if matchCase.BodySize != nil {
+			entry.MinBodyBytes, entry.MaxBodyBytes = matchCase.BodySize.Min, matchCase.BodySize.Max
+		}
+		d.Entries = append(d.Entries, entry)
+	}
+	return d
+}
+
+func (f *FallthroughResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "fallthrough"}
+	for _, resolver := range f.resolvers {
+		d.Strategies = append(d.Strategies, describeResolver(resolver))
+	}
+	return d
+}
+
+func (j *JSONRPCResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "jsonrpc"}
+	for _, name := range j.names {
+		method := j.methods[name]
+		d.Entries = append(d.Entries, EntryDescription{Method: name, Error: method.Error, Response: method.Response.describe()})
+	}
+	return d
+}
+
+func (s *ScheduledResponse) describe() StrategyDescription {
+	d := StrategyDescription{Type: "schedule", Fallback: s.fallback.describePtr(), Timezone: s.location.String()}
+	for _, window := range s.windows {
+		d.Entries = append(d.Entries, EntryDescription{
+			Window:   timeOfDay(window.Start) + "-" + timeOfDay(window.End),
+			Response: window.Response.describe(),
+		})
+	}
+	return d
+}
+
+// timeOfDay formats a duration since midnight as HH:MM.
+func timeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	ok, err := NewResponse(
+		WithResponseHeaders(map[string]string{"Content-Type": "application/json"}),
+		WithResponseHeaderTemplates(map[string][]string{"Location": {`/users/{{ .PathValue "id" }}`}}, []string{"id"}),
+		WithResponseBody([]byte(`{"ok":true}`)),
+		WithResponseDelay(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	unavailable := Response{statusCode: http.StatusServiceUnavailable}
+	reset := Response{connFault: ConnectionFaultReset}
+
+	t.Run("fault over weighted", func(t *testing.T) {
+		weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+			{Response: ok, Weight: 3},
+			{Response: unavailable, Weight: 1, MaxOccurrences: 2},
+		}, nil)
+		require.NoError(t, err)
+		faulty, err := NewFaultResponse(weighted, []Fault{{Probability: 0.1, Response: reset}}, nil)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/users/{id}", http.MethodPut, faulty)
+		require.NoError(t, err)
+
+		assert.Equal(t, EndpointDescription{
+			ID:      "PUT /users/{id}",
+			Path:    "/users/{id}",
+			Method:  http.MethodPut,
+			Enabled: true,
+			Strategy: StrategyDescription{
+				Type: "fault",
+				Entries: []EntryDescription{
+					{Probability: 0.1, Response: ResponseDescription{Fault: ConnectionFaultReset}},
+				},
+				Wrapped: &StrategyDescription{
+					Type: "weighted",
+					Entries: []EntryDescription{
+						{Weight: 3, Response: ResponseDescription{
+							Status:    http.StatusOK,
+							Headers:   map[string][]string{"Content-Type": {"application/json"}, "Location": {`/users/{{.PathValue "id"}}`}},
+							Body:      `{"ok":true}`,
+							BodyBytes: 11,
+							Delay:     "50ms",
+						}},
+						{Weight: 1, MaxOccurrences: 2, Response: ResponseDescription{Status: http.StatusServiceUnavailable}},
+					},
+				},
+			},
+		}, endpoint.Describe())
+	})
+
+	t.Run("sequence with end", func(t *testing.T) {
+		gone := Response{statusCode: http.StatusGone}
+		sequence, err := NewSequencedResponse(SequenceBehaviorEnd, []Response{unavailable}, &gone)
+		require.NoError(t, err)
+
+		assert.Equal(t, StrategyDescription{
+			Type:        "sequence",
+			Entries:     []EntryDescription{{Response: ResponseDescription{Status: http.StatusServiceUnavailable}}},
+			EndBehavior: SequenceBehaviorEnd,
+			End:         &ResponseDescription{Status: http.StatusGone},
+		}, describeResolver(sequence))
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		weighted, err := NewWeightedResponse([]WeightedResponseEntry{{Response: unavailable, Weight: 1}}, nil)
+		require.NoError(t, err)
+		sticky, err := NewStickyResponse(weighted, PerClientOptions{MaxClients: 10}, nil)
+		require.NoError(t, err)
+
+		d := describeResolver(sticky)
+		assert.Equal(t, "weighted", d.Type)
+		assert.True(t, d.Sticky)
+	})
+}
+
+func TestAdminDescribeEndpoint(t *testing.T) {
+	users, err := NewEndpoint("/users", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK, body: []byte("users")}))
+	require.NoError(t, err)
+	endpoints := []*Endpoint{users}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	RegisterAdminHandlers(mux, endpoints, "")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/__admin/endpoints/GET%20%2Fusers", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"id": "GET /users",
+		"path": "/users",
+		"method": "GET",
+		"enabled": true,
+		"strategy": {"type": "static", "entries": [{"response": {"status": 200, "body": "users", "bodyBytes": 5}}]}
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/__admin/endpoints/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,177 @@
+package rest
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drain simulates the server being taken out of rotation for a rolling deploy, without stopping it.
+// While drained, ReadinessPath fails and every request other than admin routes gets a 503. Once
+// drained for its grace period, those requests have their connection dropped instead, see
+// Refusing. Undraining restores it. Shutdown drains it for good as the process stops.
+type Drain struct {
+	grace time.Duration
+
+	mu       sync.Mutex
+	draining atomic.Bool
+	refusing atomic.Bool
+	timer    *time.Timer
+	// undrainTimer ends a drain started with a duration.
+	undrainTimer *time.Timer
+	shuttingDown atomic.Bool
+	// shutdownResp answers requests once shutting down, with nil meaning a plain 503. It's only
+	// written before shuttingDown is set.
+	shutdownResp *Response
+}
+
+// NewDrain builds a drain that drops connections once drained for grace.
+func NewDrain(grace time.Duration) *Drain {
+	return &Drain{grace: grace}
+}
+
+// Drained reports whether the server is drained.
+func (d *Drain) Drained() bool {
+	return d.draining.Load()
+}
+
+// Refusing reports whether requests outside admin routes have their connection dropped, which they
+// do once the server has been drained for the grace period.
+func (d *Drain) Refusing() bool {
+	return d.refusing.Load()
+}
+
+// Start drains the server, undraining it after duration if it's positive. Draining an already
+// drained server doesn't restart its grace period or change its duration.
+func (d *Drain) Start(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining.Load() {
+		return
+	}
+	d.draining.Store(true)
+	var timer *time.Timer
+	timer = time.AfterFunc(d.grace, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.timer != timer { // undrained as the grace period passed
+			return
+		}
+		d.refusing.Store(true)
+		slog.Info("drain grace period passed, dropping connections")
+	})
+	d.timer = timer
+	if duration > 0 {
+		d.undrainTimer = time.AfterFunc(duration, d.Stop)
+	}
+	slog.Info("draining", "grace", d.grace, "duration", duration)
+}
+
+// Stop undrains the server, accepting connections and serving requests again.
+func (d *Drain) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.draining.Load() {
+		return
+	}
+	d.timer.Stop()
+	d.timer = nil
+	if d.undrainTimer != nil {
+		d.undrainTimer.Stop()
+		d.undrainTimer = nil
+	}
+	d.refusing.Store(false)
+	d.draining.Store(false)
+	slog.Info("undrained")
+}
+
+// Shutdown drains the server for its shutdown, answering new requests with resp, or a plain 503
+// if it's nil, while requests already started complete. Unlike Start it can't be undone, covers
+// admin routes too, and leaves new connections to be accepted, so clients see the response rather
+// than connection errors until the listeners close.
+func (d *Drain) Shutdown(resp *Response) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.shuttingDown.Load() {
+		return
+	}
+	d.shutdownResp = resp
+	d.shuttingDown.Store(true)
+	slog.Info("shutting down, answering new requests with the shutdown response")
+}
+
+// Handler wraps next so requests get a 503 while drained, or have their connection dropped once
+// refusing, except for admin routes under basePath so the server can be undrained. It also answers
+// ReadinessPath, prefixed with basePath too, itself, with a 503 while drained and a 200 otherwise.
+// Once shutting down, every request gets the shutdown response.
+func (d *Drain) Handler(next http.Handler, basePath string) http.Handler {
+	adminPrefix := basePath + AdminPathPrefix
+	readinessPath := basePath + ReadinessPath
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.shuttingDown.Load() {
+			w.Header().Set("Connection", "close")
+			if d.shutdownResp == nil || r.URL.Path == readinessPath {
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			}
+			writePlainResponse(w, r, *d.shutdownResp)
+			return
+		}
+
+		drained := d.draining.Load()
+		if r.URL.Path == readinessPath && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			if drained {
+				http.Error(w, "draining", http.StatusServiceUnavailable)
+			} else {
+				http.Error(w, "ready", http.StatusOK)
+			}
+			return
+		}
+
+		if drained && !strings.HasPrefix(r.URL.Path, adminPrefix) {
+			if d.refusing.Load() {
+				// dropped without a response, like a refused connection, while new connections stay
+				// open to admin routes
+				panic(http.ErrAbortHandler)
+			}
+			// closing the connection moves keep-alive clients off the drained server
+			w.Header().Set("Connection", "close")
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type drainState struct {
+	Drained bool `json:"drained"`
+}
+
+// RegisterDrainHandlers registers routes under AdminPathPrefix, itself prefixed with basePath, for
+// draining and undraining the server. The drain route takes an optional duration query param,
+// after which the server undrains itself.
+func RegisterDrainHandlers(mux *http.ServeMux, drain *Drain, basePath string) {
+	prefix := basePath + AdminPathPrefix
+	mux.HandleFunc("POST "+prefix+"drain", func(w http.ResponseWriter, r *http.Request) {
+		var duration time.Duration
+		if raw := r.URL.Query().Get("duration"); raw != "" {
+			var err error
+			if duration, err = time.ParseDuration(raw); err != nil || duration <= 0 {
+				http.Error(w, "duration must be a positive duration", http.StatusBadRequest)
+				return
+			}
+		}
+		drain.Start(duration)
+		writeAdminJSON(w, http.StatusOK, drainState{Drained: true})
+	})
+	mux.HandleFunc("POST "+prefix+"undrain", func(w http.ResponseWriter, r *http.Request) {
+		drain.Stop()
+		writeAdminJSON(w, http.StatusOK, drainState{Drained: false})
+	})
+}
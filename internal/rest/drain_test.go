@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("routed"))
+	})
+	drain := NewDrain(50 * time.Millisecond)
+	mux := http.NewServeMux()
+	mux.Handle("/", next)
+	RegisterDrainHandlers(mux, drain, "/api")
+	handler := drain.Handler(mux, "/api")
+	readinessPath := "/api" + ReadinessPath
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, readinessPath).Code)
+	assert.Equal(t, "routed", serve(http.MethodGet, ReadinessPath).Body.String(), "readiness is under the base path")
+	assert.Equal(t, "routed", serve(http.MethodGet, "/users").Body.String())
+
+	w := serve(http.MethodPost, "/api/__admin/drain")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"drained":true}`, w.Body.String())
+	assert.True(t, drain.Drained())
+	assert.False(t, drain.Refusing(), "should accept connections during the grace period")
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodGet, readinessPath).Code)
+	w = serve(http.MethodGet, "/users")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "close", w.Header().Get("Connection"))
+	assert.Eventually(t, drain.Refusing, time.Second, 10*time.Millisecond)
+
+	w = serve(http.MethodPost, "/api/__admin/undrain")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"drained":false}`, w.Body.String())
+	assert.False(t, drain.Drained())
+	assert.False(t, drain.Refusing())
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, readinessPath).Code)
+	assert.Equal(t, "routed", serve(http.MethodGet, "/users").Body.String())
+}
+
+func TestDrainUndrainOverNewConnection(t *testing.T) {
+	drain := NewDrain(20 * time.Millisecond)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("routed"))
+	})
+	RegisterDrainHandlers(mux, drain, "")
+	server := httptest.NewServer(drain.Handler(mux, ""))
+	t.Cleanup(server.Close)
+	// every request dials a fresh connection
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	drain.Start(0)
+	require.Eventually(t, drain.Refusing, time.Second, 10*time.Millisecond)
+
+	_, err := client.Get(server.URL + "/users")
+	assert.Error(t, err, "the connection is dropped without a response")
+
+	resp, err := client.Post(server.URL+"/__admin/undrain", "", nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, drain.Drained())
+
+	resp, err = client.Get(server.URL + "/users")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestDrainUndrainedBeforeGrace(t *testing.T) {
+	drain := NewDrain(20 * time.Millisecond)
+	drain.Start(0)
+	drain.Stop()
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, drain.Refusing())
+}
+
+func TestDrainDuration(t *testing.T) {
+	drain := NewDrain(time.Minute)
+	mux := http.NewServeMux()
+	RegisterDrainHandlers(mux, drain, "")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/__admin/drain?duration=x", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, drain.Drained())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/__admin/drain?duration=30ms", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, drain.Drained())
+	assert.Eventually(t, func() bool { return !drain.Drained() }, time.Second, 10*time.Millisecond)
+}
+
+func TestDrainShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte("routed"))
+	})
+	drain := NewDrain(time.Minute)
+	mux := http.NewServeMux()
+	mux.Handle("/", next)
+	RegisterDrainHandlers(mux, drain, "")
+	handler := drain.Handler(mux, "")
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() { inFlight <- serve(http.MethodGet, "/slow") }()
+	<-started
+
+	drain.Shutdown(&Response{statusCode: http.StatusServiceUnavailable, headers: map[string]string{"Retry-After": "5"}, body: []byte("bye")})
+	w := serve(http.MethodGet, "/users")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "bye", w.Body.String())
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.Equal(t, "close", w.Header().Get("Connection"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodGet, ReadinessPath).Code)
+
+	// undraining doesn't cancel a shutdown
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "/__admin/undrain").Code)
+
+	close(release)
+	w = <-inFlight
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "routed", w.Body.String())
+}
+
+func TestDrainShutdownDefaultResponse(t *testing.T) {
+	drain := NewDrain(time.Minute)
+	handler := drain.Handler(http.NotFoundHandler(), "")
+	drain.Shutdown(nil)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "shutting down\n", w.Body.String())
+}
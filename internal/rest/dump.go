@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"unicode/utf8"
+
+	"github.com/caproven/mock-server/internal/accesslog"
+)
+
+// DumpHandler logs the full request and response of every request served by next at debug level,
+// for troubleshooting. At most maxBodyBytes of each body are logged and binary bodies are
+// summarized rather than printed. Requests aren't captured at all while debug logs are disabled.
+func DumpHandler(next http.Handler, maxBodyBytes int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqDump, err := httputil.DumpRequest(r, false)
+		if err != nil {
+			slog.Error("failed to dump request", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		reqBody := peekBody(r, maxBodyBytes)
+
+		rec := newDumpRecorder(w, maxBodyBytes)
+		next.ServeHTTP(rec, r)
+
+		slog.DebugContext(r.Context(), "dumped request",
+			slog.String("request", string(reqDump)+dumpBody(reqBody, r.ContentLength, maxBodyBytes)),
+			slog.String("response", rec.dump(r.Proto)),
+		)
+	})
+}
+
+// peekBody reads up to maxBytes of r's body, replacing the body so handlers still read all of it.
+// More than maxBytes are read when the body is longer, so the dump can tell it was truncated.
+func peekBody(r *http.Request, maxBytes int) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+	rest := io.Reader(r.Body)
+	if err != nil {
+		rest = errReader{err}
+	}
+	r.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(peeked), rest), Closer: r.Body}
+	return peeked
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader fails every read with err, so a body's read error surfaces after its peeked bytes.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// dumpBody formats body for a dump, where body holds more than maxBytes if the full body was
+// longer. total is the full body's length, or -1 if unknown.
+func dumpBody(body []byte, total int64, maxBytes int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = trimPartialRune(body[:maxBytes])
+	} else {
+		total = int64(len(body))
+	}
+
+	if bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body) {
+		if total < 0 {
+			return fmt.Sprintf("[over %d bytes of binary content]", maxBytes)
+		}
+		return fmt.Sprintf("[%d bytes of binary content]", total)
+	}
+	if !truncated {
+		return string(body)
+	}
+	if total < 0 {
+		return string(body) + "\n[truncated]"
+	}
+	return string(body) + fmt.Sprintf("\n[truncated, %d bytes total]", total)
+}
+
+// trimPartialRune drops a trailing incomplete UTF-8 sequence, which truncating text can leave.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// dumpRecorder captures the status, headers, and start of the body written through it.
+type dumpRecorder struct {
+	*accesslog.Recorder
+	maxBodyBytes int
+
+	header http.Header
+	body   []byte
+}
+
+func newDumpRecorder(w http.ResponseWriter, maxBodyBytes int) *dumpRecorder {
+	return &dumpRecorder{Recorder: &accesslog.Recorder{ResponseWriter: w}, maxBodyBytes: maxBodyBytes}
+}
+
+func (d *dumpRecorder) WriteHeader(statusCode int) {
+	if d.header == nil && statusCode >= http.StatusOK {
+		d.header = d.Header().Clone()
+	}
+	d.Recorder.WriteHeader(statusCode)
+}
+
+func (d *dumpRecorder) Write(p []byte) (int, error) {
+	if d.header == nil {
+		d.WriteHeader(http.StatusOK)
+	}
+	n, err := d.Recorder.Write(p)
+	if room := d.maxBodyBytes + 1 - len(d.body); room > 0 {
+		d.body = append(d.body, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+func (d *dumpRecorder) dump(proto string) string {
+	return string(d.head(proto)) + dumpBody(d.body, d.Bytes(), d.maxBodyBytes)
+}
+
+// head formats the response's status line and headers.
+func (d *dumpRecorder) head(proto string) []byte {
+	if d.header == nil {
+		// nothing was written, so the server sends an empty 200 once the handler returns
+		d.header = d.Header()
+	}
+	status := cmp.Or(d.Status(), http.StatusOK)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %d %s\r\n", proto, status, http.StatusText(status))
+	_ = d.header.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpHandler(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+
+	dump := func(t *testing.T, level slog.Level, body []byte) (*httptest.ResponseRecorder, map[string]any) {
+		t.Helper()
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: level})))
+
+		req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+		req.Header.Set("X-Test", "yes")
+		w := httptest.NewRecorder()
+		DumpHandler(echo, 8).ServeHTTP(w, req)
+
+		if logs.Len() == 0 {
+			return w, nil
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		return w, entry
+	}
+
+	t.Run("text", func(t *testing.T) {
+		w, entry := dump(t, slog.LevelDebug, []byte("hello"))
+		assert.Equal(t, "hello", w.Body.String(), "handler should still read the full body")
+
+		require.NotNil(t, entry)
+		assert.Contains(t, entry["request"], "POST /users HTTP/1.1")
+		assert.Contains(t, entry["request"], "X-Test: yes")
+		assert.True(t, strings.HasSuffix(entry["request"].(string), "\r\n\r\nhello"))
+		assert.Contains(t, entry["response"], "HTTP/1.1 201 Created")
+		assert.Contains(t, entry["response"], "Content-Type: text/plain")
+		assert.True(t, strings.HasSuffix(entry["response"].(string), "\r\n\r\nhello"))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		w, entry := dump(t, slog.LevelDebug, []byte("hello world"))
+		assert.Equal(t, "hello world", w.Body.String())
+
+		require.NotNil(t, entry)
+		assert.Contains(t, entry["request"], "hello wo\n[truncated, 11 bytes total]")
+		assert.Contains(t, entry["response"], "hello wo\n[truncated, 11 bytes total]")
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		w, entry := dump(t, slog.LevelDebug, []byte{0x00, 0xff, 0x10})
+		assert.Equal(t, []byte{0x00, 0xff, 0x10}, w.Body.Bytes())
+
+		require.NotNil(t, entry)
+		assert.Contains(t, entry["request"], "[3 bytes of binary content]")
+		assert.Contains(t, entry["response"], "[3 bytes of binary content]")
+	})
+
+	t.Run("debug disabled", func(t *testing.T) {
+		w, entry := dump(t, slog.LevelInfo, []byte("hello"))
+		assert.Equal(t, "hello", w.Body.String())
+		assert.Nil(t, entry)
+	})
+}
+
+func TestDumpBody(t *testing.T) {
+	tests := map[string]struct {
+		body  string
+		total int64
+		want  string
+	}{
+		"empty":                      {"", 0, ""},
+		"fits":                       {"abc", -1, "abc"},
+		"truncated unknown length":   {"abcdef", -1, "abcd\n[truncated]"},
+		"truncated known length":     {"abcdef", 100, "abcd\n[truncated, 100 bytes total]"},
+		"binary unknown length":      {"ab\x00de", -1, "[over 4 bytes of binary content]"},
+		"partial rune is not binary": {"abcé", 5, "abc\n[truncated, 5 bytes total]"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dumpBody([]byte(tt.body), tt.total, 4))
+		})
+	}
+}
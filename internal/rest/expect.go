@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExpectContinue controls how an endpoint answers requests sent with Expect: 100-continue, which
+// wait for a 100 Continue interim response before sending their body. By default Go sends it once
+// the body is first read.
+type ExpectContinue struct {
+	// Delay waits before sending 100 Continue.
+	Delay time.Duration
+	// Suppress never sends 100 Continue. The request is handled without reading its body, so
+	// clients that don't give up waiting and send it anyway have it discarded.
+	Suppress bool
+	// RejectStatus, if set, answers with this final status instead of 100 Continue, without
+	// reading the body, such as a 417 or 413.
+	RejectStatus int
+}
+
+// WithExpectContinue controls how the endpoint answers requests sent with Expect: 100-continue.
+func WithExpectContinue(expect ExpectContinue) EndpointOption {
+	return func(e *Endpoint) error {
+		if expect.Delay < 0 {
+			return errors.New("expect continue delay cannot be negative")
+		}
+		if expect.RejectStatus != 0 && (expect.RejectStatus < 400 || expect.RejectStatus > 599) {
+			return errors.New("expect continue reject status must be a 4xx or 5xx status")
+		}
+		if expect.RejectStatus != 0 && (expect.Suppress || expect.Delay != 0) {
+			return errors.New("expect continue reject status cannot be combined with suppress or delay")
+		}
+		if expect.Suppress && expect.Delay != 0 {
+			return errors.New("expect continue cannot both suppress and delay")
+		}
+		e.expectContinue = &expect
+		return nil
+	}
+}
+
+// handler wraps next, answering requests expecting 100 Continue before next sees them.
+func (c *ExpectContinue) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Expect"), "100-continue") || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case c.RejectStatus != 0:
+			// the client may send the body regardless, and it's never read
+			w.Header().Set("Connection", "close")
+			http.Error(w, http.StatusText(c.RejectStatus), c.RejectStatus)
+			return
+		case c.Suppress:
+			r.Body = http.NoBody
+			r.ContentLength = 0
+			w.Header().Set("Connection", "close")
+		default:
+			if c.Delay > 0 {
+				timer := time.NewTimer(c.Delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+			w.WriteHeader(http.StatusContinue)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectContinue(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		resp, err := NewResponse()
+		require.NoError(t, err)
+
+		for _, expect := range []ExpectContinue{
+			{Delay: -time.Second},
+			{RejectStatus: http.StatusOK},
+			{RejectStatus: http.StatusExpectationFailed, Suppress: true},
+			{RejectStatus: http.StatusExpectationFailed, Delay: time.Second},
+			{Suppress: true, Delay: time.Second},
+		} {
+			_, err = NewEndpoint("/upload", http.MethodPost, StaticResponse(resp), WithExpectContinue(expect))
+			assert.Error(t, err, expect)
+		}
+	})
+
+	const body = `{"name": "report.pdf"}`
+	send := func(t *testing.T, expect ExpectContinue) (*bufio.Reader, net.Conn) {
+		t.Helper()
+		resp, err := NewResponse(WithResponseTemplate(`uploaded {{ .JSON.name }}`, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/upload", http.MethodPost, StaticResponse(resp), WithExpectContinue(expect))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		_, err = io.WriteString(conn, "POST /upload HTTP/1.1\r\nHost: test\r\nContent-Length: 22\r\nExpect: 100-continue\r\n\r\n")
+		require.NoError(t, err)
+		return bufio.NewReader(conn), conn
+	}
+
+	t.Run("delay", func(t *testing.T) {
+		start := time.Now()
+		reader, conn := send(t, ExpectContinue{Delay: 100 * time.Millisecond})
+
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1 100 Continue\r\n", line)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "\r\n", line, "the interim response has no headers")
+
+		_, err = io.WriteString(conn, body)
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "uploaded report.pdf", string(got))
+	})
+
+	t.Run("suppress", func(t *testing.T) {
+		reader, _ := send(t, ExpectContinue{Suppress: true})
+
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "no 100 Continue comes first")
+		assert.Equal(t, "uploaded ", string(got), "the body is never read")
+		assert.True(t, resp.Close)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		reader, _ := send(t, ExpectContinue{RejectStatus: http.StatusExpectationFailed})
+
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusExpectationFailed, resp.StatusCode)
+		assert.True(t, resp.Close)
+	})
+
+	t.Run("no expectation", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseBody([]byte("uploaded")))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/upload", http.MethodPost, StaticResponse(resp),
+			WithExpectContinue(ExpectContinue{RejectStatus: http.StatusExpectationFailed}))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Fallbacks are responses replacing the server's defaults for requests no endpoint answers.
+type Fallbacks struct {
+	// NotFound replaces the default 404 for requests whose path matches no endpoint.
+	NotFound *Response
+	// MethodNotAllowed replaces the default 405 for requests whose path matches an endpoint but whose
+	// method doesn't. The Allow header is set from the methods registered for the path.
+	MethodNotAllowed *Response
+	// TooLarge replaces the default 413 for requests whose body exceeds the request size limit. It's
+	// used by LimitRequestBytes.
+	TooLarge *Response
+}
+
+// FallbackHandler serves requests with mux, substituting the configured fallbacks for the mux's
+// default responses when no endpoint matches.
+func FallbackHandler(mux *http.ServeMux, fallbacks Fallbacks) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if m, ok := h.(methodNotAllowed); ok && fallbacks.MethodNotAllowed != nil {
+			w.Header().Set("Allow", m.allow)
+			writePlainResponse(w, r, *fallbacks.MethodNotAllowed)
+			return
+		}
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Let the mux decide why nothing matched without writing its response to the client.
+		rec := &discardRecorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusNotFound && fallbacks.NotFound != nil {
+			writePlainResponse(w, r, *fallbacks.NotFound)
+			return
+		}
+		if rec.status == http.StatusMethodNotAllowed && fallbacks.MethodNotAllowed != nil {
+			// the mux computes Allow from every pattern matching the path
+			if allow := rec.header.Get("Allow"); allow != "" {
+				w.Header().Set("Allow", allow)
+			}
+			writePlainResponse(w, r, *fallbacks.MethodNotAllowed)
+			return
+		}
+
+		// no fallback configured, replay the mux's response
+		h.ServeHTTP(w, r)
+	})
+}
+
+func writePlainResponse(w http.ResponseWriter, r *http.Request, resp Response) {
+	body, ok := resp.currentBody()
+	if !ok {
+		writeMissingFile(w, resp)
+		return
+	}
+	resp.body = body
+	if !resp.loadDirBody(w) {
+		return
+	}
+	if err := resp.setHeaders(w.Header(), r, false); err != nil {
+		slog.Error("failed to render header template", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(resp.statusCode)
+	if _, err := w.Write(resp.body); err != nil {
+		slog.Warn("failed to write response", "err", err)
+	}
+}
+
+// discardRecorder records the status written to it and discards everything else.
+type discardRecorder struct {
+	header http.Header
+	status int
+}
+
+func (d *discardRecorder) Header() http.Header {
+	return d.header
+}
+
+func (d *discardRecorder) Write(p []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	return len(p), nil
+}
+
+func (d *discardRecorder) WriteHeader(statusCode int) {
+	if d.status == 0 {
+		d.status = statusCode
+	}
+}
+
+// WithoutMethodFallthrough answers requests for the endpoint's path whose method no endpoint for
+// the path serves with a 405, rather than letting them fall through to a broader endpoint for all
+// methods, such as /users/{path...} or a regex path. It applies to every endpoint for the path,
+// which can't include one for all methods, and isn't supported for regex paths.
+func WithoutMethodFallthrough() EndpointOption {
+	return func(e *Endpoint) error {
+		e.noMethodFallthrough = true
+		return nil
+	}
+}
+
+// methodNotAllowed answers requests for a path without method fallthrough whose method none of
+// its endpoints serve, like the mux's own 405. FallbackHandler replaces it with the configured
+// fallback.
+type methodNotAllowed struct {
+	allow string
+}
+
+func (m methodNotAllowed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", m.allow)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// registerMethodNotAllowed registers a methodNotAllowed route for every path without method
+// fallthrough, taking precedence over broader patterns for all methods. Endpoints for the path's
+// methods are more specific, so they still take precedence over it.
+func registerMethodNotAllowed(mux httpMux, endpoints []*Endpoint) {
+	methods := make(map[string][]string)
+	var paths []string
+	for _, endpoint := range endpoints {
+		if endpoint.pathRegex != nil {
+			continue
+		}
+		if _, ok := methods[endpoint.Path]; !ok {
+			methods[endpoint.Path] = nil
+			paths = append(paths, endpoint.Path)
+		}
+		methods[endpoint.Path] = append(methods[endpoint.Path], endpoint.Method)
+		if endpoint.Method == http.MethodGet {
+			methods[endpoint.Path] = append(methods[endpoint.Path], http.MethodHead)
+		}
+	}
+
+	strict := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		if endpoint.noMethodFallthrough && endpoint.pathRegex == nil {
+			strict[endpoint.Path] = true
+		}
+	}
+	for _, path := range paths {
+		allow := methods[path]
+		// an endpoint for all methods on the path leaves nothing to fall through
+		if !strict[path] || slices.Contains(allow, "") {
+			continue
+		}
+		slices.Sort(allow)
+		mux.Handle(path, methodNotAllowed{allow: strings.Join(slices.Compact(allow), ", ")})
+	}
+}
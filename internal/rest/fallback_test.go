@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackHandler(t *testing.T) {
+	ok := Response{statusCode: http.StatusOK, body: []byte("user")}
+	notFound := Response{
+		statusCode: http.StatusNotFound,
+		headers:    map[string]string{"Content-Type": "application/json"},
+		body:       []byte(`{"error":"not found"}`),
+	}
+
+	methodNotAllowed := Response{
+		statusCode: http.StatusMethodNotAllowed,
+		body:       []byte(`{"error":"method not allowed"}`),
+	}
+
+	getUser, err := NewEndpoint("/users/{id}", http.MethodGet, StaticResponse(ok))
+	require.NoError(t, err)
+	deleteUser, err := NewEndpoint("/users/{id}", http.MethodDelete, StaticResponse(ok))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{getUser, deleteUser})
+
+	t.Run("registered route wins", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		FallbackHandler(mux, Fallbacks{NotFound: &notFound}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user", w.Body.String())
+	})
+
+	t.Run("configured not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		FallbackHandler(mux, Fallbacks{NotFound: &notFound}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `{"error":"not found"}`, w.Body.String())
+	})
+
+	t.Run("default not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		FallbackHandler(mux, Fallbacks{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "404 page not found\n", w.Body.String())
+	})
+
+	t.Run("configured method not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		fallbacks := Fallbacks{NotFound: &notFound, MethodNotAllowed: &methodNotAllowed}
+		FallbackHandler(mux, fallbacks).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "DELETE, GET, HEAD", w.Header().Get("Allow"))
+		assert.Equal(t, `{"error":"method not allowed"}`, w.Body.String())
+	})
+
+	t.Run("default method not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		FallbackHandler(mux, Fallbacks{NotFound: &notFound}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "DELETE, GET, HEAD", w.Header().Get("Allow"))
+	})
+}
+
+func TestWithoutMethodFallthrough(t *testing.T) {
+	ok := func(body string) ResponseResolver {
+		return StaticResponse(Response{statusCode: http.StatusOK, body: []byte(body)})
+	}
+	methodNotAllowed := Response{statusCode: http.StatusMethodNotAllowed, body: []byte(`{"error":"method not allowed"}`)}
+
+	catchAll, err := NewEndpoint("/files/{path...}", "", ok("catch-all"))
+	require.NoError(t, err)
+	getReadme, err := NewEndpoint("/files/readme.txt", http.MethodGet, ok("readme"), WithoutMethodFallthrough())
+	require.NoError(t, err)
+	putReadme, err := NewEndpoint("/files/readme.txt", http.MethodPut, ok("updated"))
+	require.NoError(t, err)
+	getLicense, err := NewEndpoint("/files/license.txt", http.MethodGet, ok("license"))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{catchAll, getReadme, putReadme, getLicense})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		fallbacks Fallbacks
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{name: "endpoint for the method", method: http.MethodGet, path: "/files/readme.txt", wantCode: http.StatusOK, wantBody: "readme"},
+		{name: "other endpoint for the path", method: http.MethodPut, path: "/files/readme.txt", wantCode: http.StatusOK, wantBody: "updated"},
+		{name: "head for get", method: http.MethodHead, path: "/files/readme.txt", wantCode: http.StatusOK, wantBody: "readme"},
+		{
+			name: "unserved method gets a 405", method: http.MethodPost, path: "/files/readme.txt",
+			wantCode: http.StatusMethodNotAllowed, wantBody: "Method Not Allowed\n", wantAllow: "GET, HEAD, PUT",
+		},
+		{
+			name: "configured method not allowed", method: http.MethodDelete, path: "/files/readme.txt",
+			fallbacks: Fallbacks{MethodNotAllowed: &methodNotAllowed},
+			wantCode:  http.StatusMethodNotAllowed, wantBody: `{"error":"method not allowed"}`, wantAllow: "GET, HEAD, PUT",
+		},
+		{name: "other paths fall through", method: http.MethodPost, path: "/files/license.txt", wantCode: http.StatusOK, wantBody: "catch-all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			FallbackHandler(mux, tt.fallbacks).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantAllow, w.Header().Get("Allow"))
+		})
+	}
+
+	t.Run("regex routes don't catch the path", func(t *testing.T) {
+		files, err := NewEndpoint("/files/.*", "", ok("regex"), WithPathRegex())
+		require.NoError(t, err)
+		getReadme, err := NewEndpoint("/files/readme.txt", http.MethodGet, ok("readme"), WithoutMethodFallthrough())
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		endpoints := []*Endpoint{files, getReadme}
+		RegisterHandlers(mux, endpoints)
+
+		w := httptest.NewRecorder()
+		RegexHandler(FallbackHandler(mux, Fallbacks{}), mux, endpoints).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/readme.txt", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewEndpoint("/files/{path...}", "", ok("catch-all"), WithoutMethodFallthrough())
+		assert.ErrorContains(t, err, "method fallthrough can only be disabled")
+		_, err = NewEndpoint("/files/.*", http.MethodGet, ok("regex"), WithPathRegex(), WithoutMethodFallthrough())
+		assert.ErrorContains(t, err, "method fallthrough can only be disabled")
+	})
+}
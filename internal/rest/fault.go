@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+type ConnectionFault string
+
+const (
+	// ConnectionFaultReset drops the connection without a response, simulating a peer reset.
+	ConnectionFaultReset ConnectionFault = "connectionReset"
+	// ConnectionFaultTruncate writes only part of the body before closing the connection.
+	ConnectionFaultTruncate ConnectionFault = "truncate"
+)
+
+// probabilityScale is the resolution probabilities are rolled at against a numberGenerator.
+const probabilityScale = 1_000_000
+
+type Fault struct {
+	// Probability is the chance in (0, 1] of the fault triggering on a given request.
+	Probability float64
+	Response    Response
+}
+
+// FaultResponse overlays faults on top of another response strategy. Each request rolls against
+// every fault in order, returning the first that triggers. The wrapped strategy is only consulted
+// when no fault triggers, so its state is untouched by faulted requests. The exception is body
+// truncation, which is applied to the wrapped strategy's response.
+type FaultResponse struct {
+	resolver     ResponseResolver
+	faults       []Fault
+	numGenerator numberGenerator
+}
+
+// NewFaultResponse wraps resolver with the given faults.
+// If numGenerator is nil, a random source is used.
+func NewFaultResponse(resolver ResponseResolver, faults []Fault, numGenerator numberGenerator) (*FaultResponse, error) {
+	if resolver == nil {
+		return nil, errors.New("no response strategy to wrap")
+	}
+	if len(faults) == 0 {
+		return nil, errors.New("no faults")
+	}
+	for _, fault := range faults {
+		if fault.Probability <= 0 || fault.Probability > 1 {
+			return nil, fmt.Errorf("fault probability must be in (0, 1]: %v", fault.Probability)
+		}
+	}
+
+	if numGenerator == nil {
+		numGenerator = rng{}
+	}
+
+	return &FaultResponse{
+		resolver:     resolver,
+		faults:       faults,
+		numGenerator: numGenerator,
+	}, nil
+}
+
+func (f *FaultResponse) NextResponse(r *http.Request) Response {
+	resp, _ := f.nextChosenResponse(r)
+	return resp
+}
+
+func (f *FaultResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	for i, fault := range f.faults {
+		if f.numGenerator.N(probabilityScale) < int(fault.Probability*probabilityScale) {
+			choice := responseChoice{strategy: "fault", index: i}
+			if fault.Response.connFault == ConnectionFaultTruncate {
+				resp := f.resolver.NextResponse(r)
+				resp.connFault = ConnectionFaultTruncate
+				resp.truncateBytes = fault.Response.truncateBytes
+				return resp, choice
+			}
+			return fault.Response, choice
+		}
+	}
+	return nextChosenResponse(f.resolver, r)
+}
+
+// responseAt picks from the wrapped strategy, skipping the faults.
+func (f *FaultResponse) responseAt(r *http.Request, index int) (Response, responseChoice, bool) {
+	return responseAt(f.resolver, r, index)
+}
+
+// closeConnection hijacks the connection behind w, flushing anything already written, and closes it.
+func closeConnection(w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return fmt.Errorf("flush response: %w", err)
+	}
+	conn, _, err := rc.Hijack()
+	if err != nil {
+		return fmt.Errorf("hijack connection: %w", err)
+	}
+	return conn.Close()
+}
+
+// abortStream resets the request's stream. HTTP/2 multiplexes requests over a connection, so it
+// can't be hijacked and closed like an HTTP/1 connection. The server recovers the panic quietly.
+func abortStream() {
+	panic(http.ErrAbortHandler)
+}
+
+// resetConnection hijacks the connection behind w and closes it. For TCP connections, SO_LINGER
+// is zeroed first so the close sends an RST rather than a graceful FIN.
+func resetConnection(w http.ResponseWriter) error {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fmt.Errorf("hijack connection: %w", err)
+	}
+
+	netConn := conn
+	if tlsConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		netConn = tlsConn.NetConn()
+	}
+	if tcpConn, ok := netConn.(*net.TCPConn); ok {
+		if err := tcpConn.SetLinger(0); err != nil {
+			return errors.Join(fmt.Errorf("set linger: %w", err), conn.Close())
+		}
+	}
+
+	return conn.Close()
+}
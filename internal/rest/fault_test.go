@@ -0,0 +1,303 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultResponse(t *testing.T) {
+	base := Response{statusCode: http.StatusOK}
+	fault := Fault{
+		Probability: 0.1,
+		Response:    Response{statusCode: http.StatusInternalServerError},
+	}
+
+	t.Run("nil resolver", func(t *testing.T) {
+		strategy, err := NewFaultResponse(nil, []Fault{fault}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+	})
+
+	t.Run("no faults", func(t *testing.T) {
+		strategy, err := NewFaultResponse(StaticResponse(base), nil, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+	})
+
+	t.Run("invalid probability", func(t *testing.T) {
+		for _, probability := range []float64{-0.5, 0, 1.5} {
+			strategy, err := NewFaultResponse(StaticResponse(base), []Fault{{Probability: probability}}, nil)
+			assert.Error(t, err)
+			assert.Nil(t, strategy)
+		}
+	})
+
+	t.Run("triggers below probability", func(t *testing.T) {
+		numberGen := &mockNumGenerator{}
+		strategy, err := NewFaultResponse(StaticResponse(base), []Fault{fault}, numberGen)
+		require.NoError(t, err)
+
+		numberGen.val = 0
+		assert.Equal(t, fault.Response, strategy.NextResponse(nil))
+		numberGen.val = probabilityScale/10 - 1
+		assert.Equal(t, fault.Response, strategy.NextResponse(nil))
+		numberGen.val = probabilityScale / 10
+		assert.Equal(t, base, strategy.NextResponse(nil))
+	})
+
+	t.Run("wrapped strategy untouched by faults", func(t *testing.T) {
+		first := Response{body: []byte("first")}
+		second := Response{body: []byte("second")}
+		sequence, err := NewSequencedResponse(SequenceBehaviorRepeatLast, []Response{first, second}, nil)
+		require.NoError(t, err)
+
+		numberGen := &mockNumGenerator{}
+		strategy, err := NewFaultResponse(sequence, []Fault{fault}, numberGen)
+		require.NoError(t, err)
+
+		assert.Equal(t, fault.Response, strategy.NextResponse(nil))
+		numberGen.val = probabilityScale - 1
+		assert.Equal(t, first, strategy.NextResponse(nil))
+		assert.Equal(t, second, strategy.NextResponse(nil))
+	})
+}
+
+func TestConnectionReset(t *testing.T) {
+	resp, err := NewResponse(WithConnectionFault(ConnectionFaultReset))
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/reset", http.MethodGet, StaticResponse(resp))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+
+	t.Run("connection dropped", func(t *testing.T) {
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		got, err := server.Client().Get(server.URL + "/reset")
+		if got != nil {
+			_ = got.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("stream reset over http2", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(mux)
+		server.Config.Protocols = new(http.Protocols)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+		server.Start()
+		t.Cleanup(server.Close)
+
+		client := server.Client()
+		transport := client.Transport.(*http.Transport).Clone()
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+		client.Transport = transport
+
+		got, err := client.Get(server.URL + "/reset")
+		if got != nil {
+			_ = got.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("falls back to 502 without hijacking", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reset", nil))
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
+
+func TestWeightedConnectionFault(t *testing.T) {
+	ok, err := NewResponse(WithResponseBody([]byte("ok")))
+	require.NoError(t, err)
+	reset, err := NewResponse(WithConnectionFault(ConnectionFaultReset))
+	require.NoError(t, err)
+	truncated, err := NewResponse(WithResponseBody([]byte("partial body")), WithTruncatedBody(7))
+	require.NoError(t, err)
+
+	numberGen := &syncNumGenerator{}
+	weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+		{Response: ok, Weight: 8},
+		{Response: reset, Weight: 1},
+		{Response: truncated, Weight: 1},
+	}, numberGen)
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/flaky", http.MethodGet, weighted)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	get := func() (string, error) {
+		resp, err := server.Client().Get(server.URL + "/flaky")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	numberGen.val.Store(0)
+	body, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", body)
+
+	numberGen.val.Store(8)
+	_, err = get()
+	assert.Error(t, err)
+
+	numberGen.val.Store(9)
+	body, err = get()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "partial", body)
+}
+
+func TestUnknownConnectionFault(t *testing.T) {
+	resp, err := NewResponse(WithConnectionFault("explode"))
+	assert.Error(t, err)
+	assert.Zero(t, resp)
+}
+
+func TestTruncatedBody(t *testing.T) {
+	t.Run("negative bytes", func(t *testing.T) {
+		resp, err := NewResponse(WithTruncatedBody(-1))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+
+	t.Run("body cut short", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseBody([]byte(`{"id":1,"name":"widget"}`)),
+			WithTruncatedBody(7),
+		)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/truncate", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		got, err := server.Client().Get(server.URL + "/truncate")
+		require.NoError(t, err)
+		defer got.Body.Close()
+
+		body, err := io.ReadAll(got.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, `{"id":1`, string(body))
+	})
+
+	t.Run("overlay truncates wrapped response", func(t *testing.T) {
+		base := Response{statusCode: http.StatusOK, body: []byte("full body")}
+		truncate, err := NewResponse(WithTruncatedBody(4))
+		require.NoError(t, err)
+
+		strategy, err := NewFaultResponse(StaticResponse(base), []Fault{{Probability: 1, Response: truncate}}, nil)
+		require.NoError(t, err)
+
+		got := strategy.NextResponse(nil)
+		assert.Equal(t, base.body, got.body)
+		assert.Equal(t, ConnectionFaultTruncate, got.connFault)
+		assert.Equal(t, 4, got.truncateBytes)
+	})
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	t.Run("negative bytes", func(t *testing.T) {
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(Response{}), WithMaxResponseBytes(-1, false))
+		assert.Error(t, err)
+		assert.Nil(t, endpoint)
+	})
+
+	full := []byte(`{"id":1,"name":"widget"}`)
+	serve := func(t *testing.T, resolver ResponseResolver, fullLength bool) *http.Response {
+		t.Helper()
+		endpoint, err := NewEndpoint("/limited", http.MethodGet, resolver, WithMaxResponseBytes(7, fullLength))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, WithAutoContentLength())
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		got, err := server.Client().Get(server.URL + "/limited")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = got.Body.Close() })
+		return got
+	}
+
+	t.Run("truncated body", func(t *testing.T) {
+		got := serve(t, StaticResponse(Response{statusCode: http.StatusOK, body: full}), false)
+		assert.Equal(t, int64(7), got.ContentLength)
+
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1`, string(body))
+	})
+
+	t.Run("truncated before compressing", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseBody(full), WithCompression())
+		require.NoError(t, err)
+		// the client asks for gzip and decompresses the body itself
+		got := serve(t, StaticResponse(resp), false)
+		assert.True(t, got.Uncompressed)
+
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1`, string(body))
+	})
+
+	t.Run("generated body", func(t *testing.T) {
+		resp, err := NewResponse(WithGeneratedBody(1024, 42))
+		require.NoError(t, err)
+
+		got := serve(t, StaticResponse(resp), false)
+		assert.Equal(t, int64(7), got.ContentLength)
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Len(t, body, 7)
+
+		got = serve(t, StaticResponse(resp), true)
+		assert.Equal(t, int64(1024), got.ContentLength)
+		body, err = io.ReadAll(got.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Len(t, body, 7)
+	})
+
+	t.Run("short body", func(t *testing.T) {
+		got := serve(t, StaticResponse(Response{statusCode: http.StatusOK, body: []byte("ok")}), true)
+
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+	})
+
+	t.Run("full content length", func(t *testing.T) {
+		got := serve(t, StaticResponse(Response{statusCode: http.StatusOK, body: full}), true)
+		assert.Equal(t, int64(len(full)), got.ContentLength)
+
+		body, err := io.ReadAll(got.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, `{"id":1`, string(body))
+	})
+
+	t.Run("shorter truncate fault wins", func(t *testing.T) {
+		resp := Response{statusCode: http.StatusOK, body: full, connFault: ConnectionFaultTruncate, truncateBytes: 3}
+		got := serve(t, StaticResponse(resp), true)
+
+		body, err := io.ReadAll(got.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, `{"i`, string(body))
+	})
+}
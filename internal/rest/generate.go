@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+)
+
+// MaxGeneratedBodyBytes caps the size of generated bodies.
+const MaxGeneratedBodyBytes = 1 << 30
+
+type generatedBody struct {
+	size int64
+	seed uint64
+}
+
+// WithGeneratedBody makes the body size bytes of pseudo-random data derived from seed. The same
+// seed always produces the same bytes. The body is streamed rather than held in memory.
+func WithGeneratedBody(size int64, seed uint64) ResponseOption {
+	return func(r *Response) error {
+		if size <= 0 || size > MaxGeneratedBodyBytes {
+			return fmt.Errorf("generated body size must be between 1 and %d bytes: %d", MaxGeneratedBodyBytes, size)
+		}
+		r.generated = &generatedBody{size: size, seed: seed}
+		return nil
+	}
+}
+
+// reader returns a fresh reader over the generated bytes.
+func (g *generatedBody) reader() io.Reader {
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], g.seed)
+	return io.LimitReader(rand.NewChaCha8(seed), g.size)
+}
+
+// writeGeneratedBody streams the generated body, cut to limit's size if it's larger. It reports
+// whether the body was cut short of the Content-Length sent, in which case the caller should end
+// the connection.
+func writeGeneratedBody(w http.ResponseWriter, resp Response, limit *responseLimit) bool {
+	size, sent := resp.generated.size, resp.generated.size
+	if limit != nil && int64(limit.maxBytes) < size {
+		sent = int64(limit.maxBytes)
+		if !limit.fullLength {
+			size = sent
+		}
+	}
+	if resp.chunkSize == 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(resp.statusCode)
+	if _, err := io.Copy(w, io.LimitReader(resp.generated.reader(), sent)); err != nil {
+		slog.Warn("failed to write generated body", "err", err)
+		return false
+	}
+	return sent < size
+}
@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedBody(t *testing.T) {
+	t.Run("invalid size", func(t *testing.T) {
+		for _, size := range []int64{0, -1, MaxGeneratedBodyBytes + 1} {
+			resp, err := NewResponse(WithGeneratedBody(size, 1))
+			assert.Error(t, err, size)
+			assert.Zero(t, resp)
+		}
+	})
+
+	get := func(t *testing.T, size int64, seed uint64) *httptest.ResponseRecorder {
+		t.Helper()
+		resp, err := NewResponse(WithGeneratedBody(size, seed), WithResponseStatus(http.StatusCreated))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	size := int64(100_000)
+	first := get(t, size, 42)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, strconv.FormatInt(size, 10), first.Header().Get("Content-Length"))
+	assert.Len(t, first.Body.Bytes(), int(size))
+
+	assert.Equal(t, first.Body.Bytes(), get(t, size, 42).Body.Bytes(), "same seed should produce same body")
+	assert.NotEqual(t, first.Body.Bytes(), get(t, size, 43).Body.Bytes(), "different seeds should produce different bodies")
+}
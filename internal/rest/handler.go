@@ -0,0 +1,339 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type httpMux interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+type handlerOptions struct {
+	writeTimeout      time.Duration
+	autoContentLength bool
+	debugHeaders      bool
+	statusOverride    bool
+	indexOverride     bool
+}
+
+type HandlerOption func(*handlerOptions)
+
+// WithWriteTimeout extends the write deadline of delayed responses by their delay, so the delay
+// doesn't count against the server's write timeout.
+func WithWriteTimeout(timeout time.Duration) HandlerOption {
+	return func(o *handlerOptions) {
+		o.writeTimeout = timeout
+	}
+}
+
+// WithAutoContentLength sets the Content-Length header on every fully buffered response, rather than
+// leaving larger bodies to chunked encoding.
+func WithAutoContentLength() HandlerOption {
+	return func(o *handlerOptions) {
+		o.autoContentLength = true
+	}
+}
+
+// LimitRequestBytes rejects requests declaring a body larger than maxBytes with a 413 and caps
+// reads of any other body at maxBytes. A maxBytes of 0 disables the limit. tooLarge replaces the
+// default 413 if it's non-nil, including for endpoints rejecting a body found too large as they
+// read it.
+func LimitRequestBytes(next http.Handler, maxBytes int64, tooLarge *Response) http.Handler {
+	if maxBytes == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			if tooLarge != nil {
+				writePlainResponse(w, r, *tooLarge)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		if tooLarge != nil {
+			r = r.WithContext(context.WithValue(r.Context(), tooLargeKey{}, tooLarge))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type tooLargeKey struct{}
+
+// tooLargeResponse returns the response to r when its body exceeds the request size limit.
+func tooLargeResponse(r *http.Request) Response {
+	if resp, ok := r.Context().Value(tooLargeKey{}).(*Response); ok {
+		return *resp
+	}
+	return Response{statusCode: http.StatusRequestEntityTooLarge}
+}
+
+// RegisterHandlers registers endpoint handlers to the given HTTP mux. Endpoints matching paths by
+// regular expression are skipped, they're served by RegexHandler instead.
+func RegisterHandlers(mux httpMux, endpoints []*Endpoint, opts ...HandlerOption) {
+	options := newHandlerOptions(opts)
+
+	for _, endpoint := range endpoints {
+		if endpoint.pathRegex != nil {
+			continue
+		}
+		slog.Info("registering endpoint", "method", endpoint.Method, "path", endpoint.Path)
+		mux.HandleFunc(endpoint.pattern(), options.handler(endpoint).ServeHTTP)
+	}
+	registerMethodNotAllowed(mux, endpoints)
+}
+
+func newHandlerOptions(opts []HandlerOption) handlerOptions {
+	var options handlerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// logRequest logs a request at debug level. Callers check the level first, so building the log's
+// attributes costs nothing per request while debug logs are disabled.
+func logRequest(r *http.Request) {
+	attrs := []any{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("addr", r.RemoteAddr),
+	}
+	if id := requestID(r); id != "" {
+		attrs = append(attrs, slog.String("requestId", id))
+	}
+	slog.DebugContext(r.Context(), "handling request", attrs...)
+}
+
+// handler returns the handler serving endpoint, wrapped in its middleware.
+func (o handlerOptions) handler(endpoint *Endpoint) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			logRequest(r)
+		}
+		if o.debugHeaders {
+			w = newServerTimingWriter(w)
+		}
+
+		endpoint.hits.Add(1)
+		if endpoint.closeConnection {
+			w.Header().Set("Connection", "close")
+		}
+		if limiter := endpoint.concurrencyLimiter; limiter != nil {
+			if !limiter.acquire(r) {
+				if o.debugHeaders {
+					setDebugHeaders(w, responseChoice{strategy: "concurrency"})
+				}
+				o.writeResponse(w, r, endpoint, limiter.response)
+				return
+			}
+			defer limiter.release()
+		}
+
+		resp, choice, err := o.chosenResponse(endpoint, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if o.debugHeaders {
+			setDebugHeaders(w, choice)
+		}
+		o.writeResponse(w, r, endpoint, resp)
+	})
+	wrapped := chainMiddleware(handler, endpoint.middleware)
+	if endpoint.capture != nil {
+		wrapped = endpoint.capture.handler(wrapped)
+	}
+	if endpoint.expectContinue != nil {
+		wrapped = endpoint.expectContinue.handler(wrapped)
+	}
+	return endpoint.stats.handler(wrapped)
+}
+
+// chosenResponse returns the endpoint's response to r, applying the request's overrides if they're
+// enabled. It returns an error if an override is invalid.
+func (o handlerOptions) chosenResponse(endpoint *Endpoint, r *http.Request) (Response, responseChoice, error) {
+	var resp Response
+	var choice responseChoice
+	if header := r.Header.Get(IndexOverrideHeader); o.indexOverride && header != "" {
+		index, err := strconv.Atoi(header)
+		if err != nil {
+			return Response{}, responseChoice{}, fmt.Errorf("invalid %s header", IndexOverrideHeader)
+		}
+		var ok bool
+		if resp, choice, ok = endpoint.chosenResponseAt(r, index); !ok {
+			return Response{}, responseChoice{}, fmt.Errorf("%s %d doesn't match a response of the endpoint", IndexOverrideHeader, index)
+		}
+	} else {
+		resp, choice = endpoint.chosenResponse(r)
+	}
+	if o.statusOverride && !overrideStatus(r, &resp) {
+		return Response{}, responseChoice{}, fmt.Errorf("invalid %s header", StatusOverrideHeader)
+	}
+	return resp, choice, nil
+}
+
+func (o handlerOptions) writeResponse(w http.ResponseWriter, r *http.Request, endpoint *Endpoint, resp Response) {
+	if delay := resp.nextDelay(); delay != 0 {
+		o.wait(w, delay)
+		recordDelay(w, delay)
+	}
+
+	if resp.connFault == ConnectionFaultReset {
+		slog.Info("injecting connection reset",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("addr", r.RemoteAddr),
+		)
+		if r.ProtoMajor >= 2 {
+			abortStream()
+		}
+		if err := resetConnection(w); err != nil {
+			slog.Warn("failed to reset connection, falling back to 502", "err", err)
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		return
+	}
+
+	if resp.websocket != nil {
+		serveWebSocket(w, r, resp.websocket)
+		return
+	}
+	if resp.staticDir != nil {
+		if err := resp.setHeaders(w.Header(), r, endpoint.rawHeaderCase); err != nil {
+			slog.Error("failed to render header template", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp.staticDir.serve(w, r)
+		return
+	}
+
+	currentBody, ok := resp.currentBody()
+	if !ok {
+		writeMissingFile(w, resp)
+		return
+	}
+	resp.body = currentBody
+	if !resp.loadDirBody(w) {
+		return
+	}
+	if resp.template != nil {
+		buf := getBodyBuffer()
+		defer putBodyBuffer(buf)
+
+		renderStart := time.Now()
+		if err := resp.renderBody(r, buf); err != nil {
+			slog.Error("failed to render body template", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		recordRender(w, time.Since(renderStart))
+		resp.body = buf.Bytes()
+	}
+
+	if err := resp.setHeaders(w.Header(), r, endpoint.rawHeaderCase); err != nil {
+		slog.Error("failed to render header template", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if endpoint.reflectHeaders != nil {
+		endpoint.reflectHeaders.reflect(w, r)
+	}
+	if resp.compression != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	if resp.ttfb != 0 {
+		o.wait(w, resp.ttfb)
+		recordDelay(w, resp.ttfb)
+	}
+
+	if resp.stream != nil {
+		writeStream(w, r, resp)
+		return
+	}
+
+	if endpoint.checkNotModified(w, r, resp) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	bodyWriter := w
+	if resp.throttle > 0 {
+		bodyWriter = o.throttle(w, r, resp.throttle, resp.chunkSize)
+	} else if resp.chunkSize > 0 {
+		bodyWriter = newChunkedResponseWriter(w, resp.chunkSize)
+	}
+
+	if resp.generated != nil {
+		if writeGeneratedBody(bodyWriter, resp, endpoint.responseLimit) {
+			endTruncatedResponse(w, r)
+		}
+		return
+	}
+
+	// limit before compressing, so a truncated body is still a valid gzip stream
+	resp = endpoint.responseLimit.limit(resp)
+	if resp.compression != nil && len(resp.body) > 0 && bodyAllowedForStatus(resp.statusCode) && acceptsGzip(r) {
+		compressed, err := resp.compression.compress(resp.body)
+		if err != nil {
+			slog.Error("failed to compress body", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// sniff the type from the uncompressed body, as the server would otherwise sniff gzip
+		if _, ok := w.Header()["Content-Type"]; !ok {
+			w.Header().Set("Content-Type", http.DetectContentType(resp.body))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		resp.body = compressed
+	}
+
+	body := resp.body
+	if !bodyAllowedForStatus(resp.statusCode) {
+		// only reachable with a status override
+		body = nil
+	}
+	if resp.connFault == ConnectionFaultTruncate {
+		slog.Info("injecting truncated body",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("addr", r.RemoteAddr),
+			slog.Int("bytes", resp.truncateBytes),
+		)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		body = body[:min(resp.truncateBytes, len(body))]
+	} else if o.autoContentLength && resp.chunkSize == 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	w.WriteHeader(resp.statusCode)
+	if _, err := bodyWriter.Write(body); err != nil {
+		slog.Warn("failed to write response", "err", err)
+		return
+	}
+
+	if resp.connFault == ConnectionFaultTruncate {
+		endTruncatedResponse(w, r)
+	}
+}
+
+// endTruncatedResponse ends a response whose body is shorter than its Content-Length, so the
+// client sees it cut off rather than waiting for the rest.
+func endTruncatedResponse(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor >= 2 {
+		_ = http.NewResponseController(w).Flush()
+		abortStream()
+	}
+	if err := closeConnection(w); err != nil {
+		slog.Warn("failed to close connection after truncated body", "err", err)
+	}
+}
@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInternalError  = -32603
+)
+
+// JSONRPCMethod is the response to calls of a JSON-RPC method. The response's body must be JSON,
+// and is wrapped in a JSON-RPC 2.0 envelope as the call's result.
+type JSONRPCMethod struct {
+	Response Response
+	// Error wraps the body as the call's error instead, which should be an object with a code and
+	// message.
+	Error bool
+}
+
+// JSONRPCResponse responds to JSON-RPC 2.0 calls with the response configured for the called
+// method, echoing the call's id. Malformed calls and calls to unknown methods get the standard
+// JSON-RPC error envelopes. Notifications, calls without an id, get an empty 204 since JSON-RPC
+// doesn't answer them. Batched calls aren't supported.
+type JSONRPCResponse struct {
+	methods map[string]JSONRPCMethod
+	// names are the method names sorted, so each method has a stable index for debug headers.
+	names []string
+}
+
+func NewJSONRPCResponse(methods map[string]JSONRPCMethod) (*JSONRPCResponse, error) {
+	if len(methods) == 0 {
+		return nil, errors.New("no methods")
+	}
+	for name, method := range methods {
+		resp := method.Response
+		if resp.template != nil || resp.stream != nil || resp.generated != nil || resp.websocket != nil || resp.dir != nil {
+			return nil, fmt.Errorf("method %q must have a JSON body", name)
+		}
+		// watched files are checked when they're served, since they can change
+		if resp.file == nil && !json.Valid(resp.body) {
+			return nil, fmt.Errorf("method %q body is not valid JSON", name)
+		}
+	}
+	return &JSONRPCResponse{methods: methods, names: slices.Sorted(maps.Keys(methods))}, nil
+}
+
+func (j *JSONRPCResponse) NextResponse(r *http.Request) Response {
+	resp, _ := j.nextChosenResponse(r)
+	return resp
+}
+
+// jsonRPCRequest is a JSON-RPC 2.0 call. ID is kept raw so it's echoed exactly as sent.
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  *string         `json:"method"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCEnvelope struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   any             `json:"error,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// nextChosenResponse indexes methods in name order, with errors for calls that don't reach a method
+// indexed after them.
+func (j *JSONRPCResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	notMatched := responseChoice{strategy: "jsonrpc", index: len(j.names)}
+
+	body, err := readBody(r)
+	if err != nil {
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			return tooLargeResponse(r), notMatched
+		}
+		slog.Warn("failed to read request body", "err", err)
+		return Response{statusCode: http.StatusBadRequest}, notMatched
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return jsonRPCErrorResponse(nil, jsonRPCInvalidRequest, "Invalid Request: batch requests are not supported"), notMatched
+	}
+	var call jsonRPCRequest
+	if err := json.Unmarshal(body, &call); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return jsonRPCErrorResponse(nil, jsonRPCInvalidRequest, "Invalid Request"), notMatched
+		}
+		return jsonRPCErrorResponse(nil, jsonRPCParseError, "Parse error"), notMatched
+	}
+	if call.JSONRPC != "2.0" || call.Method == nil || !validJSONRPCID(call.ID) {
+		return jsonRPCErrorResponse(call.ID, jsonRPCInvalidRequest, "Invalid Request"), notMatched
+	}
+	if call.ID == nil {
+		return Response{statusCode: http.StatusNoContent}, notMatched
+	}
+
+	method, ok := j.methods[*call.Method]
+	if !ok {
+		return jsonRPCErrorResponse(call.ID, jsonRPCMethodNotFound, "Method not found"), notMatched
+	}
+	index, _ := slices.BinarySearch(j.names, *call.Method)
+	choice := responseChoice{strategy: "jsonrpc", index: index}
+
+	resp := method.Response
+	result, ok := resp.currentBody()
+	if !ok || !json.Valid(result) {
+		slog.Error("jsonrpc method body is not valid JSON", "method", *call.Method)
+		return jsonRPCErrorResponse(call.ID, jsonRPCInternalError, "Internal error"), choice
+	}
+	envelope := jsonRPCEnvelope{JSONRPC: "2.0", ID: call.ID}
+	if method.Error {
+		envelope.Error = json.RawMessage(result)
+	} else {
+		envelope.Result = result
+	}
+	resp.body, _ = json.Marshal(envelope) // only raw JSON, which was validated
+	resp.file = nil
+	resp.headers = withJSONContentType(resp.headers)
+	return resp, choice
+}
+
+// validJSONRPCID reports whether id is absent or one of the types JSON-RPC allows for ids.
+func validJSONRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	switch id[0] {
+	case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'n':
+		return true
+	}
+	return false
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, code int, message string) Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	body, _ := json.Marshal(jsonRPCEnvelope{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   jsonRPCError{Code: code, Message: message},
+	})
+	return Response{
+		statusCode: http.StatusOK,
+		headers:    map[string]string{"Content-Type": "application/json"},
+		body:       body,
+	}
+}
+
+// withJSONContentType returns headers with a JSON Content-Type, unless they set one.
+func withJSONContentType(headers map[string]string) map[string]string {
+	for k := range headers {
+		if http.CanonicalHeaderKey(k) == "Content-Type" {
+			return headers
+		}
+	}
+	withType := maps.Clone(headers)
+	if withType == nil {
+		withType = make(map[string]string, 1)
+	}
+	withType["Content-Type"] = "application/json"
+	return withType
+}
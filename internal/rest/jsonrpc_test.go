@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONRPCResponse(t *testing.T) {
+	result, err := NewResponse(
+		WithResponseBody([]byte(`{"name":"ada"}`)),
+		WithResponseHeaders(map[string]string{"X-Method": "getUser"}),
+	)
+	require.NoError(t, err)
+	failure, err := NewResponse(WithResponseBody([]byte(`{"code":-32000,"message":"locked"}`)))
+	require.NoError(t, err)
+
+	strategy, err := NewJSONRPCResponse(map[string]JSONRPCMethod{
+		"getUser":    {Response: result},
+		"deleteUser": {Response: failure, Error: true},
+	})
+	require.NoError(t, err)
+
+	call := func(t *testing.T, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		endpoint, err := NewEndpoint("/rpc", http.MethodPost, strategy)
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, WithDebugHeaders())
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+		return w
+	}
+
+	tests := map[string]struct {
+		body   string
+		status int
+		want   string
+		index  string
+	}{
+		"result": {
+			body:   `{"jsonrpc":"2.0","method":"getUser","params":[1],"id":7}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":7,"result":{"name":"ada"}}`,
+			index:  "1",
+		},
+		"string id": {
+			body:   `{"jsonrpc":"2.0","method":"getUser","id":"abc"}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":"abc","result":{"name":"ada"}}`,
+			index:  "1",
+		},
+		"null id": {
+			body:   `{"jsonrpc":"2.0","method":"getUser","id":null}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":null,"result":{"name":"ada"}}`,
+			index:  "1",
+		},
+		"error": {
+			body:   `{"jsonrpc":"2.0","method":"deleteUser","id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"locked"}}`,
+			index:  "0",
+		},
+		"parse error": {
+			body:   `{"jsonrpc":`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+			index:  "2",
+		},
+		"not an object": {
+			body:   `"getUser"`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}}`,
+			index:  "2",
+		},
+		"wrong version": {
+			body:   `{"jsonrpc":"1.0","method":"getUser","id":3}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":3,"error":{"code":-32600,"message":"Invalid Request"}}`,
+			index:  "2",
+		},
+		"invalid id": {
+			body:   `{"jsonrpc":"2.0","method":"getUser","id":{}}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":{},"error":{"code":-32600,"message":"Invalid Request"}}`,
+			index:  "2",
+		},
+		"batch": {
+			body:   `[{"jsonrpc":"2.0","method":"getUser","id":1}]`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request: batch requests are not supported"}}`,
+			index:  "2",
+		},
+		"unknown method": {
+			body:   `{"jsonrpc":"2.0","method":"listUsers","id":4}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"Method not found"}}`,
+			index:  "2",
+		},
+		"notification": {
+			body:   `{"jsonrpc":"2.0","method":"getUser"}`,
+			status: http.StatusNoContent,
+			index:  "2",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := call(t, tt.body)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.want == "" {
+				assert.Empty(t, w.Body.String())
+			} else {
+				assert.JSONEq(t, tt.want, w.Body.String())
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, "jsonrpc", w.Header().Get("X-Mock-Strategy"))
+			assert.Equal(t, tt.index, w.Header().Get("X-Mock-Response-Index"))
+		})
+	}
+
+	t.Run("configured headers", func(t *testing.T) {
+		w := call(t, `{"jsonrpc":"2.0","method":"getUser","id":1}`)
+		assert.Equal(t, "getUser", w.Header().Get("X-Method"))
+	})
+
+	t.Run("body not json", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseBody([]byte("plain")))
+		require.NoError(t, err)
+		_, err = NewJSONRPCResponse(map[string]JSONRPCMethod{"m": {Response: resp}})
+		assert.Error(t, err)
+	})
+
+	t.Run("no methods", func(t *testing.T) {
+		_, err := NewJSONRPCResponse(nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+type DelayDistributionType string
+
+const (
+	DelayDistributionFixed       DelayDistributionType = "fixed"
+	DelayDistributionNormal      DelayDistributionType = "normal"
+	DelayDistributionExponential DelayDistributionType = "exponential"
+	DelayDistributionLogNormal   DelayDistributionType = "lognormal"
+)
+
+// DelayDistribution describes response delays sampled per request.
+type DelayDistribution struct {
+	Type DelayDistributionType
+	Mean time.Duration
+	// StdDev is required for normal and lognormal distributions and unused by the rest.
+	StdDev time.Duration
+}
+
+// floatGenerator draws from standard distributions.
+type floatGenerator interface {
+	// NormFloat64 returns a normally distributed value with mean 0 and standard deviation 1.
+	NormFloat64() float64
+	// ExpFloat64 returns an exponentially distributed value with mean 1.
+	ExpFloat64() float64
+}
+
+func (r rng) NormFloat64() float64 {
+	return rand.NormFloat64()
+}
+
+func (r rng) ExpFloat64() float64 {
+	return rand.ExpFloat64()
+}
+
+type delaySampler struct {
+	dist DelayDistribution
+	gen  floatGenerator
+	// mu and sigma parameterize the normal distribution underlying a lognormal distribution.
+	mu, sigma float64
+}
+
+// WithDelayDistribution delays the response by a duration sampled per request from dist, clamped
+// at zero. It replaces any fixed delay.
+func WithDelayDistribution(dist DelayDistribution) ResponseOption {
+	return withDelayDistribution(dist, rng{})
+}
+
+func withDelayDistribution(dist DelayDistribution, gen floatGenerator) ResponseOption {
+	return func(r *Response) error {
+		if dist.Mean < 0 || dist.StdDev < 0 {
+			return errors.New("delay distribution mean and stddev cannot be negative")
+		}
+
+		sampler := &delaySampler{dist: dist, gen: gen}
+		switch dist.Type {
+		case DelayDistributionFixed, DelayDistributionExponential:
+		case DelayDistributionNormal:
+			if dist.StdDev == 0 {
+				return errors.New("normal delay distribution requires a stddev")
+			}
+		case DelayDistributionLogNormal:
+			if dist.Mean == 0 || dist.StdDev == 0 {
+				return errors.New("lognormal delay distribution requires a positive mean and stddev")
+			}
+			// solve for the underlying normal distribution giving the requested mean and stddev
+			mean, variance := float64(dist.Mean), math.Pow(float64(dist.StdDev), 2)
+			sampler.sigma = math.Sqrt(math.Log1p(variance / (mean * mean)))
+			sampler.mu = math.Log(mean) - sampler.sigma*sampler.sigma/2
+		default:
+			return fmt.Errorf("unknown delay distribution %q", dist.Type)
+		}
+
+		r.delay = 0
+		r.delaySampler = sampler
+		return nil
+	}
+}
+
+func (s *delaySampler) sample() time.Duration {
+	var d float64
+	switch s.dist.Type {
+	case DelayDistributionNormal:
+		d = float64(s.dist.Mean) + s.gen.NormFloat64()*float64(s.dist.StdDev)
+	case DelayDistributionExponential:
+		d = s.gen.ExpFloat64() * float64(s.dist.Mean)
+	case DelayDistributionLogNormal:
+		d = math.Exp(s.mu + s.sigma*s.gen.NormFloat64())
+	default:
+		d = float64(s.dist.Mean)
+	}
+	if d <= 0 {
+		return 0
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// nextDelay returns the delay to apply to this instance of the response.
+func (r Response) nextDelay() time.Duration {
+	if r.delaySampler != nil {
+		return r.delaySampler.sample()
+	}
+	return r.delay
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockFloatGenerator struct {
+	norm, exp float64
+}
+
+func (g mockFloatGenerator) NormFloat64() float64 {
+	return g.norm
+}
+
+func (g mockFloatGenerator) ExpFloat64() float64 {
+	return g.exp
+}
+
+func TestDelayDistribution(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]DelayDistribution{
+			"negative mean":       {Type: DelayDistributionNormal, Mean: -time.Second, StdDev: time.Second},
+			"negative stddev":     {Type: DelayDistributionNormal, Mean: time.Second, StdDev: -time.Second},
+			"normal no stddev":    {Type: DelayDistributionNormal, Mean: time.Second},
+			"lognormal no mean":   {Type: DelayDistributionLogNormal, StdDev: time.Second},
+			"lognormal no stddev": {Type: DelayDistributionLogNormal, Mean: time.Second},
+			"unknown type":        {Type: "uniform", Mean: time.Second},
+		}
+		for name, dist := range cases {
+			_, err := NewResponse(WithDelayDistribution(dist))
+			assert.Error(t, err, name)
+		}
+	})
+
+	cases := map[string]struct {
+		dist DelayDistribution
+		gen  mockFloatGenerator
+		want time.Duration
+	}{
+		"fixed": {
+			dist: DelayDistribution{Type: DelayDistributionFixed, Mean: 50 * time.Millisecond},
+			want: 50 * time.Millisecond,
+		},
+		"normal": {
+			dist: DelayDistribution{Type: DelayDistributionNormal, Mean: 100 * time.Millisecond, StdDev: 20 * time.Millisecond},
+			gen:  mockFloatGenerator{norm: 1.5},
+			want: 130 * time.Millisecond,
+		},
+		"normal clamped at zero": {
+			dist: DelayDistribution{Type: DelayDistributionNormal, Mean: 100 * time.Millisecond, StdDev: 50 * time.Millisecond},
+			gen:  mockFloatGenerator{norm: -3},
+			want: 0,
+		},
+		"exponential": {
+			dist: DelayDistribution{Type: DelayDistributionExponential, Mean: 100 * time.Millisecond},
+			gen:  mockFloatGenerator{exp: 2.5},
+			want: 250 * time.Millisecond,
+		},
+		"lognormal median": {
+			// a standard normal draw of 0 gives the median, exp(mu)
+			dist: DelayDistribution{Type: DelayDistributionLogNormal, Mean: 100 * time.Millisecond, StdDev: 100 * time.Millisecond},
+			want: time.Duration(math.Round(float64(100*time.Millisecond) / math.Sqrt2)),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := NewResponse(withDelayDistribution(tc.dist, tc.gen))
+			require.NoError(t, err)
+			assert.InDelta(t, float64(tc.want), float64(resp.nextDelay()), float64(time.Microsecond))
+		})
+	}
+
+	t.Run("delayed", func(t *testing.T) {
+		resp, err := NewResponse(WithDelayDistribution(DelayDistribution{Type: DelayDistributionFixed, Mean: time.Second}))
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Millisecond, resp.Delayed(5*time.Millisecond).nextDelay())
+		assert.Equal(t, time.Second, resp.nextDelay(), "original is unchanged")
+	})
+
+	t.Run("replaces fixed delay", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseDelay(time.Second),
+			WithDelayDistribution(DelayDistribution{Type: DelayDistributionFixed, Mean: time.Millisecond}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, time.Millisecond, resp.nextDelay())
+	})
+}
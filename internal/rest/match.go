@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MatchCase is a response returned when a request meets every one of its conditions.
+type MatchCase struct {
+	// Headers are request headers that must be present. An empty value matches any value,
+	// otherwise the header's value must be equal.
+	Headers map[string]string
+	// Query are query parameters that must be present, matched like Headers.
+	Query map[string]string
+	// HTTPVersion is the protocol version the request must use, such as HTTP/1.1 or HTTP/2.
+	HTTPVersion string
+	// ClientCertName must be the common name or a DNS name of the client's verified TLS certificate.
+	ClientCertName string
+	// BodySize bounds the size of the request body, such as to answer large uploads differently.
+	BodySize *BodySizeRange
+	Response Response
+
+	// httpMajor and httpMinor are HTTPVersion parsed.
+	httpMajor, httpMinor int
+}
+
+// BodySizeRange bounds a request body's size in bytes, inclusive.
+type BodySizeRange struct {
+	Min int64
+	// Max is unbounded if 0.
+	Max int64
+}
+
+// limit is the number of bytes of a body that must be read to tell whether it's in range.
+func (b BodySizeRange) limit() int64 {
+	if b.Max > 0 {
+		return b.Max + 1
+	}
+	return b.Min
+}
+
+func (b BodySizeRange) contains(size int64) bool {
+	return size >= b.Min && (b.Max == 0 || size <= b.Max)
+}
+
+// MatchResponse returns the response of the first case matching the request. When no case
+// matches it doesn't match either, letting a FallthroughResponse try its next strategy.
+type MatchResponse struct {
+	cases []MatchCase
+	// bodyLimit is the most of a request body any case reads to measure its size.
+	bodyLimit int64
+}
+
+func NewMatchResponse(cases []MatchCase) (*MatchResponse, error) {
+	if len(cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+	cases = slices.Clone(cases)
+	var bodyLimit int64
+	for i, c := range cases {
+		if len(c.Headers) == 0 && len(c.Query) == 0 && c.HTTPVersion == "" && c.ClientCertName == "" && c.BodySize == nil {
+			return nil, fmt.Errorf("case %d has no conditions", i)
+		}
+		if c.BodySize != nil {
+			if c.BodySize.Min < 0 || c.BodySize.Max < 0 {
+				return nil, fmt.Errorf("case %d: body size bounds cannot be negative", i)
+			}
+			if c.BodySize.Max > 0 && c.BodySize.Max < c.BodySize.Min {
+				return nil, fmt.Errorf("case %d: body size max %d is less than min %d", i, c.BodySize.Max, c.BodySize.Min)
+			}
+			bodyLimit = max(bodyLimit, c.BodySize.limit())
+		}
+		if c.HTTPVersion != "" {
+			major, minor, err := parseHTTPVersion(c.HTTPVersion)
+			if err != nil {
+				return nil, fmt.Errorf("case %d: %w", i, err)
+			}
+			cases[i].httpMajor, cases[i].httpMinor = major, minor
+		}
+	}
+	return &MatchResponse{cases: cases, bodyLimit: bodyLimit}, nil
+}
+
+// parseHTTPVersion parses a protocol version such as HTTP/1.1, where HTTP/2 is short for HTTP/2.0
+// and the HTTP/ prefix is optional.
+func parseHTTPVersion(version string) (int, int, error) {
+	v := strings.TrimPrefix(strings.ToUpper(version), "HTTP/")
+	if !strings.Contains(v, ".") {
+		v += ".0"
+	}
+	major, minor, ok := http.ParseHTTPVersion("HTTP/" + v)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid http version %q", version)
+	}
+	return major, minor, nil
+}
+
+// matches reports whether r meets every condition of the case. measureBody returns the request
+// body's size, reporting false if it couldn't be read.
+func (c MatchCase) matches(r *http.Request, query url.Values, measureBody func() (int64, bool)) bool {
+	if c.BodySize != nil {
+		size, ok := measureBody()
+		if !ok || !c.BodySize.contains(size) {
+			return false
+		}
+	}
+	if c.HTTPVersion != "" && (r.ProtoMajor != c.httpMajor || r.ProtoMinor != c.httpMinor) {
+		return false
+	}
+	if c.ClientCertName != "" && !clientCertHasName(r, c.ClientCertName) {
+		return false
+	}
+	return matchValues(c.Headers, r.Header.Values) && matchValues(c.Query, func(key string) []string { return query[key] })
+}
+
+func (c *MatchResponse) NextResponse(r *http.Request) Response {
+	resp, _ := c.nextChosenResponse(r)
+	return resp
+}
+
+// nextChosenResponse returns a 404 when no case matches, for when the strategy isn't followed by
+// another.
+func (c *MatchResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	if resp, choice, ok := c.matchResponse(r); ok {
+		return resp, choice
+	}
+	return Response{statusCode: http.StatusNotFound}, responseChoice{strategy: "match", index: len(c.cases)}
+}
+
+func (c *MatchResponse) matchResponse(r *http.Request) (Response, responseChoice, bool) {
+	query := r.URL.Query()
+	// the body is measured at most once, and only if a case checks its size
+	measureBody := sync.OnceValues(func() (int64, bool) {
+		size, err := bodySize(r, c.bodyLimit)
+		if err != nil {
+			slog.Warn("failed to read request body", "err", err)
+			return 0, false
+		}
+		return size, true
+	})
+	for i, cs := range c.cases {
+		if cs.matches(r, query, measureBody) {
+			return cs.Response, responseChoice{strategy: "match", index: i}, true
+		}
+	}
+	return Response{}, responseChoice{}, false
+}
+
+// clientCertHasName reports whether the client's verified TLS certificate has name as its common
+// name or one of its DNS names.
+func clientCertHasName(r *http.Request, name string) bool {
+	cert := clientCert(r)
+	if cert == nil {
+		return false
+	}
+	return cert.Subject.CommonName == name || slices.Contains(cert.DNSNames, name)
+}
+
+// clientCert returns the client's verified TLS certificate, or nil if it didn't present one or it
+// wasn't verified.
+func clientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// matchValues reports whether every key in want has a value. An empty wanted value matches any
+// value.
+func matchValues(want map[string]string, values func(key string) []string) bool {
+	for key, wantVal := range want {
+		got := values(key)
+		if len(got) == 0 {
+			return false
+		}
+		if wantVal != "" && got[0] != wantVal {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingResolver is implemented by resolvers that may not match a request.
+type matchingResolver interface {
+	matchResponse(r *http.Request) (Response, responseChoice, bool)
+}
+
+// FallthroughResponse tries each of its strategies in order, returning the response of the first
+// that matches the request. Strategies that always respond, like a static response, always match.
+type FallthroughResponse struct {
+	resolvers []ResponseResolver
+}
+
+// NewFallthroughResponse builds a fallthrough strategy. Only the last strategy may always match,
+// since the strategies after it would never be tried.
+func NewFallthroughResponse(resolvers []ResponseResolver) (*FallthroughResponse, error) {
+	if len(resolvers) == 0 {
+		return nil, errors.New("no strategies")
+	}
+	for i, resolver := range resolvers[:len(resolvers)-1] {
+		if _, ok := resolver.(matchingResolver); !ok {
+			return nil, fmt.Errorf("strategy %d always matches, so the strategies after it are never used", i)
+		}
+	}
+	return &FallthroughResponse{resolvers: resolvers}, nil
+}
+
+func (f *FallthroughResponse) NextResponse(r *http.Request) Response {
+	resp, _ := f.nextChosenResponse(r)
+	return resp
+}
+
+// nextChosenResponse reports the choice of the strategy that matched.
+func (f *FallthroughResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	if resp, choice, ok := f.matchResponse(r); ok {
+		return resp, choice
+	}
+	return Response{statusCode: http.StatusNotFound}, responseChoice{strategy: "fallthrough", index: len(f.resolvers)}
+}
+
+func (f *FallthroughResponse) matchResponse(r *http.Request) (Response, responseChoice, bool) {
+	for _, resolver := range f.resolvers {
+		if matcher, ok := resolver.(matchingResolver); ok {
+			if resp, choice, ok := matcher.matchResponse(r); ok {
+				return resp, choice, true
+			}
+			continue
+		}
+		resp, choice := nextChosenResponse(resolver, r)
+		return resp, choice, true
+	}
+	return Response{}, responseChoice{}, false
+}
+
+func (f *FallthroughResponse) fresh() ResponseResolver {
+	resolvers := make([]ResponseResolver, len(f.resolvers))
+	for i, resolver := range f.resolvers {
+		resolvers[i] = freshResolver(resolver)
+	}
+	return &FallthroughResponse{resolvers: resolvers}
+}
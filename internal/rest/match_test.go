@@ -0,0 +1,291 @@
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchResponse(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewMatchResponse(nil)
+		assert.Error(t, err)
+
+		_, err = NewMatchResponse([]MatchCase{{Response: Response{statusCode: http.StatusOK}}})
+		assert.Error(t, err)
+	})
+
+	admin := Response{statusCode: http.StatusOK, body: []byte("admin")}
+	beta := Response{statusCode: http.StatusOK, body: []byte("beta")}
+	strategy, err := NewMatchResponse([]MatchCase{
+		{Headers: map[string]string{"X-Role": "admin"}, Response: admin},
+		{Query: map[string]string{"beta": ""}, Response: beta},
+	})
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		target  string
+		headers map[string]string
+		want    Response
+		matched bool
+	}{
+		"header":           {target: "/", headers: map[string]string{"x-role": "admin"}, want: admin, matched: true},
+		"wrong header":     {target: "/", headers: map[string]string{"X-Role": "user"}},
+		"query any value":  {target: "/?beta=1", want: beta, matched: true},
+		"query empty":      {target: "/?beta", want: beta, matched: true},
+		"first case wins":  {target: "/?beta=1", headers: map[string]string{"X-Role": "admin"}, want: admin, matched: true},
+		"no case matching": {target: "/?alpha=1"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, _, ok := strategy.matchResponse(req)
+			assert.Equal(t, tc.matched, ok)
+			if tc.matched {
+				assert.Equal(t, tc.want, resp)
+			} else {
+				assert.Equal(t, http.StatusNotFound, strategy.NextResponse(req).statusCode)
+			}
+		})
+	}
+}
+
+func TestMatchResponseHTTPVersion(t *testing.T) {
+	_, err := NewMatchResponse([]MatchCase{{HTTPVersion: "HTTP/x", Response: Response{statusCode: http.StatusOK}}})
+	assert.Error(t, err)
+
+	h2 := Response{statusCode: http.StatusOK, body: []byte("h2")}
+	h10 := Response{statusCode: http.StatusOK, body: []byte("h1.0")}
+	strategy, err := NewMatchResponse([]MatchCase{
+		{HTTPVersion: "HTTP/2", Response: h2},
+		{HTTPVersion: "1.0", Response: h10},
+	})
+	require.NoError(t, err)
+
+	for proto, want := range map[string]*Response{"HTTP/2.0": &h2, "HTTP/1.0": &h10, "HTTP/1.1": nil} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		var ok bool
+		req.Proto = proto
+		req.ProtoMajor, req.ProtoMinor, ok = http.ParseHTTPVersion(proto)
+		require.True(t, ok)
+
+		resp, _, matched := strategy.matchResponse(req)
+		assert.Equal(t, want != nil, matched, proto)
+		if want != nil {
+			assert.Equal(t, *want, resp, proto)
+		}
+	}
+}
+
+func TestMatchResponseBodySize(t *testing.T) {
+	for _, bounds := range []BodySizeRange{{Min: -1}, {Max: -1}, {Min: 10, Max: 5}} {
+		_, err := NewMatchResponse([]MatchCase{{BodySize: &bounds, Response: Response{statusCode: http.StatusOK}}})
+		assert.Error(t, err, bounds)
+	}
+
+	tooLarge := Response{statusCode: http.StatusRequestEntityTooLarge}
+	small := Response{statusCode: http.StatusOK, body: []byte("small")}
+	strategy, err := NewMatchResponse([]MatchCase{
+		{BodySize: &BodySizeRange{Min: 1024}, Response: tooLarge},
+		{BodySize: &BodySizeRange{Min: 1, Max: 16}, Response: small},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), strategy.bodyLimit)
+
+	cases := map[string]struct {
+		size    int
+		chunked bool
+		want    *Response
+	}{
+		"large":         {size: 4096, want: &tooLarge},
+		"large chunked": {size: 4096, chunked: true, want: &tooLarge},
+		"at min":        {size: 1024, chunked: true, want: &tooLarge},
+		"small":         {size: 16, want: &small},
+		"small chunked": {size: 3, chunked: true, want: &small},
+		"in between":    {size: 17, chunked: true},
+		"empty":         {size: 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body := strings.Repeat("a", tc.size)
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+
+			resp, _, matched := strategy.matchResponse(req)
+			assert.Equal(t, tc.want != nil, matched)
+			if tc.want != nil {
+				assert.Equal(t, *tc.want, resp)
+			}
+			read, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(read), "the body is left for later readers")
+		})
+	}
+
+	t.Run("over the request size limit", func(t *testing.T) {
+		for _, buffered := range []bool{false, true} {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 100)))
+			req.ContentLength = -1
+			req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 10)
+			if buffered {
+				// as when a request schema or template has read the body first
+				_, err := readBody(req)
+				require.Error(t, err)
+			}
+
+			resp, _, matched := strategy.matchResponse(req)
+			assert.True(t, matched, "buffered: %v", buffered)
+			assert.Equal(t, tooLarge, resp, "buffered: %v", buffered)
+		}
+	})
+}
+
+func TestMatchResponseClientCert(t *testing.T) {
+	caPool, clientCert := newTestClientCert(t, "client.example")
+
+	tmpl, err := NewResponse(WithResponseTemplate(`{{ .ClientCertSubject }}`, nil))
+	require.NoError(t, err)
+	strategy, err := NewMatchResponse([]MatchCase{
+		{ClientCertName: "client.example", Response: tmpl},
+	})
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/", http.MethodGet, strategy)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: caPool}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	clientWith := func(certs ...tls.Certificate) *http.Client {
+		client := server.Client()
+		transport := client.Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = certs
+		client.Transport = transport
+		return client
+	}
+	get := func(t *testing.T, certs ...tls.Certificate) (int, string) {
+		t.Helper()
+		resp, err := clientWith(certs...).Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get(t, clientCert)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "CN=client.example,O=Mock Test", body)
+
+	status, _ = get(t)
+	assert.Equal(t, http.StatusNotFound, status, "no client cert")
+
+	_, untrustedCert := newTestClientCert(t, "client.example")
+	_, err = clientWith(untrustedCert).Get(server.URL)
+	assert.Error(t, err, "cert from an untrusted CA should fail the handshake")
+}
+
+// newTestClientCert returns a pool with a new CA, and a client certificate it signed for name.
+func newTestClientCert(t *testing.T, name string) (*x509.CertPool, tls.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Mock Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"Mock Test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestFallthroughResponse(t *testing.T) {
+	admin := Response{statusCode: http.StatusOK, body: []byte("admin")}
+	matcher, err := NewMatchResponse([]MatchCase{
+		{Headers: map[string]string{"X-Role": "admin"}, Response: admin},
+	})
+	require.NoError(t, err)
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewFallthroughResponse(nil)
+		assert.Error(t, err)
+
+		_, err = NewFallthroughResponse([]ResponseResolver{StaticResponse(admin), matcher})
+		assert.Error(t, err, "static response before another strategy")
+	})
+
+	t.Run("falls through", func(t *testing.T) {
+		fallback := Response{statusCode: http.StatusForbidden}
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{fallback, {statusCode: http.StatusUnauthorized}}, nil)
+		require.NoError(t, err)
+		strategy, err := NewFallthroughResponse([]ResponseResolver{matcher, sequence})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		resp, choice := strategy.nextChosenResponse(req)
+		assert.Equal(t, fallback, resp)
+		assert.Equal(t, responseChoice{strategy: "sequence", index: 0}, choice)
+
+		req.Header.Set("X-Role", "admin")
+		resp, choice = strategy.nextChosenResponse(req)
+		assert.Equal(t, admin, resp)
+		assert.Equal(t, responseChoice{strategy: "match", index: 0}, choice)
+
+		// only requests falling through advance the sequence
+		req.Header.Del("X-Role")
+		assert.Equal(t, http.StatusUnauthorized, strategy.NextResponse(req).statusCode)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		strategy, err := NewFallthroughResponse([]ResponseResolver{matcher})
+		require.NoError(t, err)
+
+		resp := strategy.NextResponse(httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusNotFound, resp.statusCode)
+	})
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Middleware wraps an endpoint's handler with cross-cutting behavior.
+type Middleware func(http.Handler) http.Handler
+
+// middlewares are the built-in middlewares that can be enabled per endpoint by name.
+var middlewares = map[string]Middleware{
+	"cors":    corsMiddleware,
+	"noCache": noCacheMiddleware,
+}
+
+// LookupMiddleware returns the built-in middleware with the given name.
+func LookupMiddleware(name string) (Middleware, error) {
+	mw, ok := middlewares[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown middleware %q", name)
+	}
+	return mw, nil
+}
+
+// WithMiddleware wraps the endpoint's handler in the given middlewares. The first middleware is
+// the outermost, so it sees the request first.
+func WithMiddleware(mws ...Middleware) EndpointOption {
+	return func(e *Endpoint) error {
+		e.middleware = append(e.middleware, mws...)
+		return nil
+	}
+}
+
+func chainMiddleware(h http.Handler, mws []Middleware) http.Handler {
+	for _, mw := range slices.Backward(mws) {
+		h = mw(h)
+	}
+	return h
+}
+
+// corsPreflightMaxAge is how long, in seconds, browsers may cache a preflight response.
+const corsPreflightMaxAge = "600"
+
+// corsMiddleware allows cross-origin requests from any origin. It answers preflight requests itself
+// with a 204 allowing the method and headers asked for, without consulting the endpoint.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || method == "" {
+			w.Header().Set("Access-Control-Expose-Headers", "*")
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", method)
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", corsPreflightMaxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// noCacheMiddleware tells clients and proxies not to cache responses.
+func noCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupMiddleware(t *testing.T) {
+	for _, name := range []string{"cors", "noCache"} {
+		mw, err := LookupMiddleware(name)
+		assert.NoError(t, err, name)
+		assert.NotNil(t, mw, name)
+	}
+
+	mw, err := LookupMiddleware("auth")
+	assert.Error(t, err)
+	assert.Nil(t, mw)
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var order []string
+	tracing := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	resp, err := NewResponse(WithResponseBody([]byte("hi")))
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp),
+		WithMiddleware(tracing("first"), corsMiddleware),
+		WithMiddleware(tracing("second")),
+	)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	assert.Equal(t, []string{"first", "second"}, order)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "hi", w.Body.String())
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("routed"))
+	})
+	handler := corsMiddleware(next)
+	serve := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for name, val := range headers {
+			req.Header.Set(name, val)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("same origin", func(t *testing.T) {
+		w := serve(http.MethodGet, nil)
+		assert.Equal(t, "routed", w.Body.String())
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("cross origin", func(t *testing.T) {
+		w := serve(http.MethodGet, map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "routed", w.Body.String())
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		w := serve(http.MethodOptions, map[string]string{
+			"Origin":                         "https://example.com",
+			"Access-Control-Request-Method":  http.MethodPut,
+			"Access-Control-Request-Headers": "content-type, x-token",
+		})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, http.MethodPut, w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, x-token", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, corsPreflightMaxAge, w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("options without a requested method", func(t *testing.T) {
+		w := serve(http.MethodOptions, map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "routed", w.Body.String())
+	})
+}
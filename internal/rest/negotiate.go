@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Representation is one media type an endpoint can serve.
+type Representation struct {
+	MediaType string
+	Response  Response
+}
+
+// NegotiatedResponse picks the representation best matching the request's Accept header.
+type NegotiatedResponse struct {
+	representations []Representation
+	fallback        Response
+}
+
+// NewNegotiatedResponse builds a content negotiation strategy. Each representation's response has
+// its Content-Type set to its media type. When no representation is acceptable, fallback is
+// returned, or a 406 if fallback is nil.
+func NewNegotiatedResponse(representations []Representation, fallback *Response) (*NegotiatedResponse, error) {
+	if len(representations) == 0 {
+		return nil, errors.New("no representations")
+	}
+
+	negotiated := &NegotiatedResponse{
+		fallback: Response{statusCode: http.StatusNotAcceptable},
+	}
+	if fallback != nil {
+		negotiated.fallback = *fallback
+	}
+	negotiated.fallback.headers = maps.Clone(negotiated.fallback.headers)
+	if negotiated.fallback.headers == nil {
+		negotiated.fallback.headers = make(map[string]string, 1)
+	}
+	negotiated.fallback.headers["Vary"] = "Accept"
+
+	for _, rep := range representations {
+		mediaType, _, err := mime.ParseMediaType(rep.MediaType)
+		if err != nil || strings.Contains(mediaType, "*") {
+			return nil, fmt.Errorf("invalid media type %q", rep.MediaType)
+		}
+
+		headers := make(map[string]string, len(rep.Response.headers)+2)
+		for k, v := range rep.Response.headers {
+			if !strings.EqualFold(k, "Content-Type") {
+				headers[k] = v
+			}
+		}
+		headers["Content-Type"] = rep.MediaType
+		headers["Vary"] = "Accept"
+		rep.Response.headers = headers
+
+		negotiated.representations = append(negotiated.representations, Representation{
+			MediaType: mediaType,
+			Response:  rep.Response,
+		})
+	}
+
+	return negotiated, nil
+}
+
+func (n *NegotiatedResponse) NextResponse(r *http.Request) Response {
+	resp, _ := n.nextChosenResponse(r)
+	return resp
+}
+
+func (n *NegotiatedResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	accept := parseAccept(r.Header.Values("Accept"))
+	if len(accept) == 0 {
+		return n.representations[0].Response, responseChoice{strategy: "representations"}
+	}
+
+	best, bestQuality := -1, 0.0
+	for i, rep := range n.representations {
+		if q := accept.quality(rep.MediaType); q > bestQuality {
+			best, bestQuality = i, q
+		}
+	}
+	if best < 0 {
+		return n.fallback, responseChoice{strategy: "representations", index: len(n.representations)}
+	}
+	return n.representations[best].Response, responseChoice{strategy: "representations", index: best}
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+type acceptHeader []mediaRange
+
+// parseAccept parses Accept header values into media ranges, skipping malformed ones.
+func parseAccept(values []string) acceptHeader {
+	var ranges acceptHeader
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				parsed, err := strconv.ParseFloat(q, 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					continue
+				}
+				quality = parsed
+			}
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching mediaType, or 0 if none do.
+func (a acceptHeader) quality(mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, rng := range a {
+		var s int
+		switch {
+		case rng.mediaType == mediaType:
+			s = 2
+		case rng.mediaType == typ+"/*":
+			s = 1
+		case rng.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			quality, specificity = rng.quality, s
+		}
+	}
+	return quality
+}
@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiatedResponse(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		strategy, err := NewNegotiatedResponse(nil, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+
+		strategy, err = NewNegotiatedResponse([]Representation{{MediaType: "application/*"}}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+	})
+
+	jsonResp, err := NewResponse(
+		WithResponseBody([]byte(`{"id":1}`)),
+		WithResponseHeaders(map[string]string{"content-type": "text/plain", "X-Extra": "json"}),
+	)
+	require.NoError(t, err)
+	xmlResp, err := NewResponse(WithResponseBody([]byte(`<id>1</id>`)))
+	require.NoError(t, err)
+	representations := []Representation{
+		{MediaType: "application/json", Response: jsonResp},
+		{MediaType: "application/xml", Response: xmlResp},
+	}
+
+	strategy, err := NewNegotiatedResponse(representations, nil)
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		accept    string
+		wantType  string
+		wantBody  string
+		wantCode  int
+		wantExtra string
+	}{
+		"no accept header": {
+			wantType:  "application/json",
+			wantBody:  `{"id":1}`,
+			wantCode:  http.StatusOK,
+			wantExtra: "json",
+		},
+		"exact match": {
+			accept:   "application/xml",
+			wantType: "application/xml",
+			wantBody: `<id>1</id>`,
+			wantCode: http.StatusOK,
+		},
+		"quality values": {
+			accept:   "application/json;q=0.5, application/xml;q=0.9",
+			wantType: "application/xml",
+			wantBody: `<id>1</id>`,
+			wantCode: http.StatusOK,
+		},
+		"more specific range wins": {
+			accept:   "application/*;q=0.8, application/json;q=0",
+			wantType: "application/xml",
+			wantBody: `<id>1</id>`,
+			wantCode: http.StatusOK,
+		},
+		"wildcard": {
+			accept:    "*/*",
+			wantType:  "application/json",
+			wantBody:  `{"id":1}`,
+			wantCode:  http.StatusOK,
+			wantExtra: "json",
+		},
+		"not acceptable": {
+			accept:   "text/html",
+			wantCode: http.StatusNotAcceptable,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			endpoint, err := NewEndpoint("/", http.MethodGet, strategy)
+			require.NoError(t, err)
+
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			if tc.wantType != "" {
+				assert.Equal(t, tc.wantType, w.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, tc.wantBody, w.Body.String())
+			assert.Equal(t, tc.wantExtra, w.Header().Get("X-Extra"))
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		fallback, err := NewResponse(WithResponseBody([]byte("fallback")))
+		require.NoError(t, err)
+		strategy, err := NewNegotiatedResponse(representations, &fallback)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/html")
+		resp := strategy.NextResponse(req)
+		assert.Equal(t, http.StatusOK, resp.statusCode)
+		assert.Equal(t, []byte("fallback"), resp.body)
+	})
+}
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// OutageResponse wraps a strategy with periodic outages: for the last duration of every period,
+// counted from startup, requests get the outage response instead. Unlike faults, outages are
+// predictable, for testing how circuit breakers open and close over time.
+type OutageResponse struct {
+	resolver  ResponseResolver
+	every     time.Duration
+	duration  time.Duration
+	response  Response
+	startedAt time.Time
+	clock     clock
+}
+
+// NewOutageResponse wraps resolver with an outage of duration every period, starting now. The
+// first outage ends one period after startup, so the endpoint works normally first.
+// If clk is nil, the system clock is used.
+func NewOutageResponse(resolver ResponseResolver, every, duration time.Duration, response Response, clk clock) (*OutageResponse, error) {
+	if resolver == nil {
+		return nil, errors.New("no response strategy to wrap")
+	}
+	if every <= 0 || duration <= 0 {
+		return nil, errors.New("outage period and duration must be positive")
+	}
+	if duration >= every {
+		return nil, errors.New("outage duration must be shorter than its period")
+	}
+
+	if clk == nil {
+		clk = systemClock{}
+	}
+
+	return &OutageResponse{
+		resolver:  resolver,
+		every:     every,
+		duration:  duration,
+		response:  response,
+		startedAt: clk.Now(),
+		clock:     clk,
+	}, nil
+}
+
+// down reports whether an outage is underway. It only reads fields set at construction, so it's
+// safe to call concurrently.
+func (o *OutageResponse) down() bool {
+	elapsed := o.clock.Now().Sub(o.startedAt)
+	return elapsed >= 0 && elapsed%o.every >= o.every-o.duration
+}
+
+func (o *OutageResponse) NextResponse(r *http.Request) Response {
+	resp, _ := o.nextChosenResponse(r)
+	return resp
+}
+
+// nextChosenResponse doesn't advance the wrapped strategy during an outage.
+func (o *OutageResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	if o.down() {
+		return o.response, responseChoice{strategy: "outage", index: 0}
+	}
+	return nextChosenResponse(o.resolver, r)
+}
+
+// responseAt picks from the wrapped strategy, ignoring outages.
+func (o *OutageResponse) responseAt(r *http.Request, index int) (Response, responseChoice, bool) {
+	return responseAt(o.resolver, r, index)
+}
+
+func (o *OutageResponse) fresh() ResponseResolver {
+	fresh := *o
+	fresh.resolver = freshResolver(o.resolver)
+	return &fresh
+}
+
+func (o *OutageResponse) describe() StrategyDescription {
+	wrapped := describeResolver(o.resolver)
+	return StrategyDescription{
+		Type:    "outage",
+		Entries: responseEntries(o.response),
+		Wrapped: &wrapped,
+		Every:   o.every.String(),
+		For:     o.duration.String(),
+	}
+}
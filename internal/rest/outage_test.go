@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutageResponse(t *testing.T) {
+	first := Response{statusCode: http.StatusOK}
+	second := Response{statusCode: http.StatusCreated}
+	down := Response{statusCode: http.StatusServiceUnavailable}
+
+	t.Run("invalid", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first}, nil)
+		require.NoError(t, err)
+		cases := map[string]struct {
+			resolver        ResponseResolver
+			every, duration time.Duration
+		}{
+			"no strategy":        {nil, time.Minute, time.Second},
+			"zero period":        {sequence, 0, time.Second},
+			"zero duration":      {sequence, time.Minute, 0},
+			"duration too long":  {sequence, time.Minute, time.Minute},
+			"negative durations": {sequence, -time.Minute, -time.Second},
+		}
+		for name, tc := range cases {
+			strategy, err := NewOutageResponse(tc.resolver, tc.every, tc.duration, down, nil)
+			assert.Error(t, err, name)
+			assert.Nil(t, strategy, name)
+		}
+	})
+
+	t.Run("periodic outages", func(t *testing.T) {
+		sequence, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
+		require.NoError(t, err)
+		clk := &mockClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		start := clk.now
+		strategy, err := NewOutageResponse(sequence, 2*time.Minute, 10*time.Second, down, clk)
+		require.NoError(t, err)
+
+		cases := []struct {
+			elapsed time.Duration
+			want    Response
+		}{
+			{0, first},
+			{time.Minute, second},
+			{110 * time.Second, down},
+			{119 * time.Second, down},
+			{2 * time.Minute, first},
+			{3*time.Minute + 55*time.Second, down},
+			{4 * time.Minute, second},
+		}
+		for _, tc := range cases {
+			clk.now = start.Add(tc.elapsed)
+			assert.Equal(t, tc.want, strategy.NextResponse(nil), tc.elapsed)
+		}
+	})
+
+	t.Run("debug choice", func(t *testing.T) {
+		clk := &mockClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		strategy, err := NewOutageResponse(StaticResponse(first), time.Minute, time.Second, down, clk)
+		require.NoError(t, err)
+
+		_, choice := strategy.nextChosenResponse(nil)
+		assert.Equal(t, responseChoice{strategy: "static"}, choice)
+		clk.now = clk.now.Add(59 * time.Second)
+		_, choice = strategy.nextChosenResponse(nil)
+		assert.Equal(t, responseChoice{strategy: "outage"}, choice)
+	})
+}
@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// freshResolver returns a copy of r with its state reset, so it can be used independently of r.
+// Stateless resolvers are returned as-is.
+func freshResolver(r ResponseResolver) ResponseResolver {
+	if f, ok := r.(interface{ fresh() ResponseResolver }); ok {
+		return f.fresh()
+	}
+	return r
+}
+
+func (s *SequencedResponse) fresh() ResponseResolver {
+	return &SequencedResponse{
+		endBehavior: s.endBehavior,
+		sequence:    s.sequence,
+		end:         s.end,
+	}
+}
+
+// fresh gives a weighted response with occurrence limits its own counts. Without limits, it's
+// stateless.
+func (w *WeightedResponse) fresh() ResponseResolver {
+	if w.limits == nil {
+		return w
+	}
+	limits := make([]occurrenceLimit, len(w.limits))
+	for i, limit := range w.limits {
+		limits[i] = occurrenceLimit{weight: limit.weight, max: limit.max}
+	}
+	fresh := &WeightedResponse{
+		numGenerator: w.numGenerator,
+		responses:    w.responses,
+		weights:      make([]float64, len(limits)),
+		fractional:   w.fractional,
+		limits:       limits,
+	}
+	fresh.reweigh()
+	return fresh
+}
+
+func (t *ThresholdResponse) fresh() ResponseResolver {
+	return &ThresholdResponse{
+		threshold: t.threshold,
+		before:    t.before,
+		after:     t.after,
+	}
+}
+
+func (f *FaultResponse) fresh() ResponseResolver {
+	return &FaultResponse{
+		resolver:     freshResolver(f.resolver),
+		faults:       f.faults,
+		numGenerator: f.numGenerator,
+	}
+}
+
+type PerClientOptions struct {
+	// Header identifies clients by the value of a request header. Requests without the header, or
+	// all requests if Header is empty, are identified by Cookie, then by remote IP.
+	Header string
+	// Cookie identifies clients by the value of a request cookie, when Header doesn't.
+	Cookie string
+	// TTL evicts clients that haven't made a request within it. Zero disables expiry.
+	TTL time.Duration
+	// MaxClients caps the tracked clients, evicting the least recently seen when full.
+	MaxClients int
+}
+
+type clientState struct {
+	resolver ResponseResolver
+	lastSeen time.Time
+}
+
+// PerClientResponse scopes a stateful strategy to each client. Every client gets its own copy of the
+// strategy, so e.g. a sequence advances independently per client.
+type PerClientResponse struct {
+	prototype ResponseResolver
+	opts      PerClientOptions
+	clock     clock
+
+	mu      sync.Mutex
+	clients map[string]*clientState
+}
+
+// NewPerClientResponse builds a per-client strategy from prototype, which is copied for each client.
+// If clk is nil, the system clock is used.
+func NewPerClientResponse(prototype ResponseResolver, opts PerClientOptions, clk clock) (*PerClientResponse, error) {
+	if prototype == nil {
+		return nil, errors.New("no response strategy to scope per client")
+	}
+	if opts.TTL < 0 {
+		return nil, errors.New("client ttl cannot be negative")
+	}
+	if opts.MaxClients <= 0 {
+		return nil, errors.New("max clients must be >= 1")
+	}
+
+	if clk == nil {
+		clk = systemClock{}
+	}
+
+	return &PerClientResponse{
+		prototype: prototype,
+		opts:      opts,
+		clock:     clk,
+		clients:   make(map[string]*clientState),
+	}, nil
+}
+
+func (p *PerClientResponse) NextResponse(r *http.Request) Response {
+	return p.resolverFor(p.clientKey(r)).NextResponse(r)
+}
+
+func (p *PerClientResponse) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	return nextChosenResponse(p.resolverFor(p.clientKey(r)), r)
+}
+
+func (p *PerClientResponse) responseAt(r *http.Request, index int) (Response, responseChoice, bool) {
+	return responseAt(p.resolverFor(p.clientKey(r)), r, index)
+}
+
+func (p *PerClientResponse) clientKey(r *http.Request) string {
+	if p.opts.Header != "" {
+		if key := r.Header.Get(p.opts.Header); key != "" {
+			return "header:" + key
+		}
+	}
+	if p.opts.Cookie != "" {
+		if cookie, err := r.Cookie(p.opts.Cookie); err == nil && cookie.Value != "" {
+			return "cookie:" + cookie.Value
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+func (p *PerClientResponse) resolverFor(key string) ResponseResolver {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+
+	if client, ok := p.clients[key]; ok && !p.expired(client, now) {
+		client.lastSeen = now
+		return client.resolver
+	}
+
+	if len(p.clients) >= p.opts.MaxClients {
+		p.evict(now)
+	}
+
+	client := &clientState{
+		resolver: freshResolver(p.prototype),
+		lastSeen: now,
+	}
+	p.clients[key] = client
+	return client.resolver
+}
+
+func (p *PerClientResponse) expired(client *clientState, now time.Time) bool {
+	return p.opts.TTL > 0 && now.Sub(client.lastSeen) >= p.opts.TTL
+}
+
+// evict removes expired clients, or the least recently seen client if none have expired.
+// Must be called with mu held.
+func (p *PerClientResponse) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, client := range p.clients {
+		if p.expired(client, now) {
+			delete(p.clients, key)
+			continue
+		}
+		if oldestKey == "" || client.lastSeen.Before(oldest) {
+			oldestKey, oldest = key, client.lastSeen
+		}
+	}
+
+	if len(p.clients) >= p.opts.MaxClients {
+		delete(p.clients, oldestKey)
+	}
+}
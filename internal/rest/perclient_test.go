@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerClientResponse(t *testing.T) {
+	created := Response{statusCode: http.StatusCreated}
+	conflict := Response{statusCode: http.StatusConflict}
+
+	newPrototype := func(t *testing.T) ResponseResolver {
+		sequence, err := NewSequencedResponse(SequenceBehaviorRepeatLast, []Response{created, conflict}, nil)
+		require.NoError(t, err)
+		return sequence
+	}
+	requestFrom := func(addr, clientID string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = addr
+		if clientID != "" {
+			r.Header.Set("X-Client-Id", clientID)
+		}
+		return r
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		strategy, err := NewPerClientResponse(nil, PerClientOptions{MaxClients: 1}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+
+		strategy, err = NewPerClientResponse(newPrototype(t), PerClientOptions{MaxClients: 0}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+
+		strategy, err = NewPerClientResponse(newPrototype(t), PerClientOptions{MaxClients: 1, TTL: -time.Second}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+	})
+
+	t.Run("keyed by remote ip", func(t *testing.T) {
+		strategy, err := NewPerClientResponse(newPrototype(t), PerClientOptions{MaxClients: 10}, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, created, strategy.NextResponse(requestFrom("10.0.0.1:5000", "")))
+		// port differs but same client
+		assert.Equal(t, conflict, strategy.NextResponse(requestFrom("10.0.0.1:5001", "")))
+		assert.Equal(t, created, strategy.NextResponse(requestFrom("10.0.0.2:5000", "")))
+	})
+
+	t.Run("keyed by header", func(t *testing.T) {
+		strategy, err := NewPerClientResponse(newPrototype(t), PerClientOptions{Header: "X-Client-Id", MaxClients: 10}, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, created, strategy.NextResponse(requestFrom("10.0.0.1:5000", "alice")))
+		assert.Equal(t, created, strategy.NextResponse(requestFrom("10.0.0.1:5000", "bob")))
+		assert.Equal(t, conflict, strategy.NextResponse(requestFrom("10.0.0.2:5000", "alice")))
+		// falls back to remote ip without the header
+		assert.Equal(t, created, strategy.NextResponse(requestFrom("10.0.0.1:5000", "")))
+	})
+
+	t.Run("evicts after ttl", func(t *testing.T) {
+		clk := &mockClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		strategy, err := NewPerClientResponse(newPrototype(t), PerClientOptions{TTL: time.Minute, MaxClients: 10}, clk)
+		require.NoError(t, err)
+
+		r := requestFrom("10.0.0.1:5000", "")
+		assert.Equal(t, created, strategy.NextResponse(r))
+		clk.now = clk.now.Add(30 * time.Second)
+		assert.Equal(t, conflict, strategy.NextResponse(r))
+		clk.now = clk.now.Add(time.Minute)
+		assert.Equal(t, created, strategy.NextResponse(r))
+	})
+
+	t.Run("evicts least recently seen when full", func(t *testing.T) {
+		clk := &mockClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		strategy, err := NewPerClientResponse(newPrototype(t), PerClientOptions{MaxClients: 2}, clk)
+		require.NoError(t, err)
+
+		first, second, third := requestFrom("10.0.0.1:1", ""), requestFrom("10.0.0.2:1", ""), requestFrom("10.0.0.3:1", "")
+		strategy.NextResponse(first)
+		clk.now = clk.now.Add(time.Second)
+		strategy.NextResponse(second)
+		clk.now = clk.now.Add(time.Second)
+		strategy.NextResponse(third)
+
+		assert.Len(t, strategy.clients, 2)
+		assert.Equal(t, conflict, strategy.NextResponse(second))
+		assert.Equal(t, created, strategy.NextResponse(first))
+	})
+}
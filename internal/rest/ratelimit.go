@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+	// Response is returned instead of the endpoint's response while throttled.
+	Response Response
+}
+
+// rateLimiter is a token bucket holding up to burst tokens, refilled at rate tokens per second.
+type rateLimiter struct {
+	rate     float64
+	burst    float64
+	response Response
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(limit RateLimit) (*rateLimiter, error) {
+	if limit.RequestsPerSecond <= 0 {
+		return nil, errors.New("requests per second must be > 0")
+	}
+	if limit.Burst <= 0 {
+		return nil, errors.New("burst must be >= 1")
+	}
+
+	return &rateLimiter{
+		rate:     limit.RequestsPerSecond,
+		burst:    float64(limit.Burst),
+		response: limit.Response,
+		tokens:   float64(limit.Burst),
+		now:      time.Now,
+	}, nil
+}
+
+// allow reports whether a request may proceed, consuming a token if so.
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
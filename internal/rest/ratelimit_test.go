@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("invalid rate", func(t *testing.T) {
+		limiter, err := newRateLimiter(RateLimit{RequestsPerSecond: 0, Burst: 1})
+		assert.Error(t, err)
+		assert.Nil(t, limiter)
+	})
+
+	t.Run("invalid burst", func(t *testing.T) {
+		limiter, err := newRateLimiter(RateLimit{RequestsPerSecond: 1, Burst: 0})
+		assert.Error(t, err)
+		assert.Nil(t, limiter)
+	})
+
+	t.Run("burst then refill", func(t *testing.T) {
+		limiter, err := newRateLimiter(RateLimit{RequestsPerSecond: 2, Burst: 3})
+		require.NoError(t, err)
+
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		limiter.now = func() time.Time { return now }
+
+		for range 3 {
+			assert.True(t, limiter.allow())
+		}
+		assert.False(t, limiter.allow())
+
+		// half a second at 2 rps refills a single token
+		now = now.Add(500 * time.Millisecond)
+		assert.True(t, limiter.allow())
+		assert.False(t, limiter.allow())
+
+		// refill never exceeds burst
+		now = now.Add(time.Minute)
+		for range 3 {
+			assert.True(t, limiter.allow())
+		}
+		assert.False(t, limiter.allow())
+	})
+}
+
+func TestEndpointRateLimit(t *testing.T) {
+	ok := Response{statusCode: http.StatusOK}
+	throttled := Response{
+		statusCode: http.StatusTooManyRequests,
+		headers:    map[string]string{"Retry-After": "1"},
+	}
+
+	endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(ok), WithRateLimit(RateLimit{
+		RequestsPerSecond: 1,
+		Burst:             2,
+		Response:          throttled,
+	}))
+	require.NoError(t, err)
+	endpoint.rateLimiter.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	assert.Equal(t, ok, endpoint.Response(nil))
+	assert.Equal(t, ok, endpoint.Response(nil))
+	assert.Equal(t, throttled, endpoint.Response(nil))
+}
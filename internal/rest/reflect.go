@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// reflectHeaders copies request headers into the response.
+type reflectHeaders struct {
+	names []string
+	// override replaces response headers that are already set.
+	override bool
+}
+
+// WithReflectHeaders copies the named request headers, with all their values, into the response.
+// Headers the response already sets are kept unless override is set, and headers missing from the
+// request are skipped.
+func WithReflectHeaders(names []string, override bool) EndpointOption {
+	return func(e *Endpoint) error {
+		if len(names) == 0 {
+			return errors.New("no headers to reflect")
+		}
+		canonical := make([]string, 0, len(names))
+		for _, name := range names {
+			if name == "" {
+				return errors.New("reflected header name cannot be empty")
+			}
+			canonical = append(canonical, http.CanonicalHeaderKey(name))
+		}
+		e.reflectHeaders = &reflectHeaders{names: canonical, override: override}
+		return nil
+	}
+}
+
+func (h *reflectHeaders) reflect(w http.ResponseWriter, r *http.Request) {
+	for _, name := range h.names {
+		values := r.Header[name]
+		if len(values) == 0 {
+			continue
+		}
+		if _, set := w.Header()[name]; set && !h.override {
+			continue
+		}
+		w.Header()[name] = slices.Clone(values)
+	}
+}
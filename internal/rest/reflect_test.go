@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReflectHeaders(t *testing.T) {
+	resp := Response{statusCode: http.StatusOK, headers: map[string]string{"X-Trace-Id": "configured"}}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp), WithReflectHeaders(nil, false))
+		assert.Error(t, err)
+
+		_, err = NewEndpoint("/", http.MethodGet, StaticResponse(resp), WithReflectHeaders([]string{""}, false))
+		assert.Error(t, err)
+	})
+
+	serve := func(t *testing.T, override bool) http.Header {
+		t.Helper()
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp),
+			WithReflectHeaders([]string{"x-request-id", "X-Trace-Id", "X-Missing"}, override))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Add("X-Request-Id", "abc")
+		req.Header.Add("X-Request-Id", "def")
+		req.Header.Set("X-Trace-Id", "from-request")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	t.Run("keeps configured headers", func(t *testing.T) {
+		header := serve(t, false)
+		assert.Equal(t, []string{"abc", "def"}, header.Values("X-Request-Id"))
+		assert.Equal(t, "configured", header.Get("X-Trace-Id"))
+		assert.NotContains(t, header, "X-Missing")
+	})
+
+	t.Run("override", func(t *testing.T) {
+		header := serve(t, true)
+		assert.Equal(t, "from-request", header.Get("X-Trace-Id"))
+	})
+}
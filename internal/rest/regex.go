@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// WithPathRegex matches the endpoint's path as a regular expression against the whole request
+// path, rather than as a mux pattern. Capture groups are available as path values, by name for
+// named groups and by index for all groups.
+func WithPathRegex() EndpointOption {
+	return func(e *Endpoint) error {
+		re, err := compilePathRegex(e.Path)
+		if err != nil {
+			return err
+		}
+		e.pathRegex = re
+		return nil
+	}
+}
+
+func compilePathRegex(path string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + path + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("compile path regex: %w", err)
+	}
+	return re, nil
+}
+
+// RegexPathParams returns the path value names a regular expression path provides: every group's
+// index, with 0 being the whole match, and the names of named groups.
+func RegexPathParams(path string) ([]string, error) {
+	re, err := compilePathRegex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []string
+	for i, name := range re.SubexpNames() {
+		params = append(params, strconv.Itoa(i))
+		if name != "" {
+			params = append(params, name)
+		}
+	}
+	return params, nil
+}
+
+// RegexHandler serves requests the mux has no route for with the first endpoint whose path regex
+// and method match, trying endpoints in order. Requests matching no regex endpoint are passed to
+// next.
+func RegexHandler(next http.Handler, mux *http.ServeMux, endpoints []*Endpoint, opts ...HandlerOption) http.Handler {
+	options := newHandlerOptions(opts)
+
+	type regexRoute struct {
+		endpoint *Endpoint
+		handler  http.Handler
+	}
+	var routes []regexRoute
+	for _, endpoint := range endpoints {
+		if endpoint.pathRegex == nil {
+			continue
+		}
+		slog.Info("registering regex endpoint", "method", endpoint.Method, "path", endpoint.Path)
+		routes = append(routes, regexRoute{endpoint: endpoint, handler: options.handler(endpoint)})
+	}
+	if len(routes) == 0 {
+		return next
+	}
+	// like the mux, endpoints for a specific method take precedence over those for any method
+	slices.SortStableFunc(routes, func(a, b regexRoute) int {
+		return cmp.Compare(methodSpecificity(a.endpoint), methodSpecificity(b.endpoint))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, route := range routes {
+			if !route.endpoint.matchesMethod(r.Method) {
+				continue
+			}
+			groups := route.endpoint.pathRegex.FindStringSubmatch(r.URL.Path)
+			if groups == nil {
+				continue
+			}
+			for i, name := range route.endpoint.pathRegex.SubexpNames() {
+				r.SetPathValue(strconv.Itoa(i), groups[i])
+				if name != "" {
+					r.SetPathValue(name, groups[i])
+				}
+			}
+			route.handler.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func methodSpecificity(endpoint *Endpoint) int {
+	if endpoint.Method == "" {
+		return 1
+	}
+	return 0
+}
+
+// matchesMethod reports whether the endpoint serves requests with the given method. Like the mux,
+// endpoints for GET also serve HEAD.
+func (p *Endpoint) matchesMethod(method string) bool {
+	return p.Method == "" || p.Method == method || (p.Method == http.MethodGet && method == http.MethodHead)
+}
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexPathParams(t *testing.T) {
+	params, err := RegexPathParams(`/users/(?P<id>\d+)/files/(.+)`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "id", "2"}, params)
+
+	params, err = RegexPathParams(`/users/(`)
+	assert.Error(t, err)
+	assert.Nil(t, params)
+}
+
+func TestRegexHandler(t *testing.T) {
+	t.Run("invalid regex", func(t *testing.T) {
+		endpoint, err := NewEndpoint(`/users/(`, http.MethodGet, StaticResponse(Response{}), WithPathRegex())
+		assert.Error(t, err)
+		assert.Nil(t, endpoint)
+	})
+
+	path := `/users/(?P<id>\d+)/files/(.+)`
+	params, err := RegexPathParams(path)
+	require.NoError(t, err)
+	tmpl, err := NewResponse(WithResponseTemplate(`{{ .PathValue "id" }} {{ .PathValue "2" }} {{ .PathValue "0" }}`, params))
+	require.NoError(t, err)
+	files, err := NewEndpoint(path, http.MethodGet, StaticResponse(tmpl), WithPathRegex())
+	require.NoError(t, err)
+	anyUser, err := NewEndpoint(`/users/.*`, MethodAll, StaticResponse(Response{statusCode: http.StatusAccepted}), WithPathRegex())
+	require.NoError(t, err)
+	exact, err := NewEndpoint("/users/1/files/a.txt", http.MethodGet, StaticResponse(Response{statusCode: http.StatusCreated}))
+	require.NoError(t, err)
+	// the endpoint for any method is listed first, but specific methods take precedence
+	endpoints := []*Endpoint{anyUser, files, exact}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	notFound := Response{statusCode: http.StatusTeapot}
+	handler := RegexHandler(FallbackHandler(mux, Fallbacks{NotFound: &notFound}), mux, endpoints)
+
+	cases := map[string]struct {
+		method   string
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		"mux pattern takes precedence": {method: http.MethodGet, target: "/users/1/files/a.txt", wantCode: http.StatusCreated},
+		"capture groups":               {method: http.MethodGet, target: "/users/42/files/a/b.txt", wantCode: http.StatusOK, wantBody: "42 a/b.txt /users/42/files/a/b.txt"},
+		"head matches get":             {method: http.MethodHead, target: "/users/42/files/a", wantCode: http.StatusOK},
+		"any method":                   {method: http.MethodPost, target: "/users/42/files/a", wantCode: http.StatusAccepted},
+		"pattern method mismatch":      {method: http.MethodPost, target: "/users/1/files/a.txt", wantCode: http.StatusAccepted},
+		"whole path must match":        {method: http.MethodGet, target: "/v2/users/42", wantCode: http.StatusTeapot},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
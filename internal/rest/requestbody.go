@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// bufferedBody is a request body read into memory, replacing the request's body so it's read, and
+// decoded as JSON, at most once however many parts of the handler need it.
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+	err  error
+
+	decoded bool
+	json    any
+	jsonErr error
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// readBody reads the request's body, leaving it in place for later readers. Reading errors are
+// returned again to later readers too.
+func readBody(r *http.Request) ([]byte, error) {
+	if buffered, ok := r.Body.(*bufferedBody); ok {
+		buffered.Reset(buffered.data)
+		return buffered.data, buffered.err
+	}
+	if r.Body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	r.Body = &bufferedBody{Reader: bytes.NewReader(data), data: data, err: err}
+	return data, err
+}
+
+// bodySize returns the size of the request's body, or limit if it's at least limit bytes. A
+// declared Content-Length is trusted, otherwise, as for a chunked body, at most limit bytes are read
+// to measure it and left in place for later readers. A body cut off by the request size limit is
+// at least limit bytes, since the limit is normally far larger than any size matched on.
+func bodySize(r *http.Request, limit int64) (int64, error) {
+	if buffered, ok := r.Body.(*bufferedBody); ok {
+		if isMaxBytesError(buffered.err) {
+			return limit, nil
+		}
+		return min(int64(len(buffered.data)), limit), buffered.err
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return 0, nil
+	}
+	if r.ContentLength >= 0 {
+		return min(r.ContentLength, limit), nil
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, limit))
+	rest := io.Reader(r.Body)
+	if err != nil {
+		rest = errReader{err}
+	}
+	r.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(peeked), rest), Closer: r.Body}
+	if isMaxBytesError(err) {
+		return limit, nil
+	}
+	return int64(len(peeked)), err
+}
+
+func isMaxBytesError(err error) bool {
+	maxBytesErr := (*http.MaxBytesError)(nil)
+	return errors.As(err, &maxBytesErr)
+}
+
+// requestJSON returns the request's body decoded as JSON, or nil if it can't be read or isn't valid
+// JSON. Numbers are decoded as json.Number, so they render as sent.
+func requestJSON(r *http.Request) any {
+	v, _ := decodeRequestJSON(r)
+	return v
+}
+
+// decodeRequestJSON is requestJSON, returning why the body isn't valid JSON. The body is decoded
+// once per request, and shared by everything reading it as JSON.
+func decodeRequestJSON(r *http.Request) (any, error) {
+	if _, err := readBody(r); err != nil {
+		return nil, err
+	}
+	buffered, ok := r.Body.(*bufferedBody)
+	if !ok {
+		return decodeJSON(nil)
+	}
+	if !buffered.decoded {
+		buffered.decoded = true
+		buffered.json, buffered.jsonErr = decodeJSON(buffered.data)
+	}
+	return buffered.json, buffered.jsonErr
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+	// trailing data means the body isn't a single JSON value
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("parse JSON: unexpected data after top-level value")
+	}
+	return v, nil
+}
@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"context"
+	crand "crypto/rand"
+	"io"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header request IDs are read from and written to by default.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestIDHandler tags each request with an ID, reusing the one in the request's header if it has
+// one and generating a UUID otherwise. The ID is set in the response's header, logged, and available
+// to body templates as .RequestID. An empty header defaults to DefaultRequestIDHeader.
+func RequestIDHandler(next http.Handler, header string) http.Handler {
+	return requestIDHandler(next, header, crand.Reader)
+}
+
+func requestIDHandler(next http.Handler, header string, random io.Reader) http.Handler {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if id == "" {
+			id = uuidFrom(random)
+		}
+		w.Header().Set(header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the request's ID, or "" if it wasn't tagged with one.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	resp, err := NewResponse(WithResponseTemplate(`{{ .RequestID }}`, nil))
+	require.NoError(t, err)
+	endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], 1)
+	wantID := uuidFrom(rand.NewChaCha8(seed))
+
+	t.Run("generated", func(t *testing.T) {
+		handler := requestIDHandler(mux, "", rand.NewChaCha8(seed))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, wantID, rec.Header().Get(DefaultRequestIDHeader))
+		assert.Equal(t, wantID, rec.Body.String())
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, wantID)
+	})
+
+	t.Run("reused from request", func(t *testing.T) {
+		handler := requestIDHandler(mux, "X-Correlation-Id", rand.NewChaCha8(seed))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Correlation-Id", "abc-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, "abc-123", rec.Header().Get("X-Correlation-Id"))
+		assert.Equal(t, "abc-123", rec.Body.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, rec.Header().Get(DefaultRequestIDHeader))
+		assert.Empty(t, rec.Body.String())
+	})
+}
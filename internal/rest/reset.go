@@ -0,0 +1,54 @@
+package rest
+
+// countResetter is implemented by strategies holding a request count that can be reset, or
+// wrapping strategies that might.
+type countResetter interface {
+	// resetCount resets the count, reporting whether there was one to reset.
+	resetCount() bool
+}
+
+func resetResolverCount(resolver ResponseResolver) bool {
+	if c, ok := resolver.(countResetter); ok {
+		return c.resetCount()
+	}
+	return false
+}
+
+// ResetCount resets the request count of the endpoint's afterCount strategy, so the threshold's
+// responses start over, reporting whether the endpoint has one.
+func (p *Endpoint) ResetCount() bool {
+	return resetResolverCount(p.responseResolver)
+}
+
+func (t *ThresholdResponse) resetCount() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count = 0
+	return true
+}
+
+func (f *FaultResponse) resetCount() bool {
+	return resetResolverCount(f.resolver)
+}
+
+func (o *OutageResponse) resetCount() bool {
+	return resetResolverCount(o.resolver)
+}
+
+func (f *FallthroughResponse) resetCount() bool {
+	var reset bool
+	for _, resolver := range f.resolvers {
+		reset = resetResolverCount(resolver) || reset
+	}
+	return reset
+}
+
+func (p *PerClientResponse) resetCount() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reset := resetResolverCount(p.prototype)
+	for _, client := range p.clients {
+		reset = resetResolverCount(client.resolver) || reset
+	}
+	return reset
+}
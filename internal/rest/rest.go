@@ -3,27 +3,40 @@ package rest
 import (
 	"errors"
 	"fmt"
-	"log/slog"
+	"maps"
+	"math"
 	"math/rand/v2"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 )
 
 type ResponseResolver interface {
-	NextResponse() Response
+	// NextResponse returns the response for the given request.
+	NextResponse(r *http.Request) Response
 	// TODO consider adding "StrategyName" func or similar so we can include in logs when registering
 }
 
 type StaticResponse Response
 
-func (r StaticResponse) NextResponse() Response {
+func (r StaticResponse) NextResponse(_ *http.Request) Response {
 	return Response(r)
 }
 
+func (r StaticResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
+	return Response(r), responseChoice{strategy: "static"}
+}
+
 type numberGenerator interface {
 	// N returns an integer in the half-open interval [0, n).
 	N(n int) int
+	// Float64 returns a float in the half-open interval [0, 1).
+	Float64() float64
 }
 
 type rng struct{}
@@ -32,19 +45,60 @@ func (r rng) N(n int) int {
 	return rand.N(n)
 }
 
+func (r rng) Float64() float64 {
+	return rand.Float64()
+}
+
 type WeightedResponse struct {
 	numGenerator numberGenerator
 	responses    []Response
-	weights      []int
-	weightTotal  int
+	weights      []float64
+	weightTotal  float64
+	// fractional is set if any weight isn't a whole number, so rolls can't be made with integers.
+	fractional bool
+	ramp       *weightRamp
+	// limits caps how often each entry can be chosen, and is nil if no entry has a limit. Once an
+	// entry reaches its limit, it's dropped and weights and weightTotal are recomputed, guarded by mu.
+	limits []occurrenceLimit
+	mu     sync.Mutex
 }
 
+// occurrenceLimit counts the choices of a weighted entry that may only be chosen max times.
+type occurrenceLimit struct {
+	weight float64
+	// max is 0 for entries without a limit.
+	max   int
+	count int
+}
+
+func (l occurrenceLimit) exhausted() bool {
+	return l.max > 0 && l.count >= l.max
+}
+
+// weightRamp linearly shifts weights from start to end over duration.
+type weightRamp struct {
+	start     []int
+	end       []int
+	duration  time.Duration
+	startedAt time.Time
+	clock     clock
+}
+
+// rampScale is the number of steps a weight ramp is divided into.
+const rampScale = 1000
+
 type WeightedResponseEntry struct {
 	Response Response
-	Weight   int
+	// Weight may be fractional, such as a percentage like 12.5.
+	Weight float64
+	// MaxOccurrences drops the entry once it has been chosen this many times, with the remaining
+	// entries' weights renormalized. Zero means no limit.
+	MaxOccurrences int
 }
 
-// NewWeightedResponse builds a weighted response strategy from the given responses.
+// NewWeightedResponse builds a weighted response strategy from the given responses. Whole number
+// weights are rolled with integers, fractional weights with floats. If any entry has a
+// MaxOccurrences, at least one must have none, so there's always an entry left to choose.
 // If numGenerator is nil, a random source is used.
 func NewWeightedResponse(entries []WeightedResponseEntry, numGenerator numberGenerator) (*WeightedResponse, error) {
 	// entries imo makes more sense as a map, but switched to a slice so internal ordering is deterministic
@@ -56,17 +110,35 @@ func NewWeightedResponse(entries []WeightedResponseEntry, numGenerator numberGen
 		numGenerator = rng{}
 	}
 
-	var weightTotal int
+	var weightTotal float64
 	var responses []Response
-	var weights []int
+	var weights []float64
+	var fractional bool
+	var limits []occurrenceLimit
+	var limited, unlimited bool
 
 	for _, entry := range entries {
-		if entry.Weight <= 0 {
-			return nil, errors.New("weight must be >= 1")
+		if !(entry.Weight > 0) || math.IsInf(entry.Weight, 0) {
+			return nil, fmt.Errorf("weight must be positive: %v", entry.Weight)
+		}
+		if entry.MaxOccurrences < 0 {
+			return nil, fmt.Errorf("maxOccurrences must be >= 0: %d", entry.MaxOccurrences)
 		}
 		weightTotal += entry.Weight
 		weights = append(weights, weightTotal)
 		responses = append(responses, entry.Response)
+		fractional = fractional || entry.Weight != math.Trunc(entry.Weight)
+		limits = append(limits, occurrenceLimit{weight: entry.Weight, max: entry.MaxOccurrences})
+		limited = limited || entry.MaxOccurrences > 0
+		unlimited = unlimited || entry.MaxOccurrences == 0
+	}
+	if !fractional && weightTotal > math.MaxInt32 {
+		return nil, fmt.Errorf("weights total too large: %v", weightTotal)
+	}
+	if !limited {
+		limits = nil
+	} else if !unlimited {
+		return nil, errors.New("at least one weighted response must have no maxOccurrences")
 	}
 
 	return &WeightedResponse{
@@ -74,15 +146,112 @@ func NewWeightedResponse(entries []WeightedResponseEntry, numGenerator numberGen
 		responses:    responses,
 		weights:      weights,
 		weightTotal:  weightTotal,
+		fractional:   fractional,
+		limits:       limits,
 	}, nil
 }
 
-func (w *WeightedResponse) NextResponse() Response {
-	val := w.numGenerator.N(w.weightTotal)
+// NewRampedWeightedResponse builds a weighted response strategy whose weights shift linearly from
+// the entries' weights to endWeights over duration, starting now. Ramped weights must be whole
+// numbers. Weights may be zero during a ramp, but the start and end weights must each total at
+// least 1. If numGenerator is nil, a random source is used. If clk is nil, the system clock is
+// used.
+func NewRampedWeightedResponse(entries []WeightedResponseEntry, endWeights []int, duration time.Duration, numGenerator numberGenerator, clk clock) (*WeightedResponse, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("no weighted responses")
+	}
+	if len(endWeights) != len(entries) {
+		return nil, fmt.Errorf("ramp has %d end weights for %d responses", len(endWeights), len(entries))
+	}
+	if duration <= 0 {
+		return nil, errors.New("ramp duration must be positive")
+	}
+
+	if numGenerator == nil {
+		numGenerator = rng{}
+	}
+	if clk == nil {
+		clk = systemClock{}
+	}
+
+	var responses []Response
+	var startWeights []int
+	var startTotal, endTotal int
+	for i, entry := range entries {
+		if entry.Weight < 0 || endWeights[i] < 0 {
+			return nil, errors.New("ramp weights must be >= 0")
+		}
+		if entry.Weight != math.Trunc(entry.Weight) || entry.Weight > math.MaxInt32 {
+			return nil, fmt.Errorf("ramp weights must be whole numbers: %v", entry.Weight)
+		}
+		if entry.MaxOccurrences != 0 {
+			return nil, errors.New("maxOccurrences can't be used with a weight ramp")
+		}
+		startTotal += int(entry.Weight)
+		endTotal += endWeights[i]
+		startWeights = append(startWeights, int(entry.Weight))
+		responses = append(responses, entry.Response)
+	}
+	if startTotal == 0 || endTotal == 0 {
+		return nil, errors.New("ramp weights must total >= 1")
+	}
+
+	return &WeightedResponse{
+		numGenerator: numGenerator,
+		responses:    responses,
+		ramp: &weightRamp{
+			start:     startWeights,
+			end:       endWeights,
+			duration:  duration,
+			startedAt: clk.Now(),
+			clock:     clk,
+		},
+	}, nil
+}
+
+// weights returns the cumulative weights at the current point of the ramp, scaled by rampScale.
+func (r *weightRamp) weights() ([]float64, float64) {
+	elapsed := r.clock.Now().Sub(r.startedAt)
+	step := rampScale
+	if elapsed < r.duration {
+		step = int(max(elapsed, 0) * rampScale / r.duration)
+	}
+
+	weights := make([]float64, len(r.start))
+	var total int
+	for i := range r.start {
+		total += r.start[i]*(rampScale-step) + r.end[i]*step
+		weights[i] = float64(total)
+	}
+	return weights, float64(total)
+}
+
+func (w *WeightedResponse) NextResponse(r *http.Request) Response {
+	resp, _ := w.nextChosenResponse(r)
+	return resp
+}
+
+func (w *WeightedResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
+	if w.limits != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	weights, weightTotal := w.weights, w.weightTotal
+	if w.ramp != nil {
+		weights, weightTotal = w.ramp.weights()
+	}
 
-	for i, weight := range w.weights {
+	var val float64
+	if w.fractional {
+		val = w.numGenerator.Float64() * weightTotal
+	} else {
+		val = float64(w.numGenerator.N(int(weightTotal)))
+	}
+
+	for i, weight := range weights {
 		if val < weight {
-			return w.responses[i]
+			w.occur(i)
+			return w.responses[i], responseChoice{strategy: "weighted", index: i}
 		}
 	}
 
@@ -92,24 +261,71 @@ func (w *WeightedResponse) NextResponse() Response {
 	panic("number generator should always return a valid weight")
 }
 
+// occur counts a choice of entry i, dropping it from the weights once it reaches its limit. It's
+// called with mu held.
+func (w *WeightedResponse) occur(i int) {
+	if w.limits == nil || w.limits[i].max == 0 {
+		return
+	}
+	w.limits[i].count++
+	if w.limits[i].exhausted() {
+		w.reweigh()
+	}
+}
+
+// reweigh recomputes the cumulative weights from the limits, leaving exhausted entries with no
+// share. Summing from scratch, rather than subtracting the dropped weight, keeps an exhausted entry's
+// cumulative weight exactly equal to the one before it, so it can't be rolled.
+func (w *WeightedResponse) reweigh() {
+	var total float64
+	for i, limit := range w.limits {
+		if !limit.exhausted() {
+			total += limit.weight
+		}
+		w.weights[i] = total
+	}
+	w.weightTotal = total
+}
+
+func (w *WeightedResponse) responseAt(_ *http.Request, index int) (Response, responseChoice, bool) {
+	if index < 0 || index >= len(w.responses) {
+		return Response{}, responseChoice{}, false
+	}
+	return w.responses[index], responseChoice{strategy: "weighted", index: index}, true
+}
+
 type SequenceBehavior string
 
 const (
 	SequenceBehaviorLoop       SequenceBehavior = "loop"
 	SequenceBehaviorRepeatLast SequenceBehavior = "repeatLast"
+	// SequenceBehaviorEnd answers with a terminal response, such as a 410, once the sequence is done.
+	SequenceBehaviorEnd SequenceBehavior = "end"
 )
 
 type SequencedResponse struct {
 	endBehavior SequenceBehavior
 	sequence    []Response
+	// end is the terminal response of SequenceBehaviorEnd.
+	end *Response
 
 	idx int
 	mu  sync.Mutex
 }
 
-func NewSequencedResponse(endBehavior SequenceBehavior, sequence []Response) (*SequencedResponse, error) {
+// NewSequencedResponse builds a strategy returning the responses of sequence in order. end is the
+// terminal response returned after the sequence with SequenceBehaviorEnd, which requires it, and
+// must be nil with other behaviors.
+func NewSequencedResponse(endBehavior SequenceBehavior, sequence []Response, end *Response) (*SequencedResponse, error) {
 	switch endBehavior {
 	case SequenceBehaviorLoop, SequenceBehaviorRepeatLast:
+		if end != nil {
+			return nil, fmt.Errorf("sequence end response requires end behavior %q", SequenceBehaviorEnd)
+		}
+	case SequenceBehaviorEnd:
+		if end == nil {
+			return nil, fmt.Errorf("sequence end behavior %q requires an end response", SequenceBehaviorEnd)
+		}
 	default:
 		return nil, fmt.Errorf("unknown sequence end behavior %q", endBehavior)
 	}
@@ -120,58 +336,395 @@ func NewSequencedResponse(endBehavior SequenceBehavior, sequence []Response) (*S
 	sequencedResp := &SequencedResponse{
 		endBehavior: endBehavior,
 		sequence:    sequence,
+		end:         end,
 	}
 	return sequencedResp, nil
 }
 
-func (s *SequencedResponse) NextResponse() Response {
+func (s *SequencedResponse) NextResponse(r *http.Request) Response {
+	resp, _ := s.nextChosenResponse(r)
+	return resp
+}
+
+func (s *SequencedResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	choice := responseChoice{strategy: "sequence", index: s.idx}
+	if s.idx == len(s.sequence) { // ended, only reached with SequenceBehaviorEnd
+		return *s.end, choice
+	}
 	resp := s.sequence[s.idx]
-	if s.idx < len(s.sequence)-1 { // have remaining sequence
+	if s.idx < len(s.sequence)-1 || s.endBehavior == SequenceBehaviorEnd { // have remaining sequence
 		s.idx++
 	} else if s.idx >= len(s.sequence)-1 && s.endBehavior == SequenceBehaviorLoop {
 		s.idx++
 		s.idx %= len(s.sequence)
 	}
 
+	return resp, choice
+}
+
+// responseAt indexes the end response after the sequence.
+func (s *SequencedResponse) responseAt(_ *http.Request, index int) (Response, responseChoice, bool) {
+	if index == len(s.sequence) && s.end != nil {
+		return *s.end, responseChoice{strategy: "sequence", index: index}, true
+	}
+	if index < 0 || index >= len(s.sequence) {
+		return Response{}, responseChoice{}, false
+	}
+	return s.sequence[index], responseChoice{strategy: "sequence", index: index}, true
+}
+
+// ThresholdResponse returns one response for the first threshold requests and another for every
+// request after.
+type ThresholdResponse struct {
+	threshold int
+	before    Response
+	after     Response
+
+	count int
+	mu    sync.Mutex
+}
+
+func NewThresholdResponse(threshold int, before, after Response) (*ThresholdResponse, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be >= 1: %d", threshold)
+	}
+
+	return &ThresholdResponse{
+		threshold: threshold,
+		before:    before,
+		after:     after,
+	}, nil
+}
+
+func (t *ThresholdResponse) NextResponse(r *http.Request) Response {
+	resp, _ := t.nextChosenResponse(r)
 	return resp
 }
 
+func (t *ThresholdResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.count < t.threshold {
+		t.count++
+		return t.before, responseChoice{strategy: "afterCount", index: 0}
+	}
+	return t.after, responseChoice{strategy: "afterCount", index: 1}
+}
+
+func (t *ThresholdResponse) responseAt(_ *http.Request, index int) (Response, responseChoice, bool) {
+	switch index {
+	case 0:
+		return t.before, responseChoice{strategy: "afterCount", index: 0}, true
+	case 1:
+		return t.after, responseChoice{strategy: "afterCount", index: 1}, true
+	}
+	return Response{}, responseChoice{}, false
+}
+
 type Endpoint struct {
 	Path             string
 	Method           string
 	responseResolver ResponseResolver
+	rateLimiter      *rateLimiter
+	// concurrencyLimiter is held while a request is resolved and written.
+	concurrencyLimiter *concurrencyLimiter
+	requiredQuery      *requiredQuery
+	requestSchema      *requestSchema
+	reflectHeaders     *reflectHeaders
+	rawHeaderCase      bool
+	closeConnection    bool
+	capture            *requestCapture
+	expectContinue     *ExpectContinue
+	etag               bool
+	lastModified       time.Time
+	middleware         []Middleware
+	responseLimit      *responseLimit
+	pathRegex          *regexp.Regexp
+	// noMethodFallthrough answers requests for the endpoint's path with a 405 when no endpoint for
+	// the path serves their method, see WithoutMethodFallthrough.
+	noMethodFallthrough bool
+
+	id               string
+	disabledResponse Response
+
+	// hits counts the requests the endpoint has handled.
+	hits atomic.Int64
+	// stats tracks the latency and errors of the requests the endpoint has handled.
+	stats endpointStats
+	// disabled is toggled at runtime through the admin API.
+	disabled atomic.Bool
+}
+
+// responseLimit truncates response bodies to a fixed size.
+type responseLimit struct {
+	maxBytes   int
+	fullLength bool
+}
+
+type EndpointOption func(*Endpoint) error
+
+// WithRateLimit throttles the endpoint, returning the limit's response once the rate is exceeded.
+func WithRateLimit(limit RateLimit) EndpointOption {
+	return func(e *Endpoint) error {
+		limiter, err := newRateLimiter(limit)
+		if err != nil {
+			return fmt.Errorf("rate limit: %w", err)
+		}
+		e.rateLimiter = limiter
+		return nil
+	}
 }
 
-func NewEndpoint(path, method string, respResolver ResponseResolver) *Endpoint {
-	return &Endpoint{
+// WithMaxResponseBytes truncates response bodies to maxBytes. If fullLength is set, Content-Length
+// still advertises the full body and the connection is closed after the truncated body, simulating
+// a misbehaving server.
+func WithMaxResponseBytes(maxBytes int, fullLength bool) EndpointOption {
+	return func(e *Endpoint) error {
+		if maxBytes < 0 {
+			return errors.New("max response bytes cannot be negative")
+		}
+		e.responseLimit = &responseLimit{maxBytes: maxBytes, fullLength: fullLength}
+		return nil
+	}
+}
+
+// limit applies the limit to resp, if its body is too large.
+func (l *responseLimit) limit(resp Response) Response {
+	if l == nil || len(resp.body) <= l.maxBytes {
+		return resp
+	}
+	if !l.fullLength {
+		resp.body = resp.body[:l.maxBytes]
+		return resp
+	}
+	if resp.connFault != ConnectionFaultTruncate || resp.truncateBytes > l.maxBytes {
+		resp.connFault = ConnectionFaultTruncate
+		resp.truncateBytes = l.maxBytes
+	}
+	return resp
+}
+
+// WithRawHeaderCase sends response header names with the exact casing they were configured with,
+// such as x-custom, rather than canonicalizing them, to test clients sensitive to header casing.
+// HTTP/2 always sends header names in lowercase.
+func WithRawHeaderCase() EndpointOption {
+	return func(e *Endpoint) error {
+		e.rawHeaderCase = true
+		return nil
+	}
+}
+
+// WithCloseConnection sends Connection: close with every response, so the client can't reuse the
+// connection for its next request.
+func WithCloseConnection() EndpointOption {
+	return func(e *Endpoint) error {
+		e.closeConnection = true
+		return nil
+	}
+}
+
+// WithID sets the ID the endpoint is referred to by in the admin API, in place of its pattern.
+func WithID(id string) EndpointOption {
+	return func(e *Endpoint) error {
+		if id == "" {
+			return errors.New("id cannot be empty")
+		}
+		e.id = id
+		return nil
+	}
+}
+
+// WithDisabledResponse sets the response returned while the endpoint is disabled, in place of a 503.
+func WithDisabledResponse(resp Response) EndpointOption {
+	return func(e *Endpoint) error {
+		e.disabledResponse = resp
+		return nil
+	}
+}
+
+// MethodAll explicitly matches requests with any method. Endpoints for a specific method take
+// precedence over one for all methods on the same path.
+const MethodAll = "ALL"
+
+// NormalizeMethod returns the method an endpoint is registered with, which is empty for endpoints
+// matching any method.
+func NormalizeMethod(method string) string {
+	if method == "*" || strings.EqualFold(method, MethodAll) {
+		return ""
+	}
+	return method
+}
+
+func NewEndpoint(path, method string, respResolver ResponseResolver, opts ...EndpointOption) (*Endpoint, error) {
+	endpoint := &Endpoint{
 		Path:             path,
-		Method:           method,
+		Method:           NormalizeMethod(method),
 		responseResolver: respResolver,
+		disabledResponse: Response{statusCode: http.StatusServiceUnavailable},
+	}
+
+	for _, opt := range opts {
+		if err := opt(endpoint); err != nil {
+			return nil, fmt.Errorf("apply endpoint option: %w", err)
+		}
+	}
+	if endpoint.noMethodFallthrough && (endpoint.Method == "" || endpoint.pathRegex != nil) {
+		return nil, errors.New("method fallthrough can only be disabled for endpoints with a method and a pattern path")
 	}
+
+	return endpoint, nil
 }
 
 // Response yields the next response that should be returned when the endpoint is hit.
-func (p *Endpoint) Response() Response {
-	return p.responseResolver.NextResponse()
+func (p *Endpoint) Response(r *http.Request) Response {
+	resp, _ := p.chosenResponse(r)
+	return resp
+}
+
+// ID returns the endpoint's configured ID, defaulting to its mux pattern, e.g. "GET /users/{id}".
+func (p *Endpoint) ID() string {
+	if p.id != "" {
+		return p.id
+	}
+	return p.pattern()
+}
+
+func (p *Endpoint) pattern() string {
+	if p.Method == "" {
+		return p.Path
+	}
+	return fmt.Sprintf("%s %s", p.Method, p.Path)
+}
+
+// SetEnabled enables or disables the endpoint. Disabled endpoints return their disabled response.
+func (p *Endpoint) SetEnabled(enabled bool) {
+	p.disabled.Store(!enabled)
+}
+
+func (p *Endpoint) chosenResponse(r *http.Request) (Response, responseChoice) {
+	if resp, choice, ok := p.guardResponse(r); ok {
+		return resp, choice
+	}
+	return nextChosenResponse(p.responseResolver, r)
+}
+
+// chosenResponseAt is like chosenResponse, but picks the strategy's response at index. It reports
+// false if the strategy can't pick responses by index or index is out of range.
+func (p *Endpoint) chosenResponseAt(r *http.Request, index int) (Response, responseChoice, bool) {
+	if resp, choice, ok := p.guardResponse(r); ok {
+		return resp, choice, true
+	}
+	return responseAt(p.responseResolver, r, index)
+}
+
+// guardResponse returns the response for requests stopped before reaching the endpoint's strategy,
+// reporting false if the request isn't stopped.
+func (p *Endpoint) guardResponse(r *http.Request) (Response, responseChoice, bool) {
+	if p.disabled.Load() {
+		return p.disabledResponse, responseChoice{strategy: "disabled"}, true
+	}
+	if p.rateLimiter != nil && !p.rateLimiter.allow() {
+		return p.rateLimiter.response, responseChoice{strategy: "rateLimit"}, true
+	}
+	if p.requiredQuery != nil {
+		if resp, missing := p.requiredQuery.check(r); missing {
+			return resp, responseChoice{strategy: "requiredQuery"}, true
+		}
+	}
+	if p.requestSchema != nil {
+		if resp, invalid := p.requestSchema.check(r); invalid {
+			return resp, responseChoice{strategy: "requestSchema"}, true
+		}
+	}
+	return Response{}, responseChoice{}, false
 }
 
 type ResponseOption func(*Response) error
 
 type Response struct {
-	headers    map[string]string
-	body       []byte
-	statusCode int
-	delay      time.Duration
+	headers map[string]string
+	// multiHeaders are headers with multiple values, each written as its own header line.
+	multiHeaders map[string][]string
+	// headerTemplates are headers rendered per request, by name.
+	headerTemplates map[string][]*template.Template
+	body            []byte
+	file            *fileBody
+	dir             *bodyDir
+	// missingFileStatus replaces the response when its watched body file was removed.
+	missingFileStatus int
+	generated         *generatedBody
+	statusCode        int
+	delay             time.Duration
+	delaySampler      *delaySampler
+	ttfb              time.Duration
+	throttle          int
+	chunkSize         int
+	template          *template.Template
+	stream            bodyStream
+	websocket         *webSocketBehavior
+	staticDir         *staticDir
+	compression       *compression
+
+	connFault     ConnectionFault
+	truncateBytes int
 }
 
+// WithResponseHeaders sets the response's headers. The map is copied, so a config reusing one
+// map for several responses, as a YAML alias does, can't have them change each other.
 func WithResponseHeaders(headers map[string]string) ResponseOption {
 	return func(r *Response) error {
-		r.headers = headers
+		r.headers = maps.Clone(headers)
+		return nil
+	}
+}
+
+// WithResponseHeadersMulti sets headers that repeat, such as Set-Cookie or Link, with each value
+// written as its own header line. They replace any single valued header of the same name.
+func WithResponseHeadersMulti(headers map[string][]string) ResponseOption {
+	return func(r *Response) error {
+		r.multiHeaders = maps.Clone(headers)
+		for name, vals := range r.multiHeaders {
+			r.multiHeaders[name] = slices.Clone(vals)
+		}
+		return nil
+	}
+}
+
+// setHeaders sets the response's headers on h, rendering any header templates for req. With
+// rawCase, header names keep the casing they were configured with rather than being canonicalized.
+func (r Response) setHeaders(h http.Header, req *http.Request, rawCase bool) error {
+	set := func(name string, vals []string) {
+		canonical := http.CanonicalHeaderKey(name)
+		if !rawCase || canonical == name {
+			h[canonical] = vals
+			return
+		}
+		// a nil value stops the server adding its own header under the canonical name, such as a
+		// sniffed Content-Type
+		h[canonical] = nil
+		h[name] = vals
+	}
+	for header, val := range r.headers {
+		set(header, []string{val})
+	}
+	for header, vals := range r.multiHeaders {
+		set(header, slices.Clone(vals))
+	}
+	if len(r.headerTemplates) == 0 {
 		return nil
 	}
+	rendered, err := r.renderHeaders(req)
+	if err != nil {
+		return err
+	}
+	for header, vals := range rendered {
+		set(header, vals)
+	}
+	return nil
 }
 
 func WithResponseBody(body []byte) ResponseOption {
@@ -191,12 +744,49 @@ func WithResponseStatus(statusCode int) ResponseOption {
 	}
 }
 
+// Delayed returns a copy of the response with its delay replaced by a fixed delay.
+func (r Response) Delayed(delay time.Duration) Response {
+	r.delay = delay
+	r.delaySampler = nil
+	return r
+}
+
 func WithResponseDelay(delay time.Duration) ResponseOption {
 	return func(r *Response) error {
 		if delay < 0 {
 			return errors.New("delay cannot be negative")
 		}
 		r.delay = delay
+		r.delaySampler = nil
+		return nil
+	}
+}
+
+// WithConnectionFault replaces the response with a fault at the connection level.
+// Use WithTruncatedBody for truncation, which requires a byte count.
+func WithConnectionFault(fault ConnectionFault) ResponseOption {
+	return func(r *Response) error {
+		switch fault {
+		case ConnectionFaultReset:
+		case ConnectionFaultTruncate:
+			return errors.New("truncate fault requires a byte count")
+		default:
+			return fmt.Errorf("unknown connection fault %q", fault)
+		}
+		r.connFault = fault
+		return nil
+	}
+}
+
+// WithTruncatedBody writes only the first n bytes of the body before closing the connection.
+// The Content-Length header still advertises the full body.
+func WithTruncatedBody(n int) ResponseOption {
+	return func(r *Response) error {
+		if n < 0 {
+			return errors.New("truncated bytes cannot be negative")
+		}
+		r.connFault = ConnectionFaultTruncate
+		r.truncateBytes = n
 		return nil
 	}
 }
@@ -213,43 +803,28 @@ func NewResponse(opts ...ResponseOption) (Response, error) {
 	if resp.statusCode == 0 {
 		resp.statusCode = http.StatusOK
 	}
+	if resp.hasBody() && !bodyAllowedForStatus(resp.statusCode) {
+		return Response{}, fmt.Errorf("status %d cannot have a body", resp.statusCode)
+	}
+	if err := resp.precompress(); err != nil {
+		return Response{}, fmt.Errorf("compress body: %w", err)
+	}
 
 	return resp, nil
 }
 
-type httpMux interface {
-	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+// hasBody reports whether the response is configured to write a body.
+func (r Response) hasBody() bool {
+	return len(r.body) > 0 || r.file != nil || r.dir != nil || r.template != nil || r.generated != nil || r.stream != nil
 }
 
-// RegisterHandlers registers endpoint handlers to the given HTTP mux.
-func RegisterHandlers(mux httpMux, endpoints []*Endpoint) {
-	for _, endpoint := range endpoints {
-		slog.Info("registering endpoint", "method", endpoint.Method, "path", endpoint.Path)
-		pattern := endpoint.Path
-		if endpoint.Method != "" {
-			pattern = fmt.Sprintf("%s %s", endpoint.Method, pattern)
-		}
-		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-			slog.Info("handling request",
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("addr", r.RemoteAddr),
-			)
-
-			resp := endpoint.Response()
-
-			if resp.delay != 0 {
-				time.Sleep(resp.delay)
-			}
-
-			for header, val := range resp.headers {
-				w.Header().Set(header, val)
-			}
-			w.WriteHeader(resp.statusCode)
-			if _, err := w.Write(resp.body); err != nil {
-				slog.Warn("failed to write response", "err", err)
-				return
-			}
-		})
+// bodyAllowedForStatus reports whether HTTP allows a response with the status to have a body.
+func bodyAllowedForStatus(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode < 200:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
 	}
+	return true
 }
@@ -1,7 +1,15 @@
 package rest
 
 import (
+	"io"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -49,6 +57,36 @@ func TestNewResponse(t *testing.T) {
 				body:       []byte("user created"),
 			},
 		},
+		"body with no content status": {
+			opts: []ResponseOption{
+				WithResponseStatus(http.StatusNoContent),
+				WithResponseBody([]byte("user created")),
+			},
+			wantErr: true,
+		},
+		"body with not modified status": {
+			opts: []ResponseOption{
+				WithResponseBody([]byte("user created")),
+				WithResponseStatus(http.StatusNotModified),
+			},
+			wantErr: true,
+		},
+		"body with informational status": {
+			opts: []ResponseOption{
+				WithResponseStatus(http.StatusEarlyHints),
+				WithResponseTemplate("early", nil),
+			},
+			wantErr: true,
+		},
+		"no content without body": {
+			opts: []ResponseOption{
+				WithResponseStatus(http.StatusNoContent),
+				WithResponseBody(nil),
+			},
+			want: Response{
+				statusCode: http.StatusNoContent,
+			},
+		},
 		"headers": {
 			opts: []ResponseOption{
 				WithResponseHeaders(map[string]string{
@@ -122,6 +160,20 @@ func TestNewResponse(t *testing.T) {
 	}
 }
 
+func TestResponseHeadersCopied(t *testing.T) {
+	headers := map[string]string{"X-Shared": "shared"}
+	multi := map[string][]string{"Link": {"<a>", "<b>"}}
+	resp, err := NewResponse(WithResponseHeaders(headers), WithResponseHeadersMulti(multi))
+	require.NoError(t, err)
+
+	headers["X-Shared"] = "changed"
+	headers["X-Added"] = "added"
+	multi["Link"][0] = "<changed>"
+
+	assert.Equal(t, map[string]string{"X-Shared": "shared"}, resp.headers)
+	assert.Equal(t, map[string][]string{"Link": {"<a>", "<b>"}}, resp.multiHeaders)
+}
+
 func TestStaticResponse(t *testing.T) {
 	resp := Response{
 		headers: map[string]string{
@@ -135,20 +187,20 @@ func TestStaticResponse(t *testing.T) {
 
 	// Prove same response is returned each time
 	for range 5 {
-		got := strategy.NextResponse()
+		got := strategy.NextResponse(nil)
 		assert.Equal(t, resp, got)
 	}
 }
 
 func TestSequencedResponse(t *testing.T) {
 	t.Run("nil sequence", func(t *testing.T) {
-		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, nil)
+		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, nil, nil)
 		assert.Error(t, err)
 		assert.Nil(t, strategy)
 	})
 
 	t.Run("empty sequence", func(t *testing.T) {
-		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{})
+		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{}, nil)
 		assert.Error(t, err)
 		assert.Nil(t, strategy)
 	})
@@ -159,7 +211,7 @@ func TestSequencedResponse(t *testing.T) {
 				body: []byte("unused"),
 			},
 		}
-		strategy, err := NewSequencedResponse(SequenceBehavior("invalid"), responses)
+		strategy, err := NewSequencedResponse(SequenceBehavior("invalid"), responses, nil)
 		assert.Error(t, err)
 		assert.Nil(t, strategy)
 	})
@@ -168,12 +220,12 @@ func TestSequencedResponse(t *testing.T) {
 		resp := Response{
 			statusCode: http.StatusNotFound,
 		}
-		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{resp})
+		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{resp}, nil)
 		require.NoError(t, err)
 		require.NotNil(t, strategy)
 
 		for range 5 {
-			assert.Equal(t, resp, strategy.NextResponse())
+			assert.Equal(t, resp, strategy.NextResponse(nil))
 		}
 	})
 
@@ -182,12 +234,12 @@ func TestSequencedResponse(t *testing.T) {
 			statusCode: http.StatusGatewayTimeout,
 			body:       []byte("gateway timed out"),
 		}
-		strategy, err := NewSequencedResponse(SequenceBehaviorRepeatLast, []Response{resp})
+		strategy, err := NewSequencedResponse(SequenceBehaviorRepeatLast, []Response{resp}, nil)
 		require.NoError(t, err)
 		require.NotNil(t, strategy)
 
 		for range 5 {
-			assert.Equal(t, resp, strategy.NextResponse())
+			assert.Equal(t, resp, strategy.NextResponse(nil))
 		}
 	})
 
@@ -198,12 +250,12 @@ func TestSequencedResponse(t *testing.T) {
 		second := Response{
 			statusCode: http.StatusNotFound,
 		}
-		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second})
+		strategy, err := NewSequencedResponse(SequenceBehaviorLoop, []Response{first, second}, nil)
 		require.NoError(t, err)
 		require.NotNil(t, strategy)
 
 		for i := range 10 {
-			got := strategy.NextResponse()
+			got := strategy.NextResponse(nil)
 			if i%2 == 0 {
 				assert.Equal(t, first, got)
 			} else {
@@ -223,26 +275,118 @@ func TestSequencedResponse(t *testing.T) {
 			body: []byte("third response"),
 		}
 		responses := []Response{first, second, third}
-		strategy, err := NewSequencedResponse(SequenceBehaviorRepeatLast, responses)
+		strategy, err := NewSequencedResponse(SequenceBehaviorRepeatLast, responses, nil)
 		require.NoError(t, err)
 		require.NotNil(t, strategy)
 
-		assert.Equal(t, first, strategy.NextResponse())
-		assert.Equal(t, second, strategy.NextResponse())
+		assert.Equal(t, first, strategy.NextResponse(nil))
+		assert.Equal(t, second, strategy.NextResponse(nil))
 		for range 5 {
-			assert.Equal(t, third, strategy.NextResponse())
+			assert.Equal(t, third, strategy.NextResponse(nil))
 		}
 	})
+
+	t.Run("end response", func(t *testing.T) {
+		first := Response{statusCode: http.StatusOK}
+		second := Response{statusCode: http.StatusAccepted}
+		gone := Response{statusCode: http.StatusGone}
+		strategy, err := NewSequencedResponse(SequenceBehaviorEnd, []Response{first, second}, &gone)
+		require.NoError(t, err)
+
+		assert.Equal(t, first, strategy.NextResponse(nil))
+		assert.Equal(t, second, strategy.NextResponse(nil))
+		for range 5 {
+			assert.Equal(t, gone, strategy.NextResponse(nil))
+		}
+
+		got, _, ok := strategy.responseAt(nil, 2)
+		assert.True(t, ok)
+		assert.Equal(t, gone, got)
+		assert.Equal(t, first, freshResolver(strategy).NextResponse(nil), "copies start over")
+	})
+
+	t.Run("end response mismatched with behavior", func(t *testing.T) {
+		_, err := NewSequencedResponse(SequenceBehaviorEnd, []Response{{}}, nil)
+		assert.Error(t, err, "end behavior without a response")
+		_, err = NewSequencedResponse(SequenceBehaviorRepeatLast, []Response{{}}, &Response{})
+		assert.Error(t, err, "response without end behavior")
+	})
+}
+
+func TestThresholdResponse(t *testing.T) {
+	before := Response{statusCode: http.StatusOK}
+	after := Response{statusCode: http.StatusTooManyRequests}
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		for _, threshold := range []int{-1, 0} {
+			strategy, err := NewThresholdResponse(threshold, before, after)
+			assert.Error(t, err)
+			assert.Nil(t, strategy)
+		}
+	})
+
+	t.Run("switches after threshold", func(t *testing.T) {
+		strategy, err := NewThresholdResponse(3, before, after)
+		require.NoError(t, err)
+
+		for range 3 {
+			assert.Equal(t, before, strategy.NextResponse(nil))
+		}
+		for range 5 {
+			assert.Equal(t, after, strategy.NextResponse(nil))
+		}
+	})
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		strategy, err := NewThresholdResponse(50, before, after)
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var beforeCount int
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				for range 10 {
+					if strategy.NextResponse(nil).statusCode == before.statusCode {
+						mu.Lock()
+						beforeCount++
+						mu.Unlock()
+					}
+				}
+			})
+		}
+		wg.Wait()
+
+		assert.Equal(t, 50, beforeCount)
+	})
 }
 
 type mockNumGenerator struct {
-	val int
+	val   int
+	float float64
 }
 
 func (f *mockNumGenerator) N(_ int) int {
 	return f.val
 }
 
+func (f *mockNumGenerator) Float64() float64 {
+	return f.float
+}
+
+// syncNumGenerator is a mockNumGenerator that can be set while a server goroutine reads it.
+type syncNumGenerator struct {
+	val atomic.Int64
+}
+
+func (f *syncNumGenerator) N(_ int) int {
+	return int(f.val.Load())
+}
+
+func (f *syncNumGenerator) Float64() float64 {
+	return 0
+}
+
 func TestWeightedResponse(t *testing.T) {
 	t.Run("nil responses", func(t *testing.T) {
 		strategy, err := NewWeightedResponse(nil, nil)
@@ -277,7 +421,7 @@ func TestWeightedResponse(t *testing.T) {
 
 		// Don't make assertions around rng but verify that *something* is returned
 		for range 10 {
-			assert.NotZero(t, strategy.NextResponse())
+			assert.NotZero(t, strategy.NextResponse(nil))
 		}
 	})
 
@@ -314,7 +458,7 @@ func TestWeightedResponse(t *testing.T) {
 			statusCode: http.StatusOK,
 			body:       []byte("foo bar baz"),
 		}
-		weight := 5
+		const weight = 5
 		entries := []WeightedResponseEntry{
 			{
 				Response: resp,
@@ -329,7 +473,7 @@ func TestWeightedResponse(t *testing.T) {
 		// for all possible weight values, same resp is returned
 		for i := range weight {
 			numberGen.val = i
-			got := strategy.NextResponse()
+			got := strategy.NextResponse(nil)
 			assert.Equal(t, resp, got)
 		}
 	})
@@ -365,15 +509,106 @@ func TestWeightedResponse(t *testing.T) {
 
 		var i int
 		for _, entry := range entries {
-			for range entry.Weight {
+			for range int(entry.Weight) {
 				numberGen.val = i
-				got := strategy.NextResponse()
+				got := strategy.NextResponse(nil)
 				assert.Equal(t, entry.Response, got)
 				i++
 			}
 		}
 	})
 
+	t.Run("fractional weights", func(t *testing.T) {
+		entries := []WeightedResponseEntry{
+			{Response: Response{statusCode: http.StatusOK}, Weight: 12.5},
+			{Response: Response{statusCode: http.StatusBadRequest}, Weight: 87.5},
+		}
+		numberGen := &mockNumGenerator{}
+		strategy, err := NewWeightedResponse(entries, numberGen)
+		require.NoError(t, err)
+
+		cases := map[float64]Response{
+			0:      entries[0].Response,
+			0.1249: entries[0].Response,
+			0.125:  entries[1].Response,
+			0.9999: entries[1].Response,
+		}
+		for float, want := range cases {
+			numberGen.float = float
+			assert.Equal(t, want, strategy.NextResponse(nil), float)
+		}
+	})
+
+	t.Run("invalid fractional weight", func(t *testing.T) {
+		for _, weight := range []float64{-0.5, math.NaN(), math.Inf(1)} {
+			_, err := NewWeightedResponse([]WeightedResponseEntry{{Weight: weight}}, nil)
+			assert.Error(t, err, weight)
+		}
+	})
+
+	t.Run("max occurrences", func(t *testing.T) {
+		flaky := Response{statusCode: http.StatusServiceUnavailable}
+		healthy := Response{statusCode: http.StatusOK}
+		entries := []WeightedResponseEntry{
+			{Response: flaky, Weight: 3, MaxOccurrences: 2},
+			{Response: healthy, Weight: 1},
+		}
+		numberGen := &mockNumGenerator{}
+		strategy, err := NewWeightedResponse(entries, numberGen)
+		require.NoError(t, err)
+
+		assert.Equal(t, flaky, strategy.NextResponse(nil))
+		assert.Equal(t, 4.0, strategy.weightTotal)
+		assert.Equal(t, flaky, strategy.NextResponse(nil))
+		assert.Equal(t, 1.0, strategy.weightTotal, "exhausted entries are dropped")
+		for range 5 {
+			assert.Equal(t, healthy, strategy.NextResponse(nil))
+		}
+
+		shares := strategy.weightShares()
+		assert.Zero(t, shares[0].Probability)
+		assert.Equal(t, 1.0, shares[1].Probability)
+
+		fresh := freshResolver(strategy)
+		assert.Equal(t, flaky, fresh.NextResponse(nil), "copies count separately")
+		assert.Equal(t, healthy, strategy.NextResponse(nil))
+	})
+
+	t.Run("max occurrences concurrently", func(t *testing.T) {
+		entries := []WeightedResponseEntry{
+			{Response: Response{statusCode: http.StatusServiceUnavailable}, Weight: 1, MaxOccurrences: 50},
+			{Response: Response{statusCode: http.StatusOK}, Weight: 1},
+		}
+		strategy, err := NewWeightedResponse(entries, nil)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		var failures atomic.Int64
+		for range 20 {
+			wg.Go(func() {
+				for range 50 {
+					if strategy.NextResponse(nil).statusCode == http.StatusServiceUnavailable {
+						failures.Add(1)
+					}
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, int64(50), failures.Load(), "the entry is chosen exactly its limit")
+	})
+
+	t.Run("invalid max occurrences", func(t *testing.T) {
+		_, err := NewWeightedResponse([]WeightedResponseEntry{{Weight: 1, MaxOccurrences: -1}}, nil)
+		assert.Error(t, err, "negative")
+		_, err = NewWeightedResponse([]WeightedResponseEntry{
+			{Weight: 1, MaxOccurrences: 1},
+			{Weight: 1, MaxOccurrences: 2},
+		}, nil)
+		assert.Error(t, err, "every entry limited")
+		_, err = NewRampedWeightedResponse([]WeightedResponseEntry{{Weight: 1, MaxOccurrences: 1}, {Weight: 1}}, []int{1, 1}, time.Minute, nil, nil)
+		assert.Error(t, err, "ramped")
+	})
+
 	t.Run("panics when invariant broken", func(t *testing.T) {
 		entries := []WeightedResponseEntry{
 			{
@@ -389,7 +624,332 @@ func TestWeightedResponse(t *testing.T) {
 		require.NotNil(t, strategy)
 
 		assert.Panics(t, func() {
-			_ = strategy.NextResponse()
+			_ = strategy.NextResponse(nil)
 		})
 	})
 }
+
+func TestRampedWeightedResponse(t *testing.T) {
+	healthy := Response{statusCode: http.StatusOK}
+	unhealthy := Response{statusCode: http.StatusInternalServerError}
+	entries := []WeightedResponseEntry{
+		{Response: healthy, Weight: 90},
+		{Response: unhealthy, Weight: 10},
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]struct {
+			entries    []WeightedResponseEntry
+			endWeights []int
+			duration   time.Duration
+		}{
+			"no entries":         {endWeights: []int{1}, duration: time.Minute},
+			"end weights count":  {entries: entries, endWeights: []int{50}, duration: time.Minute},
+			"zero duration":      {entries: entries, endWeights: []int{50, 50}},
+			"negative weight":    {entries: entries, endWeights: []int{-1, 50}, duration: time.Minute},
+			"zero end total":     {entries: entries, endWeights: []int{0, 0}, duration: time.Minute},
+			"zero start total":   {entries: []WeightedResponseEntry{{Weight: 0}}, endWeights: []int{1}, duration: time.Minute},
+			"negative start val": {entries: []WeightedResponseEntry{{Weight: -1}, {Weight: 2}}, endWeights: []int{1, 1}, duration: time.Minute},
+			"fractional weight":  {entries: []WeightedResponseEntry{{Weight: 0.5}, {Weight: 2}}, endWeights: []int{1, 1}, duration: time.Minute},
+		}
+		for name, tc := range cases {
+			strategy, err := NewRampedWeightedResponse(tc.entries, tc.endWeights, tc.duration, nil, nil)
+			assert.Error(t, err, name)
+			assert.Nil(t, strategy, name)
+		}
+	})
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := &mockClock{now: start}
+	numGen := &mockNumGenerator{}
+	strategy, err := NewRampedWeightedResponse(entries, []int{50, 50}, 10*time.Minute, numGen, clk)
+	require.NoError(t, err)
+
+	// weights are scaled by rampScale, so the healthy share of 100_000 is the cutoff
+	cases := map[string]struct {
+		elapsed       time.Duration
+		healthyCutoff int
+	}{
+		"start":              {elapsed: 0, healthyCutoff: 90_000},
+		"halfway":            {elapsed: 5 * time.Minute, healthyCutoff: 70_000},
+		"just before end":    {elapsed: 10*time.Minute - time.Nanosecond, healthyCutoff: 50_040},
+		"end":                {elapsed: 10 * time.Minute, healthyCutoff: 50_000},
+		"after end":          {elapsed: time.Hour, healthyCutoff: 50_000},
+		"clock before start": {elapsed: -time.Minute, healthyCutoff: 90_000},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			clk.now = start.Add(tc.elapsed)
+
+			numGen.val = tc.healthyCutoff - 1
+			assert.Equal(t, healthy, strategy.NextResponse(nil))
+			numGen.val = tc.healthyCutoff
+			assert.Equal(t, unhealthy, strategy.NextResponse(nil))
+		})
+	}
+
+	t.Run("weight ramps to zero", func(t *testing.T) {
+		clk := &mockClock{now: start}
+		strategy, err := NewRampedWeightedResponse(entries, []int{0, 1}, time.Minute, &mockNumGenerator{}, clk)
+		require.NoError(t, err)
+		assert.Equal(t, healthy, strategy.NextResponse(nil))
+
+		clk.now = start.Add(time.Minute)
+		assert.Equal(t, unhealthy, strategy.NextResponse(nil))
+	})
+}
+
+func TestRegisterHandlers(t *testing.T) {
+	t.Run("delay excluded from write timeout", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseBody([]byte("slow")),
+			WithResponseDelay(100*time.Millisecond),
+		)
+		require.NoError(t, err)
+
+		writeTimeout := 50 * time.Millisecond
+		endpoint, err := NewEndpoint("/slow", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, WithWriteTimeout(writeTimeout))
+
+		server := httptest.NewUnstartedServer(mux)
+		server.Config.WriteTimeout = writeTimeout
+		server.Start()
+		t.Cleanup(server.Close)
+
+		got, err := server.Client().Get(server.URL + "/slow")
+		require.NoError(t, err)
+		defer got.Body.Close()
+
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, got.StatusCode)
+		assert.Equal(t, "slow", string(body))
+	})
+
+	t.Run("auto content length", func(t *testing.T) {
+		body := strings.Repeat("a", 64*1024)
+		resp, err := NewResponse(WithResponseBody([]byte(body)))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/large", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		for _, auto := range []bool{false, true} {
+			var opts []HandlerOption
+			if auto {
+				opts = append(opts, WithAutoContentLength())
+			}
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint}, opts...)
+			server := httptest.NewServer(mux)
+
+			got, err := server.Client().Get(server.URL + "/large")
+			require.NoError(t, err)
+			_, err = io.Copy(io.Discard, got.Body)
+			require.NoError(t, err)
+			_ = got.Body.Close()
+			server.Close()
+
+			if auto {
+				assert.Equal(t, int64(len(body)), got.ContentLength)
+				assert.Empty(t, got.TransferEncoding)
+			} else {
+				assert.Equal(t, []string{"chunked"}, got.TransferEncoding)
+			}
+		}
+	})
+
+	t.Run("all methods with specific method override", func(t *testing.T) {
+		for _, method := range []string{MethodAll, "all", "*"} {
+			all, err := NewEndpoint("/items", method, StaticResponse(Response{statusCode: http.StatusAccepted}))
+			require.NoError(t, err)
+			assert.Empty(t, all.Method)
+			get, err := NewEndpoint("/items", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}))
+			require.NoError(t, err)
+
+			// registration order doesn't affect precedence
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{all, get})
+
+			for reqMethod, want := range map[string]int{
+				http.MethodGet:    http.StatusOK,
+				http.MethodHead:   http.StatusOK,
+				http.MethodPost:   http.StatusAccepted,
+				http.MethodDelete: http.StatusAccepted,
+			} {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(reqMethod, "/items", nil))
+				assert.Equal(t, want, rec.Code, "%s %s", method, reqMethod)
+			}
+		}
+	})
+
+	t.Run("multi valued headers", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseHeaders(map[string]string{"Content-Type": "text/plain", "Set-Cookie": "replaced=1"}),
+			WithResponseHeadersMulti(map[string][]string{"Set-Cookie": {"a=1", "b=2"}}),
+		)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, []string{"a=1", "b=2"}, rec.Header().Values("Set-Cookie"))
+		assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("close connection", func(t *testing.T) {
+		for _, closeConn := range []bool{false, true} {
+			var opts []EndpointOption
+			if closeConn {
+				opts = append(opts, WithCloseConnection())
+			}
+			endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}), opts...)
+			require.NoError(t, err)
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+			server := httptest.NewServer(mux)
+
+			got, err := server.Client().Get(server.URL)
+			require.NoError(t, err)
+			_ = got.Body.Close()
+			server.Close()
+
+			assert.Equal(t, closeConn, got.Close)
+		}
+	})
+
+	t.Run("raw header case", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseBody([]byte("hi")),
+			WithResponseHeaders(map[string]string{"x-custom": "a", "content-type": "text/plain", "X-Canonical": "b"}),
+		)
+		require.NoError(t, err)
+
+		for _, raw := range []bool{false, true} {
+			var opts []EndpointOption
+			if raw {
+				opts = append(opts, WithRawHeaderCase())
+			}
+			endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp), opts...)
+			require.NoError(t, err)
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+			server := httptest.NewServer(mux)
+
+			conn, err := net.Dial("tcp", server.Listener.Addr().String())
+			require.NoError(t, err)
+			_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+			require.NoError(t, err)
+			got, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			_ = conn.Close()
+			server.Close()
+
+			if raw {
+				assert.Contains(t, string(got), "\r\nx-custom: a\r\n")
+				assert.Contains(t, string(got), "\r\ncontent-type: text/plain\r\n")
+				assert.NotContains(t, string(got), "Content-Type", "server shouldn't sniff its own")
+			} else {
+				assert.Contains(t, string(got), "\r\nX-Custom: a\r\n")
+				assert.Contains(t, string(got), "\r\nContent-Type: text/plain\r\n")
+			}
+			assert.Contains(t, string(got), "\r\nX-Canonical: b\r\n")
+		}
+	})
+}
+
+func TestLimitRequestBytes(t *testing.T) {
+	cases := map[string]struct {
+		maxBytes int64
+		body     string
+		want     int
+	}{
+		"unlimited": {
+			maxBytes: 0,
+			body:     "0123456789",
+			want:     http.StatusOK,
+		},
+		"within limit": {
+			maxBytes: 10,
+			body:     "0123456789",
+			want:     http.StatusOK,
+		},
+		"exceeds limit": {
+			maxBytes: 5,
+			body:     "0123456789",
+			want:     http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			LimitRequestBytes(next, tc.maxBytes, nil).ServeHTTP(w, r)
+
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+
+	t.Run("too large response", func(t *testing.T) {
+		tooLarge, err := NewResponse(
+			WithResponseStatus(http.StatusRequestEntityTooLarge),
+			WithResponseHeaders(map[string]string{"Content-Type": "application/json"}),
+			WithResponseBody([]byte(`{"error": "body too large"}`)),
+		)
+		require.NoError(t, err)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request over the limit was handled")
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+		LimitRequestBytes(next, 5, &tooLarge).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `{"error": "body too large"}`, w.Body.String())
+	})
+}
+
+func BenchmarkStaticResponse(b *testing.B) {
+	resp, err := NewResponse(
+		WithResponseBody([]byte(`{"id":42,"name":"user"}`)),
+		WithResponseHeaders(map[string]string{"Content-Type": "application/json"}),
+	)
+	require.NoError(b, err)
+	endpoint, err := NewEndpoint("/users/42", http.MethodGet, StaticResponse(resp))
+	require.NoError(b, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	w := &discardResponseWriter{header: make(http.Header)}
+
+	// requests are logged at debug level, which should cost nothing while it's disabled
+	defaultLogger := slog.Default()
+	b.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	for name, level := range map[string]slog.Level{"info": slog.LevelInfo, "debug": slog.LevelDebug} {
+		b.Run(name, func(b *testing.B) {
+			slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: level})))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				mux.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ScheduleWindow is a daily time window, expressed as offsets from midnight. A window whose end is
+// before its start wraps past midnight.
+type ScheduleWindow struct {
+	Start    time.Duration
+	End      time.Duration
+	Response Response
+}
+
+func (w ScheduleWindow) contains(sinceMidnight time.Duration) bool {
+	if w.Start < w.End {
+		return sinceMidnight >= w.Start && sinceMidnight < w.End
+	}
+	return sinceMidnight >= w.Start || sinceMidnight < w.End
+}
+
+type ScheduledResponse struct {
+	windows  []ScheduleWindow
+	fallback Response
+	location *time.Location
+	clock    clock
+}
+
+// NewScheduledResponse builds a strategy returning the response of the first window containing the
+// current time of day in location, or fallback if no window matches.
+// If clk is nil, the system clock is used.
+func NewScheduledResponse(windows []ScheduleWindow, fallback Response, location *time.Location, clk clock) (*ScheduledResponse, error) {
+	if len(windows) == 0 {
+		return nil, errors.New("no schedule windows")
+	}
+	for _, window := range windows {
+		if window.Start < 0 || window.Start >= 24*time.Hour || window.End < 0 || window.End >= 24*time.Hour {
+			return nil, fmt.Errorf("schedule window %s-%s must be within a day", window.Start, window.End)
+		}
+		if window.Start == window.End {
+			return nil, fmt.Errorf("schedule window %s-%s is empty", window.Start, window.End)
+		}
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+	if clk == nil {
+		clk = systemClock{}
+	}
+
+	return &ScheduledResponse{
+		windows:  windows,
+		fallback: fallback,
+		location: location,
+		clock:    clk,
+	}, nil
+}
+
+func (s *ScheduledResponse) NextResponse(r *http.Request) Response {
+	resp, _ := s.nextChosenResponse(r)
+	return resp
+}
+
+func (s *ScheduledResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
+	// windows are in wall clock time, which isn't the time elapsed since midnight on DST change days
+	hour, minute, sec := s.clock.Now().In(s.location).Clock()
+	sinceMidnight := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(sec)*time.Second
+
+	for i, window := range s.windows {
+		if window.contains(sinceMidnight) {
+			return window.Response, responseChoice{strategy: "schedule", index: i}
+		}
+	}
+	return s.fallback, responseChoice{strategy: "schedule", index: len(s.windows)}
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClock struct {
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func TestScheduledResponse(t *testing.T) {
+	fallback := Response{statusCode: http.StatusOK}
+	maintenance := Response{statusCode: http.StatusServiceUnavailable}
+	overnight := Response{statusCode: http.StatusTooManyRequests}
+
+	t.Run("no windows", func(t *testing.T) {
+		strategy, err := NewScheduledResponse(nil, fallback, nil, nil)
+		assert.Error(t, err)
+		assert.Nil(t, strategy)
+	})
+
+	t.Run("invalid windows", func(t *testing.T) {
+		windows := [][]ScheduleWindow{
+			{{Start: -time.Hour, End: time.Hour}},
+			{{Start: time.Hour, End: 24 * time.Hour}},
+			{{Start: time.Hour, End: time.Hour}},
+		}
+		for _, w := range windows {
+			strategy, err := NewScheduledResponse(w, fallback, nil, nil)
+			assert.Error(t, err)
+			assert.Nil(t, strategy)
+		}
+	})
+
+	t.Run("windows", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		clk := &mockClock{}
+		windows := []ScheduleWindow{
+			{Start: 2 * time.Hour, End: 4 * time.Hour, Response: maintenance},
+			{Start: 22 * time.Hour, End: 3 * time.Hour, Response: overnight},
+		}
+		strategy, err := NewScheduledResponse(windows, fallback, loc, clk)
+		require.NoError(t, err)
+
+		cases := map[string]struct {
+			hour, minute int
+			want         Response
+		}{
+			"before all windows":       {hour: 12, want: fallback},
+			"start is inclusive":       {hour: 2, want: maintenance},
+			"end is exclusive":         {hour: 4, want: fallback},
+			"inside window":            {hour: 3, minute: 30, want: maintenance},
+			"overlap uses first match": {hour: 2, minute: 30, want: maintenance},
+			"wraps before midnight":    {hour: 23, want: overnight},
+			"wraps after midnight":     {hour: 1, want: overnight},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				clk.now = time.Date(2025, 6, 1, tc.hour, tc.minute, 0, 0, loc).UTC()
+				assert.Equal(t, tc.want, strategy.NextResponse(nil))
+			})
+		}
+	})
+
+	t.Run("dst change days", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		clk := &mockClock{}
+		windows := []ScheduleWindow{{Start: 9 * time.Hour, End: 17 * time.Hour, Response: maintenance}}
+		strategy, err := NewScheduledResponse(windows, fallback, loc, clk)
+		require.NoError(t, err)
+
+		cases := []struct {
+			hour, minute int
+			want         Response
+		}{
+			{hour: 8, minute: 30, want: fallback},
+			{hour: 9, want: maintenance},
+			{hour: 16, minute: 30, want: maintenance},
+			{hour: 17, want: fallback},
+		}
+		// clocks spring forward on 2025-03-09 and fall back on 2025-11-02
+		for _, day := range []time.Time{time.Date(2025, 3, 9, 0, 0, 0, 0, loc), time.Date(2025, 11, 2, 0, 0, 0, 0, loc)} {
+			for _, tc := range cases {
+				clk.now = time.Date(day.Year(), day.Month(), day.Day(), tc.hour, tc.minute, 0, 0, loc)
+				assert.Equal(t, tc.want, strategy.NextResponse(nil), clk.now)
+			}
+		}
+	})
+}
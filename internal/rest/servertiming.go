@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverTimingWriter adds a Server-Timing header to the response, breaking down how the handler
+// spent its time: the response's delay, rendering its body template, and the total time until the
+// header was written. Parts that didn't apply are left out.
+type serverTimingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	delay       time.Duration
+	render      time.Duration
+	wroteHeader bool
+}
+
+func newServerTimingWriter(w http.ResponseWriter) *serverTimingWriter {
+	return &serverTimingWriter{ResponseWriter: w, start: time.Now()}
+}
+
+func (s *serverTimingWriter) WriteHeader(statusCode int) {
+	// informational responses come before the final one, which is what the timing describes
+	if !s.wroteHeader && statusCode >= http.StatusOK {
+		s.wroteHeader = true
+		s.Header().Set("Server-Timing", s.header(time.Since(s.start)))
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *serverTimingWriter) Write(b []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and hijacking.
+func (s *serverTimingWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *serverTimingWriter) header(total time.Duration) string {
+	var metrics []string
+	if s.delay != 0 {
+		metrics = append(metrics, serverTimingMetric("delay", s.delay))
+	}
+	if s.render != 0 {
+		metrics = append(metrics, serverTimingMetric("render", s.render))
+	}
+	metrics = append(metrics, serverTimingMetric("total", total))
+	return strings.Join(metrics, ", ")
+}
+
+// serverTimingMetric formats a metric with its duration in milliseconds, to microsecond precision.
+func serverTimingMetric(name string, d time.Duration) string {
+	ms := float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+	return name + ";dur=" + strconv.FormatFloat(ms, 'f', -1, 64)
+}
+
+// recordDelay records a delay applied to the response, if w reports Server-Timing.
+func recordDelay(w http.ResponseWriter, d time.Duration) {
+	if timing, ok := w.(*serverTimingWriter); ok {
+		timing.delay += d
+	}
+}
+
+// recordRender records the time spent rendering the response's body, if w reports Server-Timing.
+func recordRender(w http.ResponseWriter, d time.Duration) {
+	if timing, ok := w.(*serverTimingWriter); ok {
+		timing.render += d
+	}
+}
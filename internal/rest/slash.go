@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TrailingSlash controls how requests differing from an endpoint's path only by a trailing slash
+// are handled.
+type TrailingSlash string
+
+const (
+	// TrailingSlashStrict leaves matching to the mux, so /users and /users/ are distinct.
+	TrailingSlashStrict TrailingSlash = "strict"
+	// TrailingSlashRedirect redirects with a 301 to the form of the path an endpoint matches.
+	TrailingSlashRedirect TrailingSlash = "redirect"
+	// TrailingSlashIgnore serves the request as if it used the form of the path an endpoint matches.
+	TrailingSlashIgnore TrailingSlash = "ignore"
+)
+
+// TrailingSlashHandler normalizes trailing slashes of requests the mux has no route for. If adding
+// or removing the trailing slash finds a route, the request is redirected or rewritten depending
+// on mode. Paths a route already matches, including wildcards like /files/{rest...} matching
+// /files/, are never changed.
+func TrailingSlashHandler(next http.Handler, mux *http.ServeMux, mode TrailingSlash) (http.Handler, error) {
+	switch mode {
+	case TrailingSlashStrict, "":
+		return next, nil
+	case TrailingSlashRedirect, TrailingSlashIgnore:
+	default:
+		return nil, fmt.Errorf("unknown trailing slash mode %q", mode)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		_, pattern := mux.Handler(r)
+		if pattern != "" && !isSlashRedirect(r.URL.Path, pattern) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		toggled := r.URL.Path + "/"
+		if trimmed, ok := strings.CutSuffix(r.URL.Path, "/"); ok {
+			toggled = trimmed
+		}
+		alt := r.Clone(r.Context())
+		alt.URL.Path = toggled
+		alt.URL.RawPath = ""
+		if _, pattern := mux.Handler(alt); pattern == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if mode == TrailingSlashRedirect {
+			target := alt.URL.EscapedPath()
+			if alt.URL.RawQuery != "" {
+				target += "?" + alt.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		next.ServeHTTP(w, alt)
+	}), nil
+}
+
+// isSlashRedirect reports whether the mux matched path to pattern only by redirecting to path with
+// a trailing slash appended. For those redirects, the mux reports the pattern the redirect target
+// matches.
+func isSlashRedirect(path, pattern string) bool {
+	if _, patternPath, ok := strings.Cut(pattern, " "); ok {
+		pattern = patternPath
+	}
+	return pattern == path+"/"
+}
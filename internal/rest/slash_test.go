@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailingSlashHandler(t *testing.T) {
+	_, err := TrailingSlashHandler(http.NotFoundHandler(), http.NewServeMux(), "sometimes")
+	assert.Error(t, err)
+
+	newMux := func(t *testing.T) *http.ServeMux {
+		t.Helper()
+		var endpoints []*Endpoint
+		for _, path := range []string{"/users", "/orders/", "/files/{rest...}"} {
+			endpoint, err := NewEndpoint(path, http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK, body: []byte(path)}))
+			require.NoError(t, err)
+			endpoints = append(endpoints, endpoint)
+		}
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, endpoints)
+		return mux
+	}
+
+	cases := map[string]struct {
+		mode         TrailingSlash
+		target       string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		"strict keeps slash distinct": {mode: TrailingSlashStrict, target: "/users/", wantCode: http.StatusNotFound},
+		"ignore strips slash":         {mode: TrailingSlashIgnore, target: "/users/", wantCode: http.StatusOK, wantBody: "/users"},
+		"ignore adds slash":           {mode: TrailingSlashIgnore, target: "/orders", wantCode: http.StatusOK, wantBody: "/orders/"},
+		"ignore exact match":          {mode: TrailingSlashIgnore, target: "/users", wantCode: http.StatusOK, wantBody: "/users"},
+		"ignore unknown path":         {mode: TrailingSlashIgnore, target: "/nope/", wantCode: http.StatusNotFound},
+		"wildcard matches slash":      {mode: TrailingSlashIgnore, target: "/files/", wantCode: http.StatusOK, wantBody: "/files/{rest...}"},
+		"redirect to canonical":       {mode: TrailingSlashRedirect, target: "/users/?page=2", wantCode: http.StatusMovedPermanently, wantLocation: "/users?page=2"},
+		"redirect exact match":        {mode: TrailingSlashRedirect, target: "/users", wantCode: http.StatusOK, wantBody: "/users"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mux := newMux(t)
+			handler, err := TrailingSlashHandler(mux, mux, tc.mode)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+			if tc.wantLocation != "" {
+				assert.Equal(t, tc.wantLocation, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// StaticDirOptions configures WithStaticDir.
+type StaticDirOptions struct {
+	// Listing lists the files of directories without an index.html, rather than a 404.
+	Listing bool
+	// SPAFallback names a file, such as index.html, served in place of missing files so
+	// client-side routes load a single-page app. Missing assets, paths with a file extension,
+	// still get a 404.
+	SPAFallback string
+}
+
+// staticDir serves files from a directory, like an asset server.
+type staticDir struct {
+	fsys     fs.FS
+	server   http.Handler
+	wildcard string
+	fallback string
+}
+
+// WithStaticDir serves files from dir instead of writing a response, with content types set from
+// their extensions. The file served is named by the path wildcard, such as path for an endpoint
+// of /assets/{path...}. Missing files get a 404.
+func WithStaticDir(dir, wildcard string, opts StaticDirOptions) ResponseOption {
+	return func(r *Response) error {
+		if wildcard == "" {
+			return errors.New("static dir requires a path wildcard naming the file to serve")
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("static dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("static dir %q is not a directory", dir)
+		}
+
+		fsys := os.DirFS(dir)
+		if !opts.Listing {
+			fsys = noListingFS{fsys}
+		}
+		fallback := strings.TrimPrefix(opts.SPAFallback, "/")
+		if opts.SPAFallback != "" {
+			if !fs.ValidPath(fallback) {
+				return fmt.Errorf("invalid static dir spa fallback %q", opts.SPAFallback)
+			}
+			info, err := fs.Stat(fsys, fallback)
+			if err != nil {
+				return fmt.Errorf("static dir spa fallback: %w", err)
+			}
+			if info.IsDir() {
+				return fmt.Errorf("static dir spa fallback %q is a directory", opts.SPAFallback)
+			}
+		}
+		r.staticDir = &staticDir{
+			fsys:     fsys,
+			server:   http.FileServerFS(fsys),
+			wildcard: wildcard,
+			fallback: fallback,
+		}
+		return nil
+	}
+}
+
+func (s *staticDir) serve(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.PathValue(s.wildcard))
+	if s.fallback != "" && path.Ext(name) == "" && !s.exists(name) {
+		s.serveFallback(w, r)
+		return
+	}
+
+	// like http.StripPrefix, so the file server sees the path within the directory
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = "/" + r.PathValue(s.wildcard)
+	r2.URL.RawPath = ""
+	s.server.ServeHTTP(w, r2)
+}
+
+// exists reports whether the file server has something to serve for name.
+func (s *staticDir) exists(name string) bool {
+	_, err := fs.Stat(s.fsys, fsPath(name))
+	return err == nil
+}
+
+// serveFallback serves the SPA fallback file with a 200, whatever the request's path.
+func (s *staticDir) serveFallback(w http.ResponseWriter, r *http.Request) {
+	f, err := s.fsys.Open(s.fallback)
+	if err != nil {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, s.fallback, info.ModTime(), content)
+}
+
+// fsPath converts a cleaned, rooted URL path to an fs.FS path.
+func fsPath(name string) string {
+	if name == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(name, "/")
+}
+
+// noListingFS hides directories without an index.html, so they get a 404 rather than a listing.
+type noListingFS struct {
+	fs.FS
+}
+
+func (n noListingFS) Open(name string) (fs.File, error) {
+	f, err := n.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return f, err
+	}
+	if _, err := fs.Stat(n.FS, path.Join(name, "index.html")); err != nil {
+		_ = f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "img"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "img", "logo.svg"), []byte("<svg/>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "200.html"), []byte("<html>fallback</html>"), 0o644))
+	outside := filepath.Join(filepath.Dir(dir), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o644))
+	t.Cleanup(func() { _ = os.Remove(outside) })
+
+	serve := func(t *testing.T, opts StaticDirOptions, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		resp, err := NewResponse(WithStaticDir(dir, "path", opts))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/assets/{path...}", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewResponse(WithStaticDir(filepath.Join(dir, "missing"), "path", StaticDirOptions{}))
+		assert.Error(t, err)
+		_, err = NewResponse(WithStaticDir(filepath.Join(dir, "app.js"), "path", StaticDirOptions{}))
+		assert.Error(t, err, "not a directory")
+		_, err = NewResponse(WithStaticDir(dir, "", StaticDirOptions{}))
+		assert.Error(t, err)
+		_, err = NewResponse(WithStaticDir(dir, "path", StaticDirOptions{SPAFallback: "missing.html"}))
+		assert.Error(t, err, "spa fallback must exist")
+		_, err = NewResponse(WithStaticDir(dir, "path", StaticDirOptions{SPAFallback: "img"}))
+		assert.Error(t, err, "spa fallback can't be a directory")
+		_, err = NewResponse(WithStaticDir(dir, "path", StaticDirOptions{SPAFallback: "../secret.txt"}))
+		assert.Error(t, err, "spa fallback must be in the dir")
+	})
+
+	t.Run("serves files", func(t *testing.T) {
+		rec := serve(t, StaticDirOptions{}, "/assets/app.js")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "console.log(1)", rec.Body.String())
+		assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+
+		rec = serve(t, StaticDirOptions{}, "/assets/img/logo.svg")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rec := serve(t, StaticDirOptions{}, "/assets/missing.js")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("listing", func(t *testing.T) {
+		rec := serve(t, StaticDirOptions{}, "/assets/img/")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(t, StaticDirOptions{Listing: true}, "/assets/img/")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "logo.svg")
+	})
+
+	t.Run("spa fallback", func(t *testing.T) {
+		opts := StaticDirOptions{SPAFallback: "index.html"}
+		rec := serve(t, opts, "/assets/orders/42")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>app</html>", rec.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		rec = serve(t, opts, "/assets/app.js")
+		assert.Equal(t, "console.log(1)", rec.Body.String(), "existing files are still served")
+
+		rec = serve(t, opts, "/assets/missing.js")
+		assert.Equal(t, http.StatusNotFound, rec.Code, "missing assets aren't replaced by the fallback")
+
+		rec = serve(t, StaticDirOptions{SPAFallback: "200.html"}, "/assets/orders")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "<html>fallback</html>", rec.Body.String())
+	})
+
+	t.Run("stays in dir", func(t *testing.T) {
+		rec := serve(t, StaticDirOptions{}, "/assets/..%2fsecret.txt")
+		assert.NotEqual(t, "secret", rec.Body.String())
+	})
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caproven/mock-server/internal/accesslog"
+)
+
+// latencyBounds are the upper bounds of the latency histogram's buckets, from 100µs to over 10
+// minutes, each 25% wider than the last so percentiles are estimated to within about 25%.
+var latencyBounds = func() []time.Duration {
+	var bounds []time.Duration
+	for bound := float64(100 * time.Microsecond); bound < float64(10*time.Minute); bound *= 1.25 {
+		bounds = append(bounds, time.Duration(bound))
+	}
+	return bounds
+}()
+
+// endpointStats tracks how long an endpoint's requests take to handle, in a histogram of latencies,
+// and how many of them fail.
+type endpointStats struct {
+	mu     sync.Mutex
+	count  int64
+	errors int64
+	// buckets counts requests by latency, indexed like latencyBounds with a final overflow bucket.
+	buckets []int64
+	max     time.Duration
+}
+
+// handler wraps next, recording the duration and status of each request it serves.
+func (s *endpointStats) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accesslog.Recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		s.record(time.Since(start), rec.Status())
+	})
+}
+
+// record counts a request that took d, and failed if its status is a 5xx. Requests that never
+// wrote a status, like connection resets, aren't counted as errors.
+func (s *endpointStats) record(d time.Duration, status int) {
+	i := len(latencyBounds)
+	for j, bound := range latencyBounds {
+		if d <= bound {
+			i = j
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets == nil {
+		s.buckets = make([]int64, len(latencyBounds)+1)
+	}
+	s.buckets[i]++
+	s.count++
+	if status >= http.StatusInternalServerError {
+		s.errors++
+	}
+	s.max = max(s.max, d)
+}
+
+func (s *endpointStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count, s.errors, s.buckets, s.max = 0, 0, nil, 0
+}
+
+// EndpointStats summarizes the requests an endpoint has handled. Latencies are in milliseconds.
+type EndpointStats struct {
+	ID        string  `json:"id"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	P50       float64 `json:"p50Ms"`
+	P90       float64 `json:"p90Ms"`
+	P99       float64 `json:"p99Ms"`
+	Max       float64 `json:"maxMs"`
+}
+
+// Stats returns the endpoint's request stats since it was created or its stats were last reset.
+func (p *Endpoint) Stats() EndpointStats {
+	s := &p.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := EndpointStats{ID: p.ID(), Count: s.count, Errors: s.errors}
+	if s.count == 0 {
+		return stats
+	}
+	stats.ErrorRate = float64(s.errors) / float64(s.count)
+	stats.P50 = milliseconds(s.quantile(0.5))
+	stats.P90 = milliseconds(s.quantile(0.9))
+	stats.P99 = milliseconds(s.quantile(0.99))
+	stats.Max = milliseconds(s.max)
+	return stats
+}
+
+// quantile estimates the latency below which q of requests fall, interpolating within the bucket
+// it lands in. It's called with mu held.
+func (s *endpointStats) quantile(q float64) time.Duration {
+	rank := q * float64(s.count)
+	var seen int64
+	for i, n := range s.buckets {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		upper := s.max
+		if i < len(latencyBounds) {
+			upper = min(latencyBounds[i], s.max)
+		}
+		fraction := (rank - float64(seen)) / float64(n)
+		return min(lower+time.Duration(fraction*float64(upper-lower)), s.max)
+	}
+	return s.max
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointStats(t *testing.T) {
+	endpoint, err := NewEndpoint("/users", http.MethodGet, StaticResponse(Response{statusCode: http.StatusOK}))
+	require.NoError(t, err)
+
+	assert.Equal(t, EndpointStats{ID: "GET /users"}, endpoint.Stats(), "no requests yet")
+
+	// 1ms to 100ms, one request each
+	for i := 1; i <= 100; i++ {
+		status := http.StatusOK
+		if i%10 == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		endpoint.stats.record(time.Duration(i)*time.Millisecond, status)
+	}
+
+	stats := endpoint.Stats()
+	assert.Equal(t, int64(100), stats.Count)
+	assert.Equal(t, int64(10), stats.Errors)
+	assert.InDelta(t, 0.1, stats.ErrorRate, 1e-9)
+	assert.InDelta(t, 50, stats.P50, 12.5)
+	assert.InDelta(t, 90, stats.P90, 22.5)
+	assert.InDelta(t, 99, stats.P99, 25)
+	assert.LessOrEqual(t, stats.P99, stats.Max)
+	assert.InDelta(t, 100, stats.Max, 1e-9)
+
+	endpoint.stats.reset()
+	assert.Equal(t, EndpointStats{ID: "GET /users"}, endpoint.Stats())
+}
+
+func TestAdminStats(t *testing.T) {
+	ok, err := NewResponse(WithResponseBody([]byte("ok")))
+	require.NoError(t, err)
+	users, err := NewEndpoint("/users", http.MethodGet, StaticResponse(ok))
+	require.NoError(t, err)
+	failing, err := NewEndpoint("/failing", http.MethodGet, StaticResponse(Response{statusCode: http.StatusBadGateway}))
+	require.NoError(t, err)
+	endpoints := []*Endpoint{users, failing}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, endpoints)
+	RegisterAdminHandlers(mux, endpoints, "")
+
+	for _, path := range []string{"/users", "/users", "/failing"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	stats := func(t *testing.T, method, path string) statsResult {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var result statsResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	result := stats(t, http.MethodGet, "/__admin/stats")
+	require.Len(t, result.Endpoints, 2)
+	assert.Equal(t, "GET /users", result.Endpoints[0].ID)
+	assert.Equal(t, int64(2), result.Endpoints[0].Count)
+	assert.Zero(t, result.Endpoints[0].Errors)
+	assert.Greater(t, result.Endpoints[0].P50, 0.0)
+	assert.Equal(t, "GET /failing", result.Endpoints[1].ID)
+	assert.Equal(t, int64(1), result.Endpoints[1].Count)
+	assert.Equal(t, 1.0, result.Endpoints[1].ErrorRate)
+
+	result = stats(t, http.MethodPost, "/__admin/stats/reset")
+	for _, endpoint := range result.Endpoints {
+		assert.Zero(t, endpoint.Count)
+	}
+	assert.Zero(t, stats(t, http.MethodGet, "/__admin/stats").Endpoints[0].Count)
+}
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// NewStickyResponse builds a strategy keeping each client on the response resolver first chose for
+// it, like a load balancer's session affinity. A client's first request rolls the dice and the rest
+// reuse its result. Clients are identified and tracked as in a PerClientResponse, so they're
+// re-rolled once evicted. If clk is nil, the system clock is used.
+func NewStickyResponse(resolver ResponseResolver, opts PerClientOptions, clk clock) (*PerClientResponse, error) {
+	if resolver == nil {
+		return nil, errors.New("no response strategy to make sticky")
+	}
+	return NewPerClientResponse(&stickyResolver{resolver: resolver}, opts, clk)
+}
+
+// stickyResolver returns the first response its resolver chooses for every request.
+type stickyResolver struct {
+	resolver ResponseResolver
+
+	mu     sync.Mutex
+	chosen bool
+	resp   Response
+	choice responseChoice
+}
+
+func (s *stickyResolver) NextResponse(r *http.Request) Response {
+	resp, _ := s.nextChosenResponse(r)
+	return resp
+}
+
+func (s *stickyResolver) nextChosenResponse(r *http.Request) (Response, responseChoice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.chosen {
+		s.resp, s.choice = nextChosenResponse(s.resolver, r)
+		s.chosen = true
+	}
+	return s.resp, s.choice
+}
+
+// responseAt doesn't make the picked response the client's sticky choice.
+func (s *stickyResolver) responseAt(r *http.Request, index int) (Response, responseChoice, bool) {
+	return responseAt(s.resolver, r, index)
+}
+
+func (s *stickyResolver) fresh() ResponseResolver {
+	return &stickyResolver{resolver: freshResolver(s.resolver)}
+}
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cyclingNumGenerator returns 0, 1, ..., n-1 in turn.
+type cyclingNumGenerator struct {
+	next int
+}
+
+func (c *cyclingNumGenerator) N(n int) int {
+	val := c.next % n
+	c.next++
+	return val
+}
+
+func (c *cyclingNumGenerator) Float64() float64 {
+	return 0
+}
+
+func TestStickyResponse(t *testing.T) {
+	a := Response{statusCode: http.StatusOK, body: []byte("a")}
+	b := Response{statusCode: http.StatusOK, body: []byte("b")}
+	weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+		{Response: a, Weight: 1},
+		{Response: b, Weight: 1},
+	}, &cyclingNumGenerator{})
+	require.NoError(t, err)
+
+	t.Run("no strategy", func(t *testing.T) {
+		_, err := NewStickyResponse(nil, PerClientOptions{MaxClients: 1}, nil)
+		assert.Error(t, err)
+	})
+
+	strategy, err := NewStickyResponse(weighted, PerClientOptions{Header: "X-Session", Cookie: "session", MaxClients: 10}, nil)
+	require.NoError(t, err)
+
+	request := func(header, cookie string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		if header != "" {
+			r.Header.Set("X-Session", header)
+		}
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+		}
+		return r
+	}
+
+	// each session's first request rolls the dice, so sessions alternate between a and b
+	assert.Equal(t, a, strategy.NextResponse(request("alice", "")))
+	assert.Equal(t, b, strategy.NextResponse(request("", "bob")))
+	assert.Equal(t, a, strategy.NextResponse(request("", "")), "remote ip")
+
+	for range 3 {
+		assert.Equal(t, a, strategy.NextResponse(request("alice", "")))
+		assert.Equal(t, b, strategy.NextResponse(request("", "bob")))
+		assert.Equal(t, a, strategy.NextResponse(request("", "")))
+	}
+	// the header identifies the client before the cookie
+	assert.Equal(t, a, strategy.NextResponse(request("alice", "bob")))
+
+	resp, choice := strategy.nextChosenResponse(request("", "bob"))
+	assert.Equal(t, b, resp)
+	assert.Equal(t, responseChoice{strategy: "weighted", index: 1}, choice)
+}
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// bodyStream writes a response body incrementally rather than all at once.
+type bodyStream interface {
+	// writeTo writes the body to w, calling flush whenever written data should reach the client.
+	// It returns early with the context's error if ctx is cancelled.
+	writeTo(ctx context.Context, w io.Writer, flush func() error) error
+}
+
+type ServerSentEvent struct {
+	Event string
+	ID    string
+	Data  string
+	// Delay is waited before sending the event.
+	Delay time.Duration
+}
+
+type eventStream struct {
+	events []ServerSentEvent
+	loop   bool
+}
+
+// WithEventStream makes the response a stream of server-sent events. If loop is set, the events
+// repeat until the client disconnects.
+func WithEventStream(events []ServerSentEvent, loop bool) ResponseOption {
+	return func(r *Response) error {
+		if len(events) == 0 {
+			return errors.New("no server-sent events")
+		}
+		var totalDelay time.Duration
+		for _, event := range events {
+			if event.Delay < 0 {
+				return errors.New("event delay cannot be negative")
+			}
+			totalDelay += event.Delay
+		}
+		if loop && totalDelay == 0 {
+			return errors.New("looping events require a delay")
+		}
+
+		headers := make(map[string]string, len(r.headers)+2)
+		headers["Content-Type"] = "text/event-stream"
+		headers["Cache-Control"] = "no-cache"
+		for k, v := range r.headers {
+			headers[k] = v
+		}
+		r.headers = headers
+		r.stream = eventStream{events: events, loop: loop}
+		return nil
+	}
+}
+
+func (s eventStream) writeTo(ctx context.Context, w io.Writer, flush func() error) error {
+	for {
+		for _, event := range s.events {
+			if err := sleepContext(ctx, event.Delay); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, event.format()); err != nil {
+				return err
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if !s.loop {
+			return nil
+		}
+	}
+}
+
+// format encodes the event per the server-sent events spec, splitting multiline data.
+func (e ServerSentEvent) format() string {
+	var sb strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&sb, "event: %s\n", e.Event)
+	}
+	for line := range strings.SplitSeq(e.Data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+type jsonArrayStream struct {
+	elements [][]byte
+	delay    time.Duration
+}
+
+// WithJSONArrayStream makes the response a JSON array written one element at a time, waiting delay
+// before each element. The client receives a single valid JSON document, just slowly.
+func WithJSONArrayStream(elements [][]byte, delay time.Duration) ResponseOption {
+	return func(r *Response) error {
+		if delay < 0 {
+			return errors.New("element delay cannot be negative")
+		}
+		for i, element := range elements {
+			if !json.Valid(element) {
+				return fmt.Errorf("array element %d is not valid json", i)
+			}
+		}
+
+		headers := make(map[string]string, len(r.headers)+1)
+		headers["Content-Type"] = "application/json"
+		for k, v := range r.headers {
+			headers[k] = v
+		}
+		r.headers = headers
+		r.stream = jsonArrayStream{elements: elements, delay: delay}
+		return nil
+	}
+}
+
+func (s jsonArrayStream) writeTo(ctx context.Context, w io.Writer, flush func() error) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, element := range s.elements {
+		if err := flush(); err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, s.delay); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(element); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "]"); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// writeStream writes a streamed response. Streams can outlive the server's write timeout, so the
+// write deadline is cleared for their duration.
+func writeStream(w http.ResponseWriter, r *http.Request, resp Response) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("failed to clear write deadline", "err", err)
+	}
+
+	w.WriteHeader(resp.statusCode)
+	if err := rc.Flush(); err != nil {
+		slog.Warn("failed to flush response", "err", err)
+		return
+	}
+
+	if err := resp.stream.writeTo(r.Context(), w, rc.Flush); err != nil {
+		if errors.Is(err, context.Canceled) {
+			slog.Info("client disconnected from stream", "path", r.URL.Path, "addr", r.RemoteAddr)
+			return
+		}
+		slog.Warn("failed to write stream", "err", err)
+	}
+}
@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream(t *testing.T) {
+	t.Run("invalid events", func(t *testing.T) {
+		cases := map[string]struct {
+			events []ServerSentEvent
+			loop   bool
+		}{
+			"no events":          {},
+			"negative delay":     {events: []ServerSentEvent{{Data: "a", Delay: -time.Second}}},
+			"loop without delay": {events: []ServerSentEvent{{Data: "a"}}, loop: true},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				resp, err := NewResponse(WithEventStream(tc.events, tc.loop))
+				assert.Error(t, err)
+				assert.Zero(t, resp)
+			})
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		event := ServerSentEvent{ID: "7", Event: "update", Data: "line 1\nline 2"}
+		assert.Equal(t, "id: 7\nevent: update\ndata: line 1\ndata: line 2\n\n", event.format())
+	})
+
+	t.Run("streams events", func(t *testing.T) {
+		resp, err := NewResponse(WithEventStream([]ServerSentEvent{
+			{Data: "first"},
+			{Event: "second", Data: "2", Delay: 10 * time.Millisecond},
+		}, false))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/events", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "data: first\n\nevent: second\ndata: 2\n\n", w.Body.String())
+		assert.True(t, w.Flushed)
+	})
+
+	t.Run("stops when client disconnects", func(t *testing.T) {
+		resp, err := NewResponse(WithEventStream([]ServerSentEvent{
+			{Data: "tick", Delay: time.Millisecond},
+		}, true))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/events", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+		require.NoError(t, err)
+		got, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer got.Body.Close()
+
+		line, err := bufio.NewReader(got.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "data: tick\n", line)
+		cancel()
+	})
+}
+
+func TestJSONArrayStream(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		resp, err := NewResponse(WithJSONArrayStream([][]byte{[]byte(`{"id":1}`)}, -time.Second))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+
+		resp, err = NewResponse(WithJSONArrayStream([][]byte{[]byte(`{"id":`)}, 0))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+
+	cases := map[string]struct {
+		elements [][]byte
+		want     string
+	}{
+		"empty": {
+			want: "[]",
+		},
+		"multiple elements": {
+			elements: [][]byte{[]byte(`{"id":1}`), []byte(`{"id":2}`), []byte(`"three"`)},
+			want:     `[{"id":1},{"id":2},"three"]`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := NewResponse(WithJSONArrayStream(tc.elements, time.Millisecond))
+			require.NoError(t, err)
+			endpoint, err := NewEndpoint("/items", http.MethodGet, StaticResponse(resp))
+			require.NoError(t, err)
+
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.want, w.Body.String())
+		})
+	}
+}
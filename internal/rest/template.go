@@ -0,0 +1,314 @@
+package rest
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// templateData is the data body templates are executed against.
+type templateData struct {
+	request *http.Request
+}
+
+// PathValue returns the value of the named path wildcard, e.g. "id" for a path of /users/{id}.
+func (d templateData) PathValue(name string) string {
+	return d.request.PathValue(name)
+}
+
+// RequestID returns the request's ID, or "" unless request IDs are enabled.
+func (d templateData) RequestID() string {
+	return requestID(d.request)
+}
+
+// Proto returns the request's protocol version, e.g. HTTP/1.1 or HTTP/2.0.
+func (d templateData) Proto() string {
+	return d.request.Proto
+}
+
+// ClientCertSubject returns the subject of the client's verified TLS certificate, e.g.
+// CN=client,O=Example, or "" if it didn't present one.
+func (d templateData) ClientCertSubject() string {
+	if cert := clientCert(d.request); cert != nil {
+		return cert.Subject.String()
+	}
+	return ""
+}
+
+// JSON returns the request's body decoded as JSON, or nil if it isn't valid JSON. Field chains
+// on it, like .JSON.user.name, render empty when a field is missing, see rewriteJSONFields.
+func (d templateData) JSON() any {
+	return requestJSON(d.request)
+}
+
+// PathParams returns the wildcard names declared by a path pattern, e.g. "id" and "rest" for
+// /users/{id}/{rest...}.
+func PathParams(path string) []string {
+	var params []string
+	for segment := range strings.SplitSeq(path, "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.Trim(segment, "{}"), "...")
+		if name == "$" {
+			continue
+		}
+		params = append(params, name)
+	}
+	return params
+}
+
+// templateFuncs returns the helper functions available to body templates. randInt draws from
+// numGenerator.
+func templateFuncs(numGenerator numberGenerator) template.FuncMap {
+	return template.FuncMap{
+		"uuid": newUUID,
+		"now": func(layout string) string {
+			return time.Now().Format(layout)
+		},
+		"randInt": func(lo, hi int) (int, error) {
+			if hi < lo {
+				return 0, fmt.Errorf("randInt max %d is less than min %d", hi, lo)
+			}
+			return lo + numGenerator.N(hi-lo+1), nil
+		},
+		"env":       os.Getenv,
+		"jsonField": jsonField,
+	}
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	return uuidFrom(crand.Reader)
+}
+
+// uuidFrom returns a version 4 UUID drawn from random.
+func uuidFrom(random io.Reader) string {
+	var b [16]byte
+	_, _ = io.ReadFull(random, b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WithResponseTemplate renders the body per request from a text/template. Any PathValue calls in
+// the template must reference one of pathParams. Templates may call uuid, now "layout",
+// randInt min max (inclusive) and env "VAR", and read the JSON request body with .JSON.
+func WithResponseTemplate(text string, pathParams []string) ResponseOption {
+	return withResponseTemplate(text, pathParams, rng{})
+}
+
+func withResponseTemplate(text string, pathParams []string, numGenerator numberGenerator) ResponseOption {
+	return func(r *Response) error {
+		tmpl, err := parseTemplate("body", text, pathParams, numGenerator)
+		if err != nil {
+			return err
+		}
+		r.template = tmpl
+		return nil
+	}
+}
+
+// WithResponseHeaderTemplates sets headers whose values are rendered per request from
+// text/templates, with the same data and functions as WithResponseTemplate, such as a Location
+// of /users/{{ .PathValue "id" }}. They replace any other header of the same name.
+func WithResponseHeaderTemplates(headers map[string][]string, pathParams []string) ResponseOption {
+	return func(r *Response) error {
+		templates := make(map[string][]*template.Template, len(headers))
+		for name, vals := range headers {
+			for _, val := range vals {
+				tmpl, err := parseTemplate(name+" header", val, pathParams, rng{})
+				if err != nil {
+					return err
+				}
+				templates[name] = append(templates[name], tmpl)
+			}
+		}
+		r.headerTemplates = templates
+		return nil
+	}
+}
+
+// IsTemplate reports whether text has template actions, rather than being a literal that renders
+// as itself.
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// parseTemplate parses a template, checking any PathValue calls in it reference one of pathParams.
+// name describes the template in errors.
+func parseTemplate(name, text string, pathParams []string, numGenerator numberGenerator) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs(numGenerator)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s template: %w", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		rewriteJSONFields(t.Root)
+	}
+	for _, param := range templatePathValues(tmpl.Root) {
+		if !slices.Contains(pathParams, param) {
+			return nil, fmt.Errorf("%s template references undeclared path value %q", name, param)
+		}
+	}
+	return tmpl, nil
+}
+
+// renderBody renders the body template into buf.
+func (r Response) renderBody(req *http.Request, buf *bytes.Buffer) error {
+	return r.template.Execute(buf, templateData{request: req})
+}
+
+// renderHeaders renders the header templates' values for req.
+func (r Response) renderHeaders(req *http.Request) (map[string][]string, error) {
+	headers := make(map[string][]string, len(r.headerTemplates))
+	for name, templates := range r.headerTemplates {
+		vals := make([]string, len(templates))
+		for i, tmpl := range templates {
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, templateData{request: req}); err != nil {
+				return nil, fmt.Errorf("render %s header template: %w", name, err)
+			}
+			vals[i] = sb.String()
+		}
+		headers[name] = vals
+	}
+	return headers, nil
+}
+
+// templatePathValues returns the literal names passed to PathValue within a template tree.
+func templatePathValues(node parse.Node) []string {
+	var names []string
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			names = append(names, templatePathValues(child)...)
+		}
+	case *parse.ActionNode:
+		names = append(names, templatePathValues(n.Pipe)...)
+	case *parse.IfNode:
+		names = append(names, templatePathValues(&n.BranchNode)...)
+	case *parse.RangeNode:
+		names = append(names, templatePathValues(&n.BranchNode)...)
+	case *parse.WithNode:
+		names = append(names, templatePathValues(&n.BranchNode)...)
+	case *parse.BranchNode:
+		names = append(names, templatePathValues(n.Pipe)...)
+		names = append(names, templatePathValues(n.List)...)
+		names = append(names, templatePathValues(n.ElseList)...)
+	case *parse.TemplateNode:
+		names = append(names, templatePathValues(n.Pipe)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			names = append(names, templatePathValues(cmd)...)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) >= 2 {
+			if field, ok := n.Args[0].(*parse.FieldNode); ok && slices.Equal(field.Ident, []string{"PathValue"}) {
+				if str, ok := n.Args[1].(*parse.StringNode); ok {
+					names = append(names, str.Text)
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			names = append(names, templatePathValues(arg)...)
+		}
+	}
+
+	return names
+}
+
+// rewriteJSONFields rewrites field chains on .JSON, like .JSON.user.name, into calls to jsonField,
+// so bodies that aren't JSON and missing fields render empty rather than failing the template.
+// Missing fields are "" so they print empty, except in range pipelines, where nil ranges over
+// nothing.
+func rewriteJSONFields(node parse.Node) {
+	rewriteJSONFieldsIn(node, false)
+}
+
+func rewriteJSONFieldsIn(node parse.Node, inRange bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			rewriteJSONFieldsIn(child, false)
+		}
+	case *parse.ActionNode:
+		rewriteJSONFieldsIn(n.Pipe, false)
+	case *parse.IfNode:
+		rewriteJSONFieldsIn(&n.BranchNode, false)
+	case *parse.RangeNode:
+		rewriteJSONFieldsIn(n.Pipe, true)
+		rewriteJSONFieldsIn(n.List, false)
+		rewriteJSONFieldsIn(n.ElseList, false)
+	case *parse.WithNode:
+		rewriteJSONFieldsIn(&n.BranchNode, false)
+	case *parse.BranchNode:
+		rewriteJSONFieldsIn(n.Pipe, false)
+		rewriteJSONFieldsIn(n.List, false)
+		rewriteJSONFieldsIn(n.ElseList, false)
+	case *parse.TemplateNode:
+		rewriteJSONFieldsIn(n.Pipe, false)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			rewriteJSONFieldsIn(cmd, inRange)
+		}
+	case *parse.CommandNode:
+		for i, arg := range n.Args {
+			field, ok := arg.(*parse.FieldNode)
+			if !ok || field.Ident[0] != "JSON" {
+				rewriteJSONFieldsIn(arg, false)
+				continue
+			}
+			// (jsonField "" .JSON "user" "name")
+			var missing parse.Node = &parse.StringNode{NodeType: parse.NodeString, Pos: field.Pos, Quoted: `""`}
+			if inRange && len(n.Args) == 1 {
+				missing = &parse.NilNode{NodeType: parse.NodeNil, Pos: field.Pos}
+			}
+			call := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: field.Pos, Args: []parse.Node{
+				&parse.IdentifierNode{NodeType: parse.NodeIdentifier, Pos: field.Pos, Ident: "jsonField"},
+				missing,
+				&parse.FieldNode{NodeType: parse.NodeField, Pos: field.Pos, Ident: []string{"JSON"}},
+			}}
+			for _, key := range field.Ident[1:] {
+				call.Args = append(call.Args, &parse.StringNode{NodeType: parse.NodeString, Pos: field.Pos, Quoted: strconv.Quote(key), Text: key})
+			}
+			n.Args[i] = &parse.PipeNode{NodeType: parse.NodePipe, Pos: field.Pos, Cmds: []*parse.CommandNode{call}}
+		}
+	}
+}
+
+// jsonField returns the value at the path of keys within decoded JSON, or missing if there's none.
+func jsonField(missing, v any, keys ...string) any {
+	for _, key := range keys {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return missing
+		}
+		v = obj[key]
+	}
+	if v == nil {
+		return missing
+	}
+	return v
+}
@@ -0,0 +1,271 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caproven/mock-server/internal/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathParams(t *testing.T) {
+	cases := map[string][]string{
+		"/users":                     nil,
+		"/users/{id}":                {"id"},
+		"/users/{id}/posts/{postID}": {"id", "postID"},
+		"/files/{rest...}":           {"rest"},
+		"/exact/{$}":                 nil,
+	}
+
+	for path, want := range cases {
+		t.Run(path, func(t *testing.T) {
+			assert.Equal(t, want, PathParams(path))
+		})
+	}
+}
+
+func TestResponseTemplate(t *testing.T) {
+	t.Run("invalid syntax", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseTemplate("{{ .PathValue ", nil))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+
+	t.Run("undeclared path value", func(t *testing.T) {
+		texts := []string{
+			`{{ .PathValue "id" }}`,
+			`{{ if true }}{{ .PathValue "id" }}{{ end }}`,
+			`{{ printf "%s" (.PathValue "id") }}`,
+		}
+		for _, text := range texts {
+			resp, err := NewResponse(WithResponseTemplate(text, []string{"other"}))
+			assert.Error(t, err, text)
+			assert.Zero(t, resp)
+		}
+	})
+
+	t.Run("renders path values", func(t *testing.T) {
+		path := "/users/{id}/files/{rest...}"
+		resp, err := NewResponse(WithResponseTemplate(`{"id":"{{ .PathValue "id" }}","file":"{{ .PathValue "rest" }}"}`, PathParams(path)))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint(path, http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42/files/a/b.txt", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"id":"42","file":"a/b.txt"}`, w.Body.String())
+	})
+
+	t.Run("renders protocol", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseTemplate(`{{ .Proto }}`, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "HTTP/1.1", w.Body.String())
+	})
+
+	t.Run("renders json body", func(t *testing.T) {
+		text := `{{ .JSON.user.name }}|{{ .JSON.count }}|{{ .JSON.user.missing }}|{{ if .JSON.admin }}admin{{ end }}|{{ printf "%s!" .JSON.user.name }}|{{ range .JSON.tags }}{{ . }},{{ end }}`
+		resp, err := NewResponse(WithResponseTemplate(text, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodPost, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		cases := map[string]string{
+			`{"user":{"name":"ada"},"count":12345678901,"admin":true,"tags":["a","b"]}`: "ada|12345678901||admin|ada!|a,b,",
+			`{"user":"ada"}`:  "||||!|",
+			`not json`:        "||||!|",
+			`{"a":1} {"b":2}`: "||||!|",
+			``:                "||||!|",
+		}
+		for body, want := range cases {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+			assert.Equal(t, http.StatusOK, w.Code, body)
+			assert.Equal(t, want, w.Body.String(), body)
+		}
+	})
+
+	t.Run("json body shared with request schema", func(t *testing.T) {
+		schema, err := jsonschema.Compile([]byte(`{"type":"object","required":["name"]}`))
+		require.NoError(t, err)
+		resp, err := NewResponse(WithResponseTemplate(`hello {{ .JSON.name }}`, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodPost, StaticResponse(resp), WithRequestSchema(schema, nil))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"ada"}`)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello ada", w.Body.String())
+	})
+
+	t.Run("execution error", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseTemplate(`{{ .Missing }}`, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/broken", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestResponseHeaderTemplates(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewResponse(WithResponseHeaderTemplates(map[string][]string{"Location": {"{{ .PathValue "}}, nil))
+		assert.Error(t, err)
+		_, err = NewResponse(WithResponseHeaderTemplates(map[string][]string{"Location": {`/users/{{ .PathValue "id" }}`}}, []string{"other"}))
+		assert.Error(t, err, "undeclared path value")
+	})
+
+	t.Run("renders", func(t *testing.T) {
+		t.Setenv("MOCK_HEADER_TEST", "eu-west-1")
+		path := "/users/{id}"
+		resp, err := NewResponse(
+			WithResponseStatus(http.StatusCreated),
+			WithResponseHeaders(map[string]string{"Location": "/placeholder", "X-Static": "static"}),
+			WithResponseHeaderTemplates(map[string][]string{
+				"Location": {`/users/{{ .PathValue "id" }}`},
+				"Link":     {`</users/{{ .PathValue "id" }}/posts>; rel="posts"`, `<{{ env "MOCK_HEADER_TEST" }}>; rel="region"`},
+			}, PathParams(path)),
+		)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint(path, http.MethodPut, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/users/42", nil))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/users/42", w.Header().Get("Location"), "templates replace static headers")
+		assert.Equal(t, "static", w.Header().Get("X-Static"))
+		assert.Equal(t, []string{`</users/42/posts>; rel="posts"`, `<eu-west-1>; rel="region"`}, w.Header().Values("Link"))
+	})
+
+	t.Run("execution error", func(t *testing.T) {
+		resp, err := NewResponse(WithResponseHeaderTemplates(map[string][]string{"X-Broken": {`{{ .Missing }}`}}, nil))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/broken", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("fallback response", func(t *testing.T) {
+		resp, err := NewResponse(
+			WithResponseStatus(http.StatusNotFound),
+			WithResponseHeaderTemplates(map[string][]string{"X-Proto": {`{{ .Proto }}`}}, nil),
+		)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		handler := FallbackHandler(http.NewServeMux(), Fallbacks{NotFound: &resp})
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "HTTP/1.1", w.Header().Get("X-Proto"))
+	})
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Setenv("MOCK_TEMPLATE_TEST", "from-env")
+
+	render := func(t *testing.T, text string, numGen numberGenerator) (int, string) {
+		t.Helper()
+		resp, err := NewResponse(withResponseTemplate(text, nil, numGen))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("uuid", func(t *testing.T) {
+		_, first := render(t, `{{ uuid }}`, nil)
+		_, second := render(t, `{{ uuid }}`, nil)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("now", func(t *testing.T) {
+		_, body := render(t, `{{ now "2006" }}`, nil)
+		assert.Equal(t, time.Now().Format("2006"), body)
+	})
+
+	t.Run("randInt", func(t *testing.T) {
+		_, body := render(t, `{{ randInt 10 20 }}`, &mockNumGenerator{val: 3})
+		assert.Equal(t, "13", body)
+
+		code, _ := render(t, `{{ randInt 20 10 }}`, &mockNumGenerator{})
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		_, body := render(t, `{{ env "MOCK_TEMPLATE_TEST" }}`, nil)
+		assert.Equal(t, "from-env", body)
+	})
+}
+
+func BenchmarkResponseTemplate(b *testing.B) {
+	path := "/users/{id}"
+	resp, err := NewResponse(WithResponseTemplate(`{"id":"{{ .PathValue "id" }}","name":"user"}`, PathParams(path)))
+	require.NoError(b, err)
+	endpoint, err := NewEndpoint(path, http.MethodGet, StaticResponse(resp))
+	require.NoError(b, err)
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, []*Endpoint{endpoint})
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	w := &discardResponseWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		mux.ServeHTTP(w, req)
+	}
+}
+
+// discardResponseWriter is a minimal http.ResponseWriter for benchmarks that don't inspect output.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (w *discardResponseWriter) WriteHeader(int) {}
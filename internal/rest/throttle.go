@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// throttleChunksPerSecond is how many chunks a throttled body is split into per second of transfer.
+const throttleChunksPerSecond = 10
+
+// WithTimeToFirstByte waits d before writing anything, once the response is ready to be sent. Unlike
+// a delay, it applies only to responses that are written normally.
+func WithTimeToFirstByte(d time.Duration) ResponseOption {
+	return func(r *Response) error {
+		if d < 0 {
+			return fmt.Errorf("time to first byte cannot be negative: %s", d)
+		}
+		r.ttfb = d
+		return nil
+	}
+}
+
+// WithThrottle caps the rate the body is written at, flushing it to the client in chunks. The chunks
+// are sized to send several a second, unless the response sets a chunk size.
+func WithThrottle(bytesPerSecond int) ResponseOption {
+	return func(r *Response) error {
+		if bytesPerSecond <= 0 {
+			return fmt.Errorf("throttle must be at least 1 byte per second: %d", bytesPerSecond)
+		}
+		r.throttle = bytesPerSecond
+		return nil
+	}
+}
+
+// wait sleeps for d, extending the write deadline so the wait doesn't count against the write
+// timeout.
+func (o handlerOptions) wait(w http.ResponseWriter, d time.Duration) {
+	if o.writeTimeout > 0 {
+		deadline := time.Now().Add(d + o.writeTimeout)
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			slog.Warn("failed to extend write deadline", "err", err)
+		}
+	}
+	time.Sleep(d)
+}
+
+// throttledResponseWriter writes the body in chunks spaced out to match a byte rate. The write
+// deadline is extended before each chunk, so only a stalled client hits the write timeout.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	r            *http.Request
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+	chunkSize    int
+	interval     time.Duration
+	started      bool
+}
+
+func (o handlerOptions) throttle(w http.ResponseWriter, r *http.Request, bytesPerSecond, chunkSize int) *throttledResponseWriter {
+	if chunkSize == 0 {
+		chunkSize = max(bytesPerSecond/throttleChunksPerSecond, 1)
+	}
+	return &throttledResponseWriter{
+		ResponseWriter: w,
+		r:              r,
+		rc:             http.NewResponseController(w),
+		writeTimeout:   o.writeTimeout,
+		chunkSize:      chunkSize,
+		interval:       time.Duration(chunkSize) * time.Second / time.Duration(bytesPerSecond),
+	}
+}
+
+func (t *throttledResponseWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		if t.started {
+			select {
+			case <-t.r.Context().Done():
+				return written, t.r.Context().Err()
+			case <-time.After(t.interval):
+			}
+		}
+		t.started = true
+
+		if t.writeTimeout > 0 {
+			err := t.rc.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				slog.Warn("failed to extend write deadline", "err", err)
+			}
+		}
+		n, err := t.ResponseWriter.Write(p[:min(t.chunkSize, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if err := t.rc.Flush(); err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (t *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeToFirstByteAndThrottle(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewResponse(WithTimeToFirstByte(-time.Second))
+		assert.Error(t, err)
+
+		_, err = NewResponse(WithThrottle(0))
+		assert.Error(t, err)
+	})
+
+	serve := func(t *testing.T, opts ...ResponseOption) (*http.Response, time.Duration) {
+		t.Helper()
+		resp, err := NewResponse(append(opts, WithResponseStatus(http.StatusOK))...)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/slow", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint}, WithWriteTimeout(time.Second))
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		start := time.Now()
+		got, err := server.Client().Get(server.URL + "/slow")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = got.Body.Close() })
+		return got, time.Since(start)
+	}
+
+	t.Run("ttfb", func(t *testing.T) {
+		got, firstByte := serve(t, WithTimeToFirstByte(50*time.Millisecond), WithResponseBody([]byte("ok")))
+		assert.GreaterOrEqual(t, firstByte, 50*time.Millisecond)
+
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		// 10 byte chunks every 100ms
+		full := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+		got, firstByte := serve(t, WithThrottle(100), WithResponseBody(full))
+
+		start := time.Now()
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Equal(t, full, body)
+		assert.GreaterOrEqual(t, firstByte+time.Since(start), 300*time.Millisecond)
+	})
+
+	t.Run("throttled generated body", func(t *testing.T) {
+		got, firstByte := serve(t, WithThrottle(100), WithGeneratedBody(25, 1))
+
+		start := time.Now()
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Len(t, body, 25)
+		assert.GreaterOrEqual(t, firstByte+time.Since(start), 200*time.Millisecond)
+	})
+}
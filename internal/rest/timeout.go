@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestTimeout answers requests with a response, normally a 504, when they aren't answered in
+// time. Unlike http.TimeoutHandler, responses aren't buffered: once a response starts, such as an
+// event stream or a WebSocket upgrade, it's left to finish however long it takes.
+type RequestTimeout struct {
+	timeout  time.Duration
+	response Response
+}
+
+// NewRequestTimeout returns a RequestTimeout answering requests with resp once timeout passes
+// without a response.
+func NewRequestTimeout(timeout time.Duration, resp Response) *RequestTimeout {
+	return &RequestTimeout{timeout: timeout, response: resp}
+}
+
+// Handler wraps next, answering with the timeout's response if next hasn't started responding
+// within the timeout. next's request context is then canceled, and anything it writes afterwards is
+// discarded. Responses that started in time keep their context.
+func (t *RequestTimeout) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		timer := time.NewTimer(t.timeout)
+		defer timer.Stop()
+
+		tw := &timeoutWriter{w: w, header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		tw.mu.Lock()
+		if tw.started {
+			// too late to answer instead, let the response finish
+			tw.mu.Unlock()
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+			}
+			return
+		}
+		tw.timedOut = true
+		tw.mu.Unlock()
+		cancel()
+		writePlainResponse(w, r, t.response)
+	})
+}
+
+// timeoutWriter holds a response's headers until it starts, so a timeout can answer instead of
+// it, and discards writes once it has.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu       sync.Mutex
+	started  bool
+	timedOut bool
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.header
+}
+
+func (t *timeoutWriter) WriteHeader(statusCode int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.start(statusCode)
+}
+
+func (t *timeoutWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	t.start(http.StatusOK)
+	return t.w.Write(p)
+}
+
+// start sends the response's headers if it hasn't started yet. It's called with mu held.
+func (t *timeoutWriter) start(statusCode int) {
+	if t.timedOut || t.started {
+		return
+	}
+	for k, v := range t.header {
+		t.w.Header()[k] = v
+	}
+	// informational responses come before the final one, which can still time out
+	if statusCode >= http.StatusOK || statusCode == http.StatusSwitchingProtocols {
+		t.started = true
+	}
+	t.w.WriteHeader(statusCode)
+}
+
+func (t *timeoutWriter) FlushError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	t.start(http.StatusOK)
+	return http.NewResponseController(t.w).Flush()
+}
+
+func (t *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	// the connection is the handler's now, so the timeout can't answer on it
+	t.started = true
+	return http.NewResponseController(t.w).Hijack()
+}
+
+func (t *timeoutWriter) Unwrap() http.ResponseWriter {
+	return t.w
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	timeoutResp, err := NewResponse(
+		WithResponseStatus(http.StatusGatewayTimeout),
+		WithResponseBody([]byte(`{"error": "timeout"}`)),
+	)
+	require.NoError(t, err)
+	serve := func(t *testing.T, delay time.Duration) *httptest.ResponseRecorder {
+		t.Helper()
+		resp, err := NewResponse(
+			WithResponseStatus(http.StatusCreated),
+			WithResponseHeaders(map[string]string{"X-Custom": "value"}),
+			WithResponseBody([]byte("created")),
+			WithResponseDelay(delay),
+		)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/orders", http.MethodPost, StaticResponse(resp))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		rec := httptest.NewRecorder()
+		handler := NewRequestTimeout(50*time.Millisecond, timeoutResp).Handler(mux)
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+		return rec
+	}
+
+	t.Run("in time", func(t *testing.T) {
+		rec := serve(t, 0)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "value", rec.Header().Get("X-Custom"))
+		assert.Equal(t, "created", rec.Body.String())
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		start := time.Now()
+		rec := serve(t, time.Second)
+		assert.Less(t, time.Since(start), time.Second, "the timeout doesn't wait for the delay")
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Custom"), "the late response's headers aren't sent")
+		assert.Equal(t, `{"error": "timeout"}`, rec.Body.String())
+	})
+
+	t.Run("started responses finish", func(t *testing.T) {
+		resp, err := NewResponse(WithEventStream([]ServerSentEvent{
+			{Data: "first"},
+			{Data: "second", Delay: 100 * time.Millisecond},
+		}, false))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/events", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		server := httptest.NewServer(NewRequestTimeout(50*time.Millisecond, timeoutResp).Handler(mux))
+		t.Cleanup(server.Close)
+
+		got, err := http.Get(server.URL + "/events")
+		require.NoError(t, err)
+		defer got.Body.Close()
+		assert.Equal(t, http.StatusOK, got.StatusCode)
+		line, err := bufio.NewReader(got.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "data: first\n", line)
+		body, err := io.ReadAll(got.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "data: second", "the stream's delay isn't cut off at the timeout")
+	})
+
+	t.Run("panics propagate", func(t *testing.T) {
+		handler := NewRequestTimeout(time.Second, timeoutResp).Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		assert.Panics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/caproven/mock-server/internal/jsonschema"
+)
+
+// requiredQuery rejects requests missing any of a set of query parameters.
+type requiredQuery struct {
+	params []string
+	// response replaces the default 400 listing the missing parameters, if set.
+	response *Response
+}
+
+// WithRequiredQuery rejects requests missing any of params from their query string before the
+// endpoint's strategy is consulted. They get resp if it's non-nil, otherwise a 400 naming the
+// missing parameters.
+func WithRequiredQuery(params []string, resp *Response) EndpointOption {
+	return func(e *Endpoint) error {
+		if len(params) == 0 {
+			return errors.New("no required query parameters")
+		}
+		for _, param := range params {
+			if param == "" {
+				return errors.New("required query parameter name cannot be empty")
+			}
+		}
+		e.requiredQuery = &requiredQuery{params: params, response: resp}
+		return nil
+	}
+}
+
+// check returns the response for a request missing required parameters, or false if none are
+// missing. Parameters with empty values are present.
+func (q *requiredQuery) check(r *http.Request) (Response, bool) {
+	query := r.URL.Query()
+	var missing []string
+	for _, param := range q.params {
+		if !query.Has(param) {
+			missing = append(missing, param)
+		}
+	}
+	if len(missing) == 0 {
+		return Response{}, false
+	}
+
+	if q.response != nil {
+		return *q.response, true
+	}
+	return Response{
+		statusCode: http.StatusBadRequest,
+		headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		body:       fmt.Appendf(nil, "missing required query parameters: %s\n", strings.Join(missing, ", ")),
+	}, true
+}
+
+// requestSchema rejects requests whose body isn't JSON valid against a schema.
+type requestSchema struct {
+	schema *jsonschema.Schema
+	// response replaces the default 400 listing the validation errors, if set.
+	response *Response
+}
+
+// WithRequestSchema rejects requests whose body isn't JSON matching schema before the endpoint's
+// strategy is consulted. They get resp if it's non-nil, otherwise a 400 with a JSON body listing
+// the validation errors. The body is buffered, so later handlers can still read it.
+func WithRequestSchema(schema *jsonschema.Schema, resp *Response) EndpointOption {
+	return func(e *Endpoint) error {
+		if schema == nil {
+			return errors.New("no request schema")
+		}
+		e.requestSchema = &requestSchema{schema: schema, response: resp}
+		return nil
+	}
+}
+
+// check returns the response for a request with an invalid body, or false if the body is valid.
+func (s *requestSchema) check(r *http.Request) (Response, bool) {
+	if _, err := readBody(r); err != nil {
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			return tooLargeResponse(r), true
+		}
+		slog.Warn("failed to read request body", "err", err)
+		return Response{statusCode: http.StatusBadRequest}, true
+	}
+
+	// decoded through the request so templates reuse the document rather than decoding it again
+	var errs []jsonschema.ValidationError
+	if doc, err := decodeRequestJSON(r); err != nil {
+		errs = []jsonschema.ValidationError{{Message: err.Error()}}
+	} else {
+		errs = s.schema.ValidateValue(doc)
+	}
+	if len(errs) == 0 {
+		return Response{}, false
+	}
+
+	if s.response != nil {
+		return *s.response, true
+	}
+	data, err := json.Marshal(struct {
+		Errors []jsonschema.ValidationError `json:"errors"`
+	}{Errors: errs})
+	if err != nil {
+		slog.Error("failed to encode validation errors", "err", err)
+		return Response{statusCode: http.StatusBadRequest}, true
+	}
+	return Response{
+		statusCode: http.StatusBadRequest,
+		headers:    map[string]string{"Content-Type": "application/json"},
+		body:       data,
+	}, true
+}
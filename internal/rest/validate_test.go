@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caproven/mock-server/internal/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredQuery(t *testing.T) {
+	ok := StaticResponse(Response{statusCode: http.StatusOK})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewEndpoint("/", http.MethodGet, ok, WithRequiredQuery(nil, nil))
+		assert.Error(t, err)
+
+		_, err = NewEndpoint("/", http.MethodGet, ok, WithRequiredQuery([]string{"id", ""}, nil))
+		assert.Error(t, err)
+	})
+
+	custom := Response{statusCode: http.StatusUnprocessableEntity, body: []byte("bad request")}
+	cases := map[string]struct {
+		resp     *Response
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		"all present":       {target: "/search?id=1&token=abc", wantCode: http.StatusOK},
+		"empty value":       {target: "/search?id=&token", wantCode: http.StatusOK},
+		"one missing":       {target: "/search?id=1", wantCode: http.StatusBadRequest, wantBody: "missing required query parameters: token\n"},
+		"all missing":       {target: "/search", wantCode: http.StatusBadRequest, wantBody: "missing required query parameters: id, token\n"},
+		"custom response":   {resp: &custom, target: "/search?token=abc", wantCode: http.StatusUnprocessableEntity, wantBody: "bad request"},
+		"custom no missing": {resp: &custom, target: "/search?id=1&token=abc", wantCode: http.StatusOK},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			endpoint, err := NewEndpoint("/search", http.MethodGet, ok, WithRequiredQuery([]string{"id", "token"}, tc.resp))
+			require.NoError(t, err)
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestSchema(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`))
+	require.NoError(t, err)
+
+	custom := Response{statusCode: http.StatusUnprocessableEntity}
+	cases := map[string]struct {
+		resp     *Response
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		"valid":           {body: `{"name": "ada"}`, wantCode: http.StatusOK},
+		"invalid":         {body: `{"name": 1}`, wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"path":"/name","message":"expected string but got number"}]}`},
+		"not json":        {body: `name=ada`, wantCode: http.StatusBadRequest},
+		"empty":           {body: ``, wantCode: http.StatusBadRequest},
+		"custom response": {resp: &custom, body: `{}`, wantCode: http.StatusUnprocessableEntity},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resolver := &bodyRecordingResolver{}
+			endpoint, err := NewEndpoint("/users", http.MethodPost, resolver, WithRequestSchema(schema, tc.resp))
+			require.NoError(t, err)
+			mux := http.NewServeMux()
+			RegisterHandlers(mux, []*Endpoint{endpoint})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, tc.body, resolver.body, "body is restored after validation")
+			}
+		})
+	}
+
+	t.Run("decoded once", func(t *testing.T) {
+		resolver := &jsonRecordingResolver{}
+		endpoint, err := NewEndpoint("/users", http.MethodPost, resolver, WithRequestSchema(schema, nil))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "ada", "age": 36}`)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, resolver.decoded, "validation should leave the decoded body for templates")
+		assert.Equal(t, map[string]any{"name": "ada", "age": json.Number("36")}, resolver.json)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		endpoint, err := NewEndpoint("/users", http.MethodPost, &bodyRecordingResolver{}, WithRequestSchema(schema, nil))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "ada lovelace"}`))
+		req.ContentLength = -1
+		LimitRequestBytes(mux, 8, nil).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("too large response", func(t *testing.T) {
+		tooLarge, err := NewResponse(
+			WithResponseStatus(http.StatusRequestEntityTooLarge),
+			WithResponseBody([]byte(`{"error": "too large"}`)),
+		)
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/users", http.MethodPost, &bodyRecordingResolver{}, WithRequestSchema(schema, nil))
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "ada lovelace"}`))
+		req.ContentLength = -1
+		LimitRequestBytes(mux, 8, &tooLarge).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.JSONEq(t, `{"error": "too large"}`, rec.Body.String())
+	})
+}
+
+// bodyRecordingResolver records the body of the last request it resolved.
+type bodyRecordingResolver struct {
+	body string
+}
+
+func (b *bodyRecordingResolver) NextResponse(r *http.Request) Response {
+	data, _ := io.ReadAll(r.Body)
+	b.body = string(data)
+	return Response{statusCode: http.StatusOK}
+}
+
+// jsonRecordingResolver records whether the last request's body was already decoded as JSON when
+// it was resolved, and the decoded body.
+type jsonRecordingResolver struct {
+	decoded bool
+	json    any
+}
+
+func (j *jsonRecordingResolver) NextResponse(r *http.Request) Response {
+	buffered, ok := r.Body.(*bufferedBody)
+	j.decoded = ok && buffered.decoded
+	j.json = requestJSON(r)
+	return Response{statusCode: http.StatusOK}
+}
@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ReadinessPath reports whether the server has finished warming up.
+const ReadinessPath = "/readyz"
+
+// Warmup simulates a backend that's slow to become ready. Until its delay has passed since Start,
+// every request gets its response instead of being routed.
+type Warmup struct {
+	delay    time.Duration
+	response Response
+	ready    atomic.Bool
+}
+
+// NewWarmup builds a warmup lasting delay, during which requests get resp.
+func NewWarmup(delay time.Duration, resp Response) *Warmup {
+	return &Warmup{delay: delay, response: resp}
+}
+
+// Start begins the warmup, marking the server ready once its delay has passed.
+func (w *Warmup) Start() {
+	time.AfterFunc(w.delay, func() {
+		w.ready.Store(true)
+		slog.Info("warmup finished, serving requests")
+	})
+}
+
+// Handler wraps next so requests get the warmup's response until it's ready. During the warmup it
+// also answers ReadinessPath, prefixed with basePath, itself with a 503. After, it's passed to next
+// like any other request, so a Drain can report readiness.
+func (w *Warmup) Handler(next http.Handler, basePath string) http.Handler {
+	readinessPath := basePath + ReadinessPath
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if w.ready.Load() {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if r.URL.Path == readinessPath && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			http.Error(rw, "warming up", http.StatusServiceUnavailable)
+			return
+		}
+		writePlainResponse(rw, r, w.response)
+	})
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("routed"))
+	})
+	resp, err := NewResponse(WithResponseStatus(http.StatusServiceUnavailable), WithResponseBody([]byte("starting")))
+	require.NoError(t, err)
+
+	warmup := NewWarmup(50*time.Millisecond, resp)
+	handler := warmup.Handler(next, "")
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// not ready until started
+	w := serve("/users")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "starting", w.Body.String())
+	assert.Equal(t, http.StatusServiceUnavailable, serve(ReadinessPath).Code)
+
+	warmup.Start()
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/users").Code)
+
+	require.Eventually(t, func() bool {
+		return serve(ReadinessPath).Code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	w = serve("/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "routed", w.Body.String())
+
+	// under a base path, the unprefixed readiness path is just another request
+	prefixed := NewWarmup(time.Minute, resp).Handler(next, "/api")
+	w = httptest.NewRecorder()
+	prefixed.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api"+ReadinessPath, nil))
+	assert.Equal(t, "warming up\n", w.Body.String())
+	w = httptest.NewRecorder()
+	prefixed.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+	assert.Equal(t, "starting", w.Body.String())
+}
@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/caproven/mock-server/internal/websocket"
+)
+
+type WebSocketMessage struct {
+	Text string
+	// Delay is waited before sending the message.
+	Delay time.Duration
+}
+
+type webSocketBehavior struct {
+	echo   bool
+	script []WebSocketMessage
+}
+
+// WithWebSocket upgrades requests to a WebSocket connection instead of writing a response. The
+// scripted messages are sent in order, and if echo is set, messages received are sent back.
+func WithWebSocket(echo bool, script []WebSocketMessage) ResponseOption {
+	return func(r *Response) error {
+		if !echo && len(script) == 0 {
+			return errors.New("websocket must echo or have scripted messages")
+		}
+		for _, msg := range script {
+			if msg.Delay < 0 {
+				return errors.New("websocket message delay cannot be negative")
+			}
+		}
+		r.websocket = &webSocketBehavior{echo: echo, script: script}
+		return nil
+	}
+}
+
+func serveWebSocket(w http.ResponseWriter, r *http.Request, behavior *webSocketBehavior) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		slog.Warn("failed to upgrade websocket", "err", err)
+		return
+	}
+
+	// The request context isn't cancelled once hijacked, so the read loop ends the script instead.
+	ctx, cancel := context.WithCancel(context.Background())
+	scriptDone := make(chan struct{})
+	go func() {
+		defer close(scriptDone)
+		for _, msg := range behavior.script {
+			if err := sleepContext(ctx, msg.Delay); err != nil {
+				return
+			}
+			if err := conn.WriteMessage(websocket.OpText, []byte(msg.Text)); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, websocket.ErrClosed) {
+				slog.Info("websocket read ended", "path", r.URL.Path, "err", err)
+			}
+			break
+		}
+		if behavior.echo {
+			if err := conn.WriteMessage(op, msg); err != nil {
+				break
+			}
+		}
+	}
+
+	cancel()
+	<-scriptDone
+	_ = conn.Close(websocket.CloseNormal, "")
+}
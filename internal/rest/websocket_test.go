@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocket(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		resp, err := NewResponse(WithWebSocket(false, nil))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+
+		resp, err = NewResponse(WithWebSocket(false, []WebSocketMessage{{Text: "a", Delay: -time.Second}}))
+		assert.Error(t, err)
+		assert.Zero(t, resp)
+	})
+
+	t.Run("scripted messages", func(t *testing.T) {
+		resp, err := NewResponse(WithWebSocket(false, []WebSocketMessage{
+			{Text: "first"},
+			{Text: "second", Delay: 10 * time.Millisecond},
+		}))
+		require.NoError(t, err)
+		endpoint, err := NewEndpoint("/ws", http.MethodGet, StaticResponse(resp))
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, []*Endpoint{endpoint})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+		require.NoError(t, err)
+
+		br := bufio.NewReader(conn)
+		got, err := http.ReadResponse(br, nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSwitchingProtocols, got.StatusCode)
+
+		for _, want := range []string{"first", "second"} {
+			header := make([]byte, 2)
+			_, err := io.ReadFull(br, header)
+			require.NoError(t, err)
+			assert.Equal(t, byte(0x81), header[0])
+			payload := make([]byte, header[1])
+			_, err = io.ReadFull(br, payload)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(payload))
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package rest
+
+// WeightShare is the chance of a response being chosen for a request.
+type WeightShare struct {
+	// Strategy is "weighted" for a weighted response, or "fault" for a fault overlaid on one.
+	Strategy string
+	Index    int
+	// Status is the response's status, or 0 if it has none: a connection reset, or a truncate fault
+	// that keeps the status of the response it truncates.
+	Status int
+	Fault  ConnectionFault
+	// Weight is the response's current weight, or 0 for faults.
+	Weight      float64
+	Probability float64
+}
+
+// EndpointWeights are the chances of each response of an endpoint using a weighted strategy.
+type EndpointWeights struct {
+	ID     string
+	Shares []WeightShare
+}
+
+// weightedResolver is implemented by resolvers that choose responses by weight.
+type weightedResolver interface {
+	// weightShares returns the current chance of each response being chosen.
+	weightShares() []WeightShare
+}
+
+// Weights returns the current chances of each response being chosen, for the endpoints using a
+// weighted strategy, including those wrapped with faults or kept per client. Endpoints using other
+// strategies are left out.
+func Weights(endpoints []*Endpoint) []EndpointWeights {
+	var weights []EndpointWeights
+	for _, e := range endpoints {
+		resolver, ok := e.responseResolver.(weightedResolver)
+		if !ok {
+			continue
+		}
+		if shares := resolver.weightShares(); len(shares) > 0 {
+			weights = append(weights, EndpointWeights{ID: e.ID(), Shares: shares})
+		}
+	}
+	return weights
+}
+
+func (w *WeightedResponse) weightShares() []WeightShare {
+	if w.limits != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	weights, weightTotal := w.weights, w.weightTotal
+	scale := 1.0
+	if w.ramp != nil {
+		weights, weightTotal = w.ramp.weights()
+		scale = rampScale
+	}
+
+	shares := make([]WeightShare, len(w.responses))
+	prev := 0.0
+	for i, resp := range w.responses {
+		weight := weights[i] - prev
+		prev = weights[i]
+		shares[i] = WeightShare{
+			Strategy:    "weighted",
+			Index:       i,
+			Fault:       resp.connFault,
+			Weight:      weight / scale,
+			Probability: weight / weightTotal,
+		}
+		if resp.connFault != ConnectionFaultReset {
+			shares[i].Status = resp.statusCode
+		}
+	}
+	return shares
+}
+
+// weightShares lists the faults before the wrapped strategy's responses, whose chances are scaled
+// by the chance of no fault triggering. It reports nothing if the wrapped strategy isn't weighted.
+func (f *FaultResponse) weightShares() []WeightShare {
+	resolver, ok := f.resolver.(weightedResolver)
+	if !ok {
+		return nil
+	}
+
+	var shares []WeightShare
+	remaining := 1.0
+	for i, fault := range f.faults {
+		share := WeightShare{
+			Strategy:    "fault",
+			Index:       i,
+			Fault:       fault.Response.connFault,
+			Probability: remaining * fault.Probability,
+		}
+		if fault.Response.connFault == "" {
+			share.Status = fault.Response.statusCode
+		}
+		shares = append(shares, share)
+		remaining -= share.Probability
+	}
+	for _, share := range resolver.weightShares() {
+		share.Probability *= remaining
+		shares = append(shares, share)
+	}
+	return shares
+}
+
+// weightShares reports the wrapped strategy's chances outside of outages.
+func (o *OutageResponse) weightShares() []WeightShare {
+	if resolver, ok := o.resolver.(weightedResolver); ok {
+		return resolver.weightShares()
+	}
+	return nil
+}
+
+func (p *PerClientResponse) weightShares() []WeightShare {
+	if resolver, ok := p.prototype.(weightedResolver); ok {
+		return resolver.weightShares()
+	}
+	return nil
+}
+
+func (s *stickyResolver) weightShares() []WeightShare {
+	if resolver, ok := s.resolver.(weightedResolver); ok {
+		return resolver.weightShares()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeights(t *testing.T) {
+	ok := Response{statusCode: http.StatusOK}
+	unavailable := Response{statusCode: http.StatusServiceUnavailable}
+
+	weighted, err := NewWeightedResponse([]WeightedResponseEntry{
+		{Response: ok, Weight: 3},
+		{Response: unavailable, Weight: 1},
+	}, nil)
+	require.NoError(t, err)
+	weightedEndpoint, err := NewEndpoint("/weighted", http.MethodGet, weighted)
+	require.NoError(t, err)
+
+	faulted, err := NewFaultResponse(weighted, []Fault{
+		{Probability: 0.5, Response: Response{connFault: ConnectionFaultReset}},
+		{Probability: 0.2, Response: Response{connFault: ConnectionFaultTruncate, truncateBytes: 4}},
+	}, nil)
+	require.NoError(t, err)
+	faultedEndpoint, err := NewEndpoint("/faulted", http.MethodGet, faulted)
+	require.NoError(t, err)
+
+	clk := &mockClock{now: time.Unix(0, 0)}
+	ramped, err := NewRampedWeightedResponse([]WeightedResponseEntry{
+		{Response: ok, Weight: 1},
+		{Response: unavailable, Weight: 0},
+	}, []int{0, 1}, time.Minute, nil, clk)
+	require.NoError(t, err)
+	clk.now = clk.now.Add(15 * time.Second)
+	rampedEndpoint, err := NewEndpoint("/ramped", http.MethodGet, ramped)
+	require.NoError(t, err)
+
+	static, err := NewEndpoint("/static", http.MethodGet, StaticResponse(ok))
+	require.NoError(t, err)
+
+	weights := Weights([]*Endpoint{weightedEndpoint, static, faultedEndpoint, rampedEndpoint})
+	require.Len(t, weights, 3)
+
+	assert.Equal(t, EndpointWeights{ID: "GET /weighted", Shares: []WeightShare{
+		{Strategy: "weighted", Index: 0, Status: 200, Weight: 3, Probability: 0.75},
+		{Strategy: "weighted", Index: 1, Status: 503, Weight: 1, Probability: 0.25},
+	}}, weights[0])
+
+	assert.Equal(t, "GET /faulted", weights[1].ID)
+	shares := weights[1].Shares
+	require.Len(t, shares, 4)
+	assert.Equal(t, WeightShare{Strategy: "fault", Index: 0, Fault: ConnectionFaultReset, Probability: 0.5}, shares[0])
+	assert.Equal(t, WeightShare{Strategy: "fault", Index: 1, Fault: ConnectionFaultTruncate, Probability: 0.1}, shares[1])
+	assert.InDelta(t, 0.3, shares[2].Probability, 1e-9)
+	assert.InDelta(t, 0.1, shares[3].Probability, 1e-9)
+
+	assert.Equal(t, "GET /ramped", weights[2].ID)
+	require.Len(t, weights[2].Shares, 2)
+	assert.InDelta(t, 0.75, weights[2].Shares[0].Weight, 1e-9)
+	assert.InDelta(t, 0.75, weights[2].Shares[0].Probability, 1e-9)
+	assert.InDelta(t, 0.25, weights[2].Shares[1].Probability, 1e-9)
+}
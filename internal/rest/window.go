@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type WindowMode string
+
+const (
+	// WindowModeFixed counts requests in consecutive windows, the first starting at the first request.
+	WindowModeFixed WindowMode = "fixed"
+	// WindowModeSliding counts requests in the window ending at each request.
+	WindowModeSliding WindowMode = "sliding"
+)
+
+// WindowResponse returns one response for the first limit requests within a time window and
+// another for requests exceeding it. Only allowed requests count towards the limit.
+type WindowResponse struct {
+	limit    int
+	window   time.Duration
+	mode     WindowMode
+	allowed  Response
+	exceeded Response
+	clock    clock
+
+	mu sync.Mutex
+	// windowStart and count track the current fixed window.
+	windowStart time.Time
+	count       int
+	// allowedAt holds the times of allowed requests still within a sliding window, oldest first.
+	allowedAt []time.Time
+}
+
+// NewWindowResponse builds a window strategy. If clk is nil, the system clock is used.
+func NewWindowResponse(limit int, window time.Duration, mode WindowMode, allowed, exceeded Response, clk clock) (*WindowResponse, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("window limit must be >= 1: %d", limit)
+	}
+	if window <= 0 {
+		return nil, errors.New("window duration must be positive")
+	}
+	switch mode {
+	case WindowModeFixed, WindowModeSliding:
+	default:
+		return nil, fmt.Errorf("unknown window mode %q", mode)
+	}
+	if clk == nil {
+		clk = systemClock{}
+	}
+
+	return &WindowResponse{
+		limit:    limit,
+		window:   window,
+		mode:     mode,
+		allowed:  allowed,
+		exceeded: exceeded,
+		clock:    clk,
+	}, nil
+}
+
+func (w *WindowResponse) NextResponse(r *http.Request) Response {
+	resp, _ := w.nextChosenResponse(r)
+	return resp
+}
+
+func (w *WindowResponse) nextChosenResponse(_ *http.Request) (Response, responseChoice) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.allow(w.clock.Now()) {
+		return w.allowed, responseChoice{strategy: "window", index: 0}
+	}
+	return w.exceeded, responseChoice{strategy: "window", index: 1}
+}
+
+func (w *WindowResponse) allow(now time.Time) bool {
+	if w.mode == WindowModeFixed {
+		if w.windowStart.IsZero() || !now.Before(w.windowStart.Add(w.window)) {
+			w.windowStart = now
+			w.count = 0
+		}
+		if w.count >= w.limit {
+			return false
+		}
+		w.count++
+		return true
+	}
+
+	cutoff := now.Add(-w.window)
+	expired := 0
+	for expired < len(w.allowedAt) && !w.allowedAt[expired].After(cutoff) {
+		expired++
+	}
+	w.allowedAt = w.allowedAt[expired:]
+	if len(w.allowedAt) >= w.limit {
+		return false
+	}
+	w.allowedAt = append(w.allowedAt, now)
+	return true
+}
+
+func (w *WindowResponse) fresh() ResponseResolver {
+	return &WindowResponse{
+		limit:    w.limit,
+		window:   w.window,
+		mode:     w.mode,
+		allowed:  w.allowed,
+		exceeded: w.exceeded,
+		clock:    w.clock,
+	}
+}
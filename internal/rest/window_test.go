@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowResponse(t *testing.T) {
+	allowed := Response{statusCode: http.StatusOK}
+	exceeded := Response{statusCode: http.StatusTooManyRequests}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]struct {
+			limit  int
+			window time.Duration
+			mode   WindowMode
+		}{
+			"zero limit":   {limit: 0, window: time.Second, mode: WindowModeFixed},
+			"zero window":  {limit: 1, window: 0, mode: WindowModeFixed},
+			"unknown mode": {limit: 1, window: time.Second, mode: "tumbling"},
+		}
+		for name, tc := range cases {
+			strategy, err := NewWindowResponse(tc.limit, tc.window, tc.mode, allowed, exceeded, nil)
+			assert.Error(t, err, name)
+			assert.Nil(t, strategy, name)
+		}
+	})
+
+	// each step advances the clock by its offset, then expects the given response
+	type step struct {
+		advance time.Duration
+		want    Response
+	}
+	cases := map[string]struct {
+		mode  WindowMode
+		steps []step
+	}{
+		"fixed": {
+			mode: WindowModeFixed,
+			steps: []step{
+				{0, allowed},
+				{100 * time.Millisecond, allowed},
+				{100 * time.Millisecond, exceeded},
+				// window started at the first request, so it resets at 1s
+				{799 * time.Millisecond, exceeded},
+				{time.Millisecond, allowed},
+				{0, allowed},
+				{0, exceeded},
+			},
+		},
+		"sliding": {
+			mode: WindowModeSliding,
+			steps: []step{
+				{0, allowed},
+				{600 * time.Millisecond, allowed},
+				{100 * time.Millisecond, exceeded},
+				// the first request leaves the window at 1s, the second not until 1.6s
+				{300 * time.Millisecond, allowed},
+				{0, exceeded},
+				{599 * time.Millisecond, exceeded},
+				{time.Millisecond, allowed},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			clk := &mockClock{now: start}
+			strategy, err := NewWindowResponse(2, time.Second, tc.mode, allowed, exceeded, clk)
+			require.NoError(t, err)
+
+			for i, s := range tc.steps {
+				clk.now = clk.now.Add(s.advance)
+				assert.Equal(t, s.want, strategy.NextResponse(nil), "step %d", i)
+			}
+		})
+	}
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		strategy, err := NewWindowResponse(10, time.Hour, WindowModeSliding, allowed, exceeded, &mockClock{now: start})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var allowedCount int
+		for range 50 {
+			wg.Go(func() {
+				if strategy.NextResponse(nil).statusCode == http.StatusOK {
+					mu.Lock()
+					allowedCount++
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, 10, allowedCount)
+	})
+}
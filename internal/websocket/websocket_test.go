@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	req := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	_, err = conn.Write([]byte(req))
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// example from RFC 6455
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	return &testClient{conn: conn, br: br}
+}
+
+func (c *testClient) write(t *testing.T, fin bool, op Opcode, payload []byte) {
+	t.Helper()
+
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	require.NoError(t, err)
+}
+
+func (c *testClient) read(t *testing.T) (Opcode, []byte) {
+	t.Helper()
+
+	var header [2]byte
+	_, err := io.ReadFull(c.br, header[:])
+	require.NoError(t, err)
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		_, err := io.ReadFull(c.br, ext[:])
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	require.NoError(t, err)
+	return Opcode(header[0] & 0x0F), payload
+}
+
+func echoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		for {
+			op, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(op, msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Run("rejects plain requests", func(t *testing.T) {
+		server := echoServer(t)
+		resp, err := server.Client().Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+	})
+
+	t.Run("echo", func(t *testing.T) {
+		client := dial(t, echoServer(t))
+
+		client.write(t, true, OpText, []byte("hello"))
+		op, payload := client.read(t)
+		assert.Equal(t, OpText, op)
+		assert.Equal(t, "hello", string(payload))
+	})
+
+	t.Run("fragmented message", func(t *testing.T) {
+		client := dial(t, echoServer(t))
+
+		client.write(t, false, OpBinary, []byte("hel"))
+		client.write(t, true, OpPing, []byte("p"))
+		client.write(t, true, OpContinuation, []byte("lo"))
+
+		op, payload := client.read(t)
+		assert.Equal(t, OpPong, op)
+		assert.Equal(t, "p", string(payload))
+		op, payload = client.read(t)
+		assert.Equal(t, OpBinary, op)
+		assert.Equal(t, "hello", string(payload))
+	})
+
+	t.Run("closing handshake", func(t *testing.T) {
+		client := dial(t, echoServer(t))
+
+		client.write(t, true, OpClose, binary.BigEndian.AppendUint16(nil, CloseNormal))
+		op, payload := client.read(t)
+		assert.Equal(t, OpClose, op)
+		assert.Equal(t, uint16(CloseNormal), binary.BigEndian.Uint16(payload))
+
+		_, err := client.br.ReadByte()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/lmittmann/tint"
+)
+
+// newLogHandler builds the server's log handler. format is text, for colorized human-readable
+// logs, or json.
+func newLogHandler(w io.Writer, level, format string, addSource bool) (slog.Handler, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, must be one of [debug, info, warn, error]", level)
+	}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return tint.NewHandler(w, &tint.Options{Level: lvl, AddSource: addSource}), nil
+	case "json":
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl, AddSource: addSource}), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be one of [text, json]", format)
+	}
+}
+
+// envOr returns the value of the environment variable key, or fallback if it's unset.
+func envOr(key, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+	return fallback
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		level     string
+		format    string
+		wantLevel slog.Level
+		wantJSON  bool
+		wantErr   string
+	}{
+		{name: "defaults", level: "info", format: "text", wantLevel: slog.LevelInfo},
+		{name: "json", level: "debug", format: "json", wantLevel: slog.LevelDebug, wantJSON: true},
+		{name: "case insensitive", level: "WARN", format: "JSON", wantLevel: slog.LevelWarn, wantJSON: true},
+		{
+			name:      "env fallbacks",
+			env:       map[string]string{"LOG_LEVEL": "error", "LOG_FORMAT": "json"},
+			wantLevel: slog.LevelError,
+			wantJSON:  true,
+		},
+		{
+			name:    "set but empty env",
+			env:     map[string]string{"LOG_LEVEL": "", "LOG_FORMAT": "text"},
+			wantErr: `invalid log level ""`,
+		},
+		{name: "invalid level", level: "verbose", format: "text", wantErr: `invalid log level "verbose"`},
+		{name: "invalid format", level: "info", format: "xml", wantErr: `invalid log format "xml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, format := tt.level, tt.format
+			if tt.env != nil {
+				for key, val := range tt.env {
+					t.Setenv(key, val)
+				}
+				level, format = envOr("LOG_LEVEL", "info"), envOr("LOG_FORMAT", "text")
+			}
+
+			var buf bytes.Buffer
+			handler, err := newLogHandler(&buf, level, format, false)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			assert.True(t, handler.Enabled(ctx, tt.wantLevel))
+			assert.False(t, handler.Enabled(ctx, tt.wantLevel-1), "below the level")
+
+			slog.New(handler).Log(ctx, tt.wantLevel, "hello")
+			var record map[string]any
+			if tt.wantJSON {
+				require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+				assert.Equal(t, "hello", record["msg"])
+			} else {
+				assert.Error(t, json.Unmarshal(buf.Bytes(), &record), "text logs aren't JSON")
+				assert.Contains(t, buf.String(), "hello")
+			}
+		})
+	}
+
+	t.Run("unset env uses the default", func(t *testing.T) {
+		t.Setenv("LOG_FORMAT", "json")
+		// t.Setenv restores the variable, unsetting it only for the rest of the test
+		require.NoError(t, os.Unsetenv("LOG_FORMAT"))
+		assert.Equal(t, "text", envOr("LOG_FORMAT", "text"))
+	})
+}
@@ -1,54 +1,364 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // embed timezones for schedules, the container image has none
 
+	"github.com/caproven/mock-server/internal/accesslog"
 	"github.com/caproven/mock-server/internal/config"
 	"github.com/caproven/mock-server/internal/rest"
-	"github.com/goccy/go-yaml"
-	"github.com/lmittmann/tint"
 )
 
-func main() {
-	slog.SetDefault(slog.New(tint.NewHandler(os.Stdout, &tint.Options{
-		AddSource: true,
-	})))
+// bodyFileWatchInterval is how often watched body files are checked for changes.
+const bodyFileWatchInterval = time.Second
+
+// dumpBodyBytes is the most of each request and response body logged by -dump.
+const dumpBodyBytes = 4 << 10
 
+func main() {
 	configFilePath := flag.String("config", "config.yaml", "path to config file")
+	watchFiles := flag.Bool("watch-files", false, "reload response body files when they change")
+	logLevel := flag.String("log-level", envOr("LOG_LEVEL", "info"), "minimum log level, one of [debug, info, warn, error], or $LOG_LEVEL")
+	logFormat := flag.String("log-format", envOr("LOG_FORMAT", "text"), "log format, one of [text, json], or $LOG_FORMAT")
+	logSource := flag.Bool("log-source", true, "include the source location in logs")
+	dump := flag.Bool("dump", false, "log full requests and responses at debug level")
+	dryRunWeights := flag.Bool("dry-run-weights", false, "print each weighted endpoint's response probabilities and exit")
 	flag.Parse()
 
+	logHandler, err := newLogHandler(os.Stdout, *logLevel, *logFormat, *logSource)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(slog.New(logHandler))
+
 	cfg, err := readConfig(*configFilePath)
 	if err != nil {
 		slog.Error("failed to read config", "err", err)
 		os.Exit(1)
 	}
 
-	endpoints, err := cfg.RestEndpoints()
+	var bodyFiles *rest.BodyFiles
+	if *watchFiles {
+		bodyFiles = rest.NewBodyFiles()
+	}
+
+	endpoints, err := cfg.RestEndpoints(bodyFiles)
 	if err != nil {
 		slog.Error("failed to build rest endpoints", "err", err)
 		os.Exit(1)
 	}
 
-	mux := http.NewServeMux()
-	rest.RegisterHandlers(mux, endpoints)
+	if *dryRunWeights {
+		if err := printWeights(os.Stdout, rest.Weights(endpoints)); err != nil {
+			slog.Error("failed to print weights", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	timeouts, err := cfg.Server.Timeouts()
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	maxRequestBytes, err := cfg.Server.RequestBytesLimit()
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	fallbacks, err := cfg.Fallbacks(bodyFiles)
+	if err != nil {
+		slog.Error("failed to build fallback responses", "err", err)
+		os.Exit(1)
+	}
+
+	warmup, err := cfg.Warmup(bodyFiles)
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	requestTimeout, err := cfg.RequestTimeout(bodyFiles)
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	drainGrace, err := cfg.Server.DrainGrace()
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	shutdownDrain, shutdownResp, err := cfg.ShutdownDrain(bodyFiles)
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+
+	handlerOpts := []rest.HandlerOption{rest.WithWriteTimeout(timeouts.Write)}
+	if cfg.AutoContentLength {
+		handlerOpts = append(handlerOpts, rest.WithAutoContentLength())
+	}
+	if cfg.DebugHeaders {
+		handlerOpts = append(handlerOpts, rest.WithDebugHeaders())
+	}
+	if cfg.AllowStatusOverride {
+		handlerOpts = append(handlerOpts, rest.WithStatusOverride())
+	}
+	if cfg.AllowIndexOverride {
+		handlerOpts = append(handlerOpts, rest.WithIndexOverride())
+	}
+
+	var adminBasePath string
+	if cfg.Server.AdminInBasePath {
+		adminBasePath, _ = cfg.Server.PathPrefix() // already validated by RestEndpoints
+	}
+	drain := rest.NewDrain(drainGrace)
+	mux := newMux(endpoints, drain, adminBasePath, handlerOpts...)
+
+	handler := rest.RegexHandler(rest.FallbackHandler(mux, fallbacks), mux, endpoints, handlerOpts...)
+	handler, err = rest.TrailingSlashHandler(handler, mux, rest.TrailingSlash(cfg.Server.TrailingSlash))
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		os.Exit(1)
+	}
+	if requestTimeout != nil {
+		handler = requestTimeout.Handler(handler)
+	}
+	if cfg.RequestID != nil {
+		handler = rest.RequestIDHandler(handler, cfg.RequestID.Header)
+	}
+	if *dump {
+		handler = rest.DumpHandler(handler, dumpBodyBytes)
+	}
+	handler = rest.DecompressRequestBody(handler, maxRequestBytes)
+	handler = rest.LimitRequestBytes(handler, maxRequestBytes, fallbacks.TooLarge)
+	handler = drain.Handler(handler, adminBasePath)
+	if warmup != nil {
+		handler = warmup.Handler(handler, adminBasePath)
+	}
+	// os.Exit skips deferred calls, so the access log is closed explicitly on every exit path
+	closeAccessLog := func() {}
+	if cfg.AccessLog != nil {
+		accessLogWriter, err := newAccessLogWriter(*cfg.AccessLog)
+		if err != nil {
+			slog.Error("failed to open access log", "err", err)
+			os.Exit(1)
+		}
+		closeAccessLog = func() {
+			if err := accessLogWriter.Close(); err != nil {
+				slog.Error("failed to close access log", "err", err)
+			}
+		}
+		handler = accesslog.Handler(handler, slog.New(slog.NewJSONHandler(accessLogWriter, nil)))
+	}
 
 	addr := os.Getenv("ADDR")
 	if addr == "" {
 		addr = ":8080"
 	}
-	slog.Info("starting server", "addr", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	listeners, err := cfg.Server.ServerListeners(addr)
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		closeAccessLog()
+		os.Exit(1)
+	}
+	maxConnections, err := cfg.Server.ConnectionLimit()
+	if err != nil {
+		slog.Error("invalid server config", "err", err)
+		closeAccessLog()
+		os.Exit(1)
+	}
+
+	servers, err := listen(listeners, maxConnections, func() *http.Server {
+		server := &http.Server{
+			Handler:           handler,
+			ReadTimeout:       timeouts.Read,
+			ReadHeaderTimeout: timeouts.ReadHeader,
+			WriteTimeout:      timeouts.Write,
+			IdleTimeout:       timeouts.Idle,
+		}
+		server.SetKeepAlivesEnabled(cfg.Server.KeepAlivesEnabled())
+		if cfg.Server.H2C {
+			server.Protocols = new(http.Protocols)
+			server.Protocols.SetHTTP1(true)
+			server.Protocols.SetHTTP2(true)
+			server.Protocols.SetUnencryptedHTTP2(true)
+		}
+		return server
+	})
+	if err != nil {
+		slog.Error("failed to start server", "err", err)
+		closeAccessLog()
+		os.Exit(1)
+	}
+
+	if cfg.Debug != nil && (cfg.Debug.PProf || cfg.Debug.Expvar) {
+		debugAddr := cfg.Debug.Addr
+		if debugAddr == "" {
+			debugAddr = defaultDebugAddr
+		}
+		debugHandler := newDebugHandler(*cfg.Debug)
+		debugServers, err := listen([]config.Listener{{Addr: debugAddr}}, 0, func() *http.Server {
+			// no write timeout, profiles and traces stream for as long as requested
+			return &http.Server{
+				Handler:           debugHandler,
+				ReadHeaderTimeout: timeouts.ReadHeader,
+				IdleTimeout:       timeouts.Idle,
+			}
+		})
+		if err != nil {
+			slog.Error("failed to start debug server", "err", err)
+			closeAccessLog()
+			os.Exit(1)
+		}
+		servers = append(servers, debugServers...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if bodyFiles != nil {
+		go bodyFiles.Watch(ctx, bodyFileWatchInterval)
+	}
+	if warmup != nil {
+		warmup.Start()
+	}
+
+	serveCtx := ctx
+	if shutdownDrain > 0 {
+		serveCtx = drainOnShutdown(ctx, stop, drain, shutdownDrain, shutdownResp)
+	}
+	if err := serve(serveCtx, servers); err != nil {
 		slog.Error("server stopped", "err", err)
+		closeAccessLog()
 		os.Exit(1)
 	}
+	closeAccessLog()
+	slog.Info("server stopped")
+}
+
+func newAccessLogWriter(cfg config.AccessLog) (*accesslog.RotatingWriter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("access log path is required")
+	}
+	if cfg.MaxSizeMB < 0 {
+		return nil, fmt.Errorf("access log max size must be >= 0: %d", cfg.MaxSizeMB)
+	}
+	return accesslog.NewRotatingWriter(cfg.Path, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxBackups)
 }
 
 func readConfig(filePath string) (config.Config, error) {
+	cfg, _, err := readConfigFile(filePath, nil, make(map[string]bool))
+	return cfg, err
+}
+
+// readConfigFile reads the config at filePath, merging in the files it includes. Included files'
+// endpoints come first, in include order, followed by the file's own. It also returns the file each
+// endpoint was defined in. includedBy is the chain of files including this one, to detect cycles.
+// read holds the absolute paths of the files read so far, so a file included from more than one
+// place is only merged once.
+func readConfigFile(filePath string, includedBy []string, read map[string]bool) (config.Config, []string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return config.Config{}, nil, fmt.Errorf("resolve config path: %w", err)
+	}
+	if slices.Contains(includedBy, absPath) {
+		return config.Config{}, nil, fmt.Errorf("circular include: %s", strings.Join(append(includedBy, absPath), " -> "))
+	}
+	if read[absPath] {
+		return config.Config{}, nil, nil
+	}
+	read[absPath] = true
+
+	cfg, err := decodeConfigFile(absPath)
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	cfg.SetDir(filepath.Dir(absPath))
+	if len(cfg.Include) == 0 {
+		return cfg, slices.Repeat([]string{filePath}, len(cfg.Endpoints)), nil
+	}
+
+	var endpoints []config.Endpoint
+	var origins []string
+	responses := make(map[string]config.Response)
+	addResponses := func(named map[string]config.Response, file string) error {
+		for name, resp := range named {
+			if _, ok := responses[name]; ok {
+				return fmt.Errorf("named response %q is defined more than once, including in %s", name, file)
+			}
+			responses[name] = resp
+		}
+		return nil
+	}
+
+	for _, include := range cfg.Include {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filePath), path)
+		}
+		included, includedOrigins, err := readConfigFile(path, append(includedBy, absPath), read)
+		if err != nil {
+			return config.Config{}, nil, fmt.Errorf("include %q: %w", include, err)
+		}
+
+		endpoints = append(endpoints, included.Endpoints...)
+		origins = append(origins, includedOrigins...)
+		if err := addResponses(included.Responses, path); err != nil {
+			return config.Config{}, nil, err
+		}
+
+		included.Include, included.Endpoints, included.Responses = nil, nil, nil
+		if !reflect.ValueOf(included).IsZero() {
+			return config.Config{}, nil, fmt.Errorf("included config %q can only define endpoints and responses", include)
+		}
+	}
+
+	endpoints = append(endpoints, cfg.Endpoints...)
+	origins = append(origins, slices.Repeat([]string{filePath}, len(cfg.Endpoints))...)
+	if err := addResponses(cfg.Responses, filePath); err != nil {
+		return config.Config{}, nil, err
+	}
+
+	defined := make(map[string]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		key := cmp.Or(rest.NormalizeMethod(strings.ToUpper(endpoint.Method)), rest.MethodAll) + " " + endpoint.Path
+		if file, ok := defined[key]; ok {
+			if file == origins[i] {
+				return config.Config{}, nil, fmt.Errorf("endpoint %s is defined more than once in %s", key, file)
+			}
+			return config.Config{}, nil, fmt.Errorf("endpoint %s is defined in both %s and %s", key, file, origins[i])
+		}
+		defined[key] = origins[i]
+	}
+
+	cfg.Include = nil
+	cfg.Endpoints = endpoints
+	cfg.Responses = responses
+	return cfg, origins, nil
+}
+
+func decodeConfigFile(filePath string) (config.Config, error) {
 	configFile, err := os.Open(filePath)
 	if err != nil {
 		return config.Config{}, fmt.Errorf("open config file: %w", err)
@@ -59,10 +369,20 @@ func readConfig(filePath string) (config.Config, error) {
 		}
 	}(configFile)
 
-	var cfg config.Config
-	if err := yaml.NewDecoder(configFile).Decode(&cfg); err != nil {
+	cfg, err := config.Decode(configFile)
+	if err != nil {
 		return config.Config{}, fmt.Errorf("decode config file: %w", err)
 	}
 
 	return cfg, nil
 }
+
+// newMux routes the endpoints and the admin API. It's a new mux rather than http.DefaultServeMux,
+// which net/http/pprof registers its routes on, so profiling is only served by newDebugHandler.
+func newMux(endpoints []*rest.Endpoint, drain *rest.Drain, adminBasePath string, opts ...rest.HandlerOption) *http.ServeMux {
+	mux := http.NewServeMux()
+	rest.RegisterHandlers(mux, endpoints, opts...)
+	rest.RegisterAdminHandlers(mux, endpoints, adminBasePath)
+	rest.RegisterDrainHandlers(mux, drain, adminBasePath)
+	return mux
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigs writes each file's contents under dir, creating parent directories as needed.
+func writeConfigs(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+}
+
+func endpointYAML(path string) string {
+	return "endpoints:\n  - path: " + path + "\n    method: GET\n    response:\n      static:\n        status: 200\n"
+}
+
+func TestReadConfigFile(t *testing.T) {
+	t.Run("nested includes", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfigs(t, dir, map[string]string{
+			"main.yaml":                   "include:\n  - services/users.yaml\n" + endpointYAML("/health"),
+			"services/users.yaml":         "include:\n  - shared/orders.yaml\n" + endpointYAML("/users"),
+			"services/shared/orders.yaml": endpointYAML("/orders") + "responses:\n  gone:\n    status: 410\n",
+		})
+
+		cfg, origins, err := readConfigFile(filepath.Join(dir, "main.yaml"), nil, make(map[string]bool))
+		require.NoError(t, err)
+		var paths []string
+		for _, endpoint := range cfg.Endpoints {
+			paths = append(paths, endpoint.Path)
+		}
+		assert.Equal(t, []string{"/orders", "/users", "/health"}, paths)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "services/shared/orders.yaml"),
+			filepath.Join(dir, "services/users.yaml"),
+			filepath.Join(dir, "main.yaml"),
+		}, origins)
+		assert.Contains(t, cfg.Responses, "gone")
+		assert.Empty(t, cfg.Include)
+	})
+
+	t.Run("diamond includes merged once", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfigs(t, dir, map[string]string{
+			"main.yaml": "include:\n  - b.yaml\n  - c.yaml\n",
+			"b.yaml":    "include:\n  - d.yaml\n" + endpointYAML("/b"),
+			"c.yaml":    "include:\n  - d.yaml\n" + endpointYAML("/c"),
+			"d.yaml":    endpointYAML("/d") + "responses:\n  shared:\n    status: 204\n",
+		})
+
+		cfg, err := readConfig(filepath.Join(dir, "main.yaml"))
+		require.NoError(t, err)
+		var paths []string
+		for _, endpoint := range cfg.Endpoints {
+			paths = append(paths, endpoint.Path)
+		}
+		assert.Equal(t, []string{"/d", "/b", "/c"}, paths)
+		assert.Len(t, cfg.Responses, 1)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := map[string]struct {
+			files map[string]string
+			want  string
+		}{
+			"circular include": {
+				files: map[string]string{
+					"main.yaml": "include:\n  - a.yaml\n",
+					"a.yaml":    "include:\n  - main.yaml\n",
+				},
+				want: "circular include",
+			},
+			"self include": {
+				files: map[string]string{"main.yaml": "include:\n  - main.yaml\n"},
+				want:  "circular include",
+			},
+			"endpoint in two files": {
+				files: map[string]string{
+					"main.yaml": "include:\n  - a.yaml\n" + endpointYAML("/users"),
+					"a.yaml":    endpointYAML("/users"),
+				},
+				want: "is defined in both",
+			},
+			"endpoint twice in one file": {
+				files: map[string]string{
+					"main.yaml": "include:\n  - a.yaml\n" + endpointYAML("/users") + "  - path: /users\n    method: get\n    response:\n      static:\n        status: 204\n",
+					"a.yaml":    endpointYAML("/orders"),
+				},
+				want: "is defined more than once in",
+			},
+			"response in two files": {
+				files: map[string]string{
+					"main.yaml": "include:\n  - a.yaml\nresponses:\n  gone:\n    status: 410\n",
+					"a.yaml":    "responses:\n  gone:\n    status: 404\n",
+				},
+				want: `named response "gone" is defined more than once`,
+			},
+			"server settings in included file": {
+				files: map[string]string{
+					"main.yaml": "include:\n  - a.yaml\n",
+					"a.yaml":    "debugHeaders: true\n",
+				},
+				want: "can only define endpoints and responses",
+			},
+			"missing include": {
+				files: map[string]string{"main.yaml": "include:\n  - missing.yaml\n"},
+				want:  "open config file",
+			},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				writeConfigs(t, dir, tc.files)
+				_, err := readConfig(filepath.Join(dir, "main.yaml"))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.want)
+			})
+		}
+	})
+}
+
+func TestDecodeConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		"valid.yaml":   endpointYAML("/users"),
+		"invalid.yaml": "endpoints: [\n",
+	})
+
+	cfg, err := decodeConfigFile(filepath.Join(dir, "valid.yaml"))
+	require.NoError(t, err)
+	require.Len(t, cfg.Endpoints, 1)
+	assert.Equal(t, "/users", cfg.Endpoints[0].Path)
+
+	_, err = decodeConfigFile(filepath.Join(dir, "invalid.yaml"))
+	assert.ErrorContains(t, err, "decode config file")
+
+	_, err = decodeConfigFile(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorContains(t, err, "open config file")
+}
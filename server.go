@@ -0,0 +1,235 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caproven/mock-server/internal/config"
+	"github.com/caproven/mock-server/internal/rest"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+type listenerServer struct {
+	server   *http.Server
+	listener net.Listener
+	tls      *config.TLS
+}
+
+// listen binds every listener and loads its TLS certificate up front, so a bad addr or certificate
+// aborts startup before anything is served.
+// maxConns limits the connections open at once across all listeners, with 0 meaning no limit.
+func listen(listeners []config.Listener, maxConns int, newServer func() *http.Server) ([]listenerServer, error) {
+	var servers []listenerServer
+	var sem chan struct{}
+	if maxConns > 0 {
+		sem = make(chan struct{}, maxConns)
+	}
+
+	for _, listenerCfg := range listeners {
+		ln, err := net.Listen("tcp", listenerCfg.Addr)
+		if err != nil {
+			for _, s := range servers {
+				_ = s.listener.Close()
+			}
+			return nil, fmt.Errorf("listen on %q: %w", listenerCfg.Addr, err)
+		}
+		if sem != nil {
+			ln = newLimitListener(ln, sem)
+		}
+
+		server := newServer()
+		server.Addr = listenerCfg.Addr
+		idle, err := listenerCfg.Idle(server.IdleTimeout)
+		if err != nil {
+			_ = ln.Close()
+			for _, s := range servers {
+				_ = s.listener.Close()
+			}
+			return nil, fmt.Errorf("listener %q: %w", listenerCfg.Addr, err)
+		}
+		server.IdleTimeout = idle
+		server.ConnState = newConnStateLogger(listenerCfg.Addr, cmp.Or(server.IdleTimeout, server.ReadTimeout)).log
+		if listenerCfg.TLS != nil {
+			tlsConfig, err := listenerCfg.TLS.Config()
+			if err != nil {
+				_ = ln.Close()
+				for _, s := range servers {
+					_ = s.listener.Close()
+				}
+				return nil, fmt.Errorf("listener %q tls: %w", listenerCfg.Addr, err)
+			}
+			cert, err := tls.LoadX509KeyPair(listenerCfg.TLS.KeyPair())
+			if err != nil {
+				_ = ln.Close()
+				for _, s := range servers {
+					_ = s.listener.Close()
+				}
+				return nil, fmt.Errorf("listener %q tls: %w", listenerCfg.Addr, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+			server.TLSConfig = tlsConfig
+			if listenerCfg.TLS.HandshakeDelay != "" {
+				slog.Warn("delaying every tls handshake", "addr", listenerCfg.Addr, "delay", listenerCfg.TLS.HandshakeDelay)
+			}
+		}
+		servers = append(servers, listenerServer{
+			server:   server,
+			listener: ln,
+			tls:      listenerCfg.TLS,
+		})
+	}
+
+	return servers, nil
+}
+
+// serve runs all servers until ctx is done or any of them fails, then gracefully shuts them all down.
+func serve(ctx context.Context, servers []listenerServer) error {
+	errs := make(chan error, len(servers))
+	var wg sync.WaitGroup
+
+	for _, s := range servers {
+		wg.Go(func() {
+			slog.Info("starting server", "addr", s.server.Addr, "tls", s.tls != nil)
+			var err error
+			if s.tls != nil {
+				// the certificate was loaded into the server's TLS config by listen
+				err = s.server.ServeTLS(s.listener, "", "")
+			} else {
+				err = s.server.Serve(s.listener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("serve %q: %w", s.server.Addr, err)
+			}
+		})
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var shutdownErrs []error
+	for _, s := range servers {
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			shutdownErrs = append(shutdownErrs, fmt.Errorf("shut down %q: %w", s.server.Addr, err))
+		}
+	}
+	wg.Wait()
+
+	return errors.Join(append([]error{serveErr}, shutdownErrs...)...)
+}
+
+// drainOnShutdown returns a context done window after ctx, keeping the servers up through a shutdown
+// drain during which drain answers new requests with resp. It calls stop once ctx is done, so a
+// second signal kills the process instead of waiting out the window.
+func drainOnShutdown(ctx context.Context, stop func(), drain *rest.Drain, window time.Duration, resp *rest.Response) context.Context {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	context.AfterFunc(ctx, func() {
+		stop()
+		drain.Shutdown(resp)
+		time.AfterFunc(window, cancel)
+	})
+	return drainCtx
+}
+
+// limitListener accepts a connection only once it can take a slot in sem, which it holds until the
+// connection is closed. Sharing sem between listeners limits their connections in total. Beyond
+// the limit, connections wait in the kernel's accept queue.
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLimitListener(ln net.Listener, sem chan struct{}) *limitListener {
+	return &limitListener{Listener: ln, sem: sem, done: make(chan struct{})}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// limitConn releases its listener slot the first time it's closed.
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
+// connStateLogger logs a listener's connection state transitions, and connections closed after
+// idling for the idle timeout, which the server closes to evict them from clients' pools.
+type connStateLogger struct {
+	addr        string
+	idleTimeout time.Duration
+	// idleSince tracks when each idle connection went idle.
+	idleSince sync.Map
+}
+
+func newConnStateLogger(addr string, idleTimeout time.Duration) *connStateLogger {
+	return &connStateLogger{addr: addr, idleTimeout: idleTimeout}
+}
+
+func (c *connStateLogger) log(conn net.Conn, state http.ConnState) {
+	slog.Debug("connection state changed",
+		slog.String("listener", c.addr),
+		slog.String("remote", conn.RemoteAddr().String()),
+		slog.String("state", state.String()),
+	)
+
+	switch state {
+	case http.StateIdle:
+		c.idleSince.Store(conn, time.Now())
+	case http.StateClosed, http.StateHijacked:
+		since, ok := c.idleSince.LoadAndDelete(conn)
+		if !ok || state != http.StateClosed || c.idleTimeout <= 0 {
+			return
+		}
+		// clients may close idle connections themselves, before the timeout
+		if idle := time.Since(since.(time.Time)); idle >= c.idleTimeout {
+			slog.Info("closed idle connection",
+				slog.String("listener", c.addr),
+				slog.String("remote", conn.RemoteAddr().String()),
+				slog.Duration("idle", idle),
+			)
+		}
+	default:
+		c.idleSince.Delete(conn)
+	}
+}
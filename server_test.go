@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caproven/mock-server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitListener(t *testing.T) {
+	listen := func(t *testing.T, sem chan struct{}) *limitListener {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		l := newLimitListener(ln, sem)
+		t.Cleanup(func() { _ = l.Close() })
+		return l
+	}
+	dial := func(t *testing.T, l net.Listener) {
+		t.Helper()
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+	}
+	accept := func(l net.Listener) <-chan net.Conn {
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err == nil {
+				accepted <- conn
+			}
+			close(accepted)
+		}()
+		return accepted
+	}
+
+	t.Run("waits for a slot", func(t *testing.T) {
+		l := listen(t, make(chan struct{}, 2))
+		var conns []net.Conn
+		for range 2 {
+			dial(t, l)
+			conn, err := l.Accept()
+			require.NoError(t, err)
+			conns = append(conns, conn)
+		}
+
+		dial(t, l)
+		accepted := accept(l)
+		select {
+		case <-accepted:
+			t.Fatal("accepted a connection over the limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, conns[0].Close())
+		assert.Error(t, conns[0].Close(), "closing again doesn't free another slot")
+		select {
+		case conn := <-accepted:
+			require.NotNil(t, conn)
+			_ = conn.Close()
+		case <-time.After(time.Second):
+			t.Fatal("connection not accepted once a slot was freed")
+		}
+		_ = conns[1].Close()
+	})
+
+	t.Run("shared between listeners", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		first, second := listen(t, sem), listen(t, sem)
+		dial(t, first)
+		conn, err := first.Accept()
+		require.NoError(t, err)
+
+		dial(t, second)
+		accepted := accept(second)
+		select {
+		case <-accepted:
+			t.Fatal("accepted a connection over the shared limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, conn.Close())
+		select {
+		case conn := <-accepted:
+			require.NotNil(t, conn)
+			_ = conn.Close()
+		case <-time.After(time.Second):
+			t.Fatal("connection not accepted once a slot was freed")
+		}
+	})
+
+	t.Run("close stops a waiting accept", func(t *testing.T) {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		l := listen(t, sem)
+		errs := make(chan error, 1)
+		go func() {
+			_, err := l.Accept()
+			errs <- err
+		}()
+
+		require.NoError(t, l.Close())
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, net.ErrClosed)
+		case <-time.After(time.Second):
+			t.Fatal("accept still waiting after close")
+		}
+		assert.Len(t, sem, 1, "a closed listener doesn't take a slot")
+	})
+}
+
+// writeTestKeyPair writes a self-signed certificate for localhost and its key to dir.
+func writeTestKeyPair(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestListenAndServe(t *testing.T) {
+	newServer := func() *http.Server {
+		return &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "shared")
+		})}
+	}
+	// run serves servers in the background, returning serve's result once it stops.
+	run := func(ctx context.Context, servers []listenerServer) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- serve(ctx, servers) }()
+		return done
+	}
+	get := func(client *http.Client, url string) (string, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+	client := &http.Client{Timeout: time.Second, Transport: &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+	}}
+
+	t.Run("listeners share the handler", func(t *testing.T) {
+		certFile, keyFile := writeTestKeyPair(t, t.TempDir())
+		servers, err := listen([]config.Listener{
+			{Addr: "127.0.0.1:0"},
+			{Addr: "127.0.0.1:0", TLS: &config.TLS{CertFile: certFile, KeyFile: keyFile}},
+		}, 0, newServer)
+		require.NoError(t, err)
+		require.Len(t, servers, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := run(ctx, servers)
+		for i, scheme := range []string{"http", "https"} {
+			body, err := get(client, scheme+"://"+servers[i].listener.Addr().String())
+			require.NoError(t, err, scheme)
+			assert.Equal(t, "shared", body, scheme)
+		}
+
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("serve didn't return after its context was done")
+		}
+	})
+
+	t.Run("bind failure names the addr", func(t *testing.T) {
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = taken.Close() })
+
+		_, err = listen([]config.Listener{{Addr: "127.0.0.1:0"}, {Addr: taken.Addr().String()}}, 0, newServer)
+		assert.ErrorContains(t, err, `listen on "`+taken.Addr().String()+`"`)
+	})
+
+	t.Run("bad certificate fails at startup", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, _ := writeTestKeyPair(t, dir)
+		_, err := listen([]config.Listener{
+			{Addr: "127.0.0.1:0", TLS: &config.TLS{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")}},
+		}, 0, newServer)
+		assert.ErrorContains(t, err, `listener "127.0.0.1:0" tls`)
+	})
+
+	t.Run("one failing listener stops the others", func(t *testing.T) {
+		servers, err := listen([]config.Listener{{Addr: "127.0.0.1:0"}, {Addr: "127.0.0.1:0"}}, 0, newServer)
+		require.NoError(t, err)
+		healthy := servers[0].listener.Addr().String()
+		// serving on a closed listener fails right away
+		require.NoError(t, servers[1].listener.Close())
+
+		select {
+		case err := <-run(context.Background(), servers):
+			assert.ErrorContains(t, err, `serve "127.0.0.1:0"`)
+		case <-time.After(time.Second):
+			t.Fatal("serve didn't return after a listener failed")
+		}
+		_, err = get(client, "http://"+healthy)
+		assert.Error(t, err, "the healthy listener is shut down too")
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of server goroutines' logs.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestListenerIdleTimeout(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	var logs syncBuffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	servers, err := listen([]config.Listener{{Addr: "127.0.0.1:0", IdleTimeout: "100ms"}}, 0, func() *http.Server {
+		return &http.Server{
+			Handler:     http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			IdleTimeout: time.Minute,
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, servers[0].server.IdleTimeout, "the listener overrides the server's idle timeout")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, servers) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	conn, err := net.Dial("tcp", servers[0].listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	idleSince := time.Now()
+
+	// the keep-alive connection is left idle until the server closes it
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.GreaterOrEqual(t, time.Since(idleSince), 100*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		for line := range strings.Lines(logs.String()) {
+			var record map[string]any
+			if json.Unmarshal([]byte(line), &record) == nil && record["msg"] == "closed idle connection" {
+				return record["listener"] == "127.0.0.1:0" && record["remote"] == conn.LocalAddr().String()
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
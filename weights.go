@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/caproven/mock-server/internal/rest"
+)
+
+// printWeights writes a table of each weighted endpoint's responses and their chance of being
+// chosen, for -dry-run-weights.
+func printWeights(w io.Writer, weights []rest.EndpointWeights) error {
+	if len(weights) == 0 {
+		_, err := fmt.Fprintln(w, "no weighted endpoints")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tSTRATEGY\tINDEX\tSTATUS\tFAULT\tWEIGHT\tPROBABILITY")
+	for _, endpoint := range weights {
+		for _, share := range endpoint.Shares {
+			status, fault, weight := "-", "-", "-"
+			if share.Status != 0 {
+				status = strconv.Itoa(share.Status)
+			}
+			if share.Fault != "" {
+				fault = string(share.Fault)
+			}
+			if share.Strategy == "weighted" {
+				weight = strconv.FormatFloat(share.Weight, 'f', -1, 64)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%.2f%%\n",
+				endpoint.ID, share.Strategy, share.Index, status, fault, weight, share.Probability*100)
+		}
+	}
+	return tw.Flush()
+}